@@ -0,0 +1,539 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: songlibrary/v1/song_library.proto
+
+package songlibraryv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SongLibrary_ListSongs_FullMethodName        = "/songlibrary.v1.SongLibrary/ListSongs"
+	SongLibrary_StreamSongs_FullMethodName      = "/songlibrary.v1.SongLibrary/StreamSongs"
+	SongLibrary_GetSong_FullMethodName          = "/songlibrary.v1.SongLibrary/GetSong"
+	SongLibrary_CreateSong_FullMethodName       = "/songlibrary.v1.SongLibrary/CreateSong"
+	SongLibrary_UpdateSong_FullMethodName       = "/songlibrary.v1.SongLibrary/UpdateSong"
+	SongLibrary_DeleteSong_FullMethodName       = "/songlibrary.v1.SongLibrary/DeleteSong"
+	SongLibrary_GetLyrics_FullMethodName        = "/songlibrary.v1.SongLibrary/GetLyrics"
+	SongLibrary_ListRevisions_FullMethodName    = "/songlibrary.v1.SongLibrary/ListRevisions"
+	SongLibrary_RestoreRevision_FullMethodName  = "/songlibrary.v1.SongLibrary/RestoreRevision"
+	SongLibrary_DiffRevisions_FullMethodName    = "/songlibrary.v1.SongLibrary/DiffRevisions"
+	SongLibrary_ListAuditEntries_FullMethodName = "/songlibrary.v1.SongLibrary/ListAuditEntries"
+)
+
+// SongLibraryClient is the client API for SongLibrary service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SongLibrary exposes the song catalog to internal services. It mirrors the
+// REST API under /api/v1/songs.
+type SongLibraryClient interface {
+	// ListSongs returns all songs, or those in one language.
+	ListSongs(ctx context.Context, in *ListSongsRequest, opts ...grpc.CallOption) (*ListSongsResponse, error)
+	// StreamSongs streams all songs one message at a time.
+	StreamSongs(ctx context.Context, in *StreamSongsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Song], error)
+	// GetSong returns a song by ID.
+	GetSong(ctx context.Context, in *GetSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// CreateSong adds a new song.
+	CreateSong(ctx context.Context, in *CreateSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// UpdateSong applies the non-empty fields of song to an existing song.
+	UpdateSong(ctx context.Context, in *UpdateSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// DeleteSong removes a song.
+	DeleteSong(ctx context.Context, in *DeleteSongRequest, opts ...grpc.CallOption) (*DeleteSongResponse, error)
+	// GetLyrics returns the lyrics of a song with per-line timestamps when
+	// synchronized LRC lyrics are stored.
+	GetLyrics(ctx context.Context, in *GetLyricsRequest, opts ...grpc.CallOption) (*Lyrics, error)
+	// ListRevisions returns the revision history of a song, oldest first.
+	ListRevisions(ctx context.Context, in *ListRevisionsRequest, opts ...grpc.CallOption) (*ListRevisionsResponse, error)
+	// RestoreRevision overwrites a song with one of its revisions.
+	RestoreRevision(ctx context.Context, in *RestoreRevisionRequest, opts ...grpc.CallOption) (*Song, error)
+	// DiffRevisions compares two revisions of a song field by field, and
+	// their lyrics line by line.
+	DiffRevisions(ctx context.Context, in *DiffRevisionsRequest, opts ...grpc.CallOption) (*RevisionDiff, error)
+	// ListAuditEntries returns the audit log entries matching the request,
+	// newest first.
+	ListAuditEntries(ctx context.Context, in *ListAuditEntriesRequest, opts ...grpc.CallOption) (*ListAuditEntriesResponse, error)
+}
+
+type songLibraryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSongLibraryClient(cc grpc.ClientConnInterface) SongLibraryClient {
+	return &songLibraryClient{cc}
+}
+
+func (c *songLibraryClient) ListSongs(ctx context.Context, in *ListSongsRequest, opts ...grpc.CallOption) (*ListSongsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSongsResponse)
+	err := c.cc.Invoke(ctx, SongLibrary_ListSongs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) StreamSongs(ctx context.Context, in *StreamSongsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Song], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SongLibrary_ServiceDesc.Streams[0], SongLibrary_StreamSongs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamSongsRequest, Song]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SongLibrary_StreamSongsClient = grpc.ServerStreamingClient[Song]
+
+func (c *songLibraryClient) GetSong(ctx context.Context, in *GetSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongLibrary_GetSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) CreateSong(ctx context.Context, in *CreateSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongLibrary_CreateSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) UpdateSong(ctx context.Context, in *UpdateSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongLibrary_UpdateSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) DeleteSong(ctx context.Context, in *DeleteSongRequest, opts ...grpc.CallOption) (*DeleteSongResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSongResponse)
+	err := c.cc.Invoke(ctx, SongLibrary_DeleteSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) GetLyrics(ctx context.Context, in *GetLyricsRequest, opts ...grpc.CallOption) (*Lyrics, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Lyrics)
+	err := c.cc.Invoke(ctx, SongLibrary_GetLyrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) ListRevisions(ctx context.Context, in *ListRevisionsRequest, opts ...grpc.CallOption) (*ListRevisionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRevisionsResponse)
+	err := c.cc.Invoke(ctx, SongLibrary_ListRevisions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) RestoreRevision(ctx context.Context, in *RestoreRevisionRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongLibrary_RestoreRevision_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) DiffRevisions(ctx context.Context, in *DiffRevisionsRequest, opts ...grpc.CallOption) (*RevisionDiff, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevisionDiff)
+	err := c.cc.Invoke(ctx, SongLibrary_DiffRevisions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songLibraryClient) ListAuditEntries(ctx context.Context, in *ListAuditEntriesRequest, opts ...grpc.CallOption) (*ListAuditEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAuditEntriesResponse)
+	err := c.cc.Invoke(ctx, SongLibrary_ListAuditEntries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SongLibraryServer is the server API for SongLibrary service.
+// All implementations must embed UnimplementedSongLibraryServer
+// for forward compatibility.
+//
+// SongLibrary exposes the song catalog to internal services. It mirrors the
+// REST API under /api/v1/songs.
+type SongLibraryServer interface {
+	// ListSongs returns all songs, or those in one language.
+	ListSongs(context.Context, *ListSongsRequest) (*ListSongsResponse, error)
+	// StreamSongs streams all songs one message at a time.
+	StreamSongs(*StreamSongsRequest, grpc.ServerStreamingServer[Song]) error
+	// GetSong returns a song by ID.
+	GetSong(context.Context, *GetSongRequest) (*Song, error)
+	// CreateSong adds a new song.
+	CreateSong(context.Context, *CreateSongRequest) (*Song, error)
+	// UpdateSong applies the non-empty fields of song to an existing song.
+	UpdateSong(context.Context, *UpdateSongRequest) (*Song, error)
+	// DeleteSong removes a song.
+	DeleteSong(context.Context, *DeleteSongRequest) (*DeleteSongResponse, error)
+	// GetLyrics returns the lyrics of a song with per-line timestamps when
+	// synchronized LRC lyrics are stored.
+	GetLyrics(context.Context, *GetLyricsRequest) (*Lyrics, error)
+	// ListRevisions returns the revision history of a song, oldest first.
+	ListRevisions(context.Context, *ListRevisionsRequest) (*ListRevisionsResponse, error)
+	// RestoreRevision overwrites a song with one of its revisions.
+	RestoreRevision(context.Context, *RestoreRevisionRequest) (*Song, error)
+	// DiffRevisions compares two revisions of a song field by field, and
+	// their lyrics line by line.
+	DiffRevisions(context.Context, *DiffRevisionsRequest) (*RevisionDiff, error)
+	// ListAuditEntries returns the audit log entries matching the request,
+	// newest first.
+	ListAuditEntries(context.Context, *ListAuditEntriesRequest) (*ListAuditEntriesResponse, error)
+	mustEmbedUnimplementedSongLibraryServer()
+}
+
+// UnimplementedSongLibraryServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSongLibraryServer struct{}
+
+func (UnimplementedSongLibraryServer) ListSongs(context.Context, *ListSongsRequest) (*ListSongsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSongs not implemented")
+}
+func (UnimplementedSongLibraryServer) StreamSongs(*StreamSongsRequest, grpc.ServerStreamingServer[Song]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamSongs not implemented")
+}
+func (UnimplementedSongLibraryServer) GetSong(context.Context, *GetSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSong not implemented")
+}
+func (UnimplementedSongLibraryServer) CreateSong(context.Context, *CreateSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSong not implemented")
+}
+func (UnimplementedSongLibraryServer) UpdateSong(context.Context, *UpdateSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSong not implemented")
+}
+func (UnimplementedSongLibraryServer) DeleteSong(context.Context, *DeleteSongRequest) (*DeleteSongResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSong not implemented")
+}
+func (UnimplementedSongLibraryServer) GetLyrics(context.Context, *GetLyricsRequest) (*Lyrics, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLyrics not implemented")
+}
+func (UnimplementedSongLibraryServer) ListRevisions(context.Context, *ListRevisionsRequest) (*ListRevisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRevisions not implemented")
+}
+func (UnimplementedSongLibraryServer) RestoreRevision(context.Context, *RestoreRevisionRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreRevision not implemented")
+}
+func (UnimplementedSongLibraryServer) DiffRevisions(context.Context, *DiffRevisionsRequest) (*RevisionDiff, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiffRevisions not implemented")
+}
+func (UnimplementedSongLibraryServer) ListAuditEntries(context.Context, *ListAuditEntriesRequest) (*ListAuditEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEntries not implemented")
+}
+func (UnimplementedSongLibraryServer) mustEmbedUnimplementedSongLibraryServer() {}
+func (UnimplementedSongLibraryServer) testEmbeddedByValue()                     {}
+
+// UnsafeSongLibraryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SongLibraryServer will
+// result in compilation errors.
+type UnsafeSongLibraryServer interface {
+	mustEmbedUnimplementedSongLibraryServer()
+}
+
+func RegisterSongLibraryServer(s grpc.ServiceRegistrar, srv SongLibraryServer) {
+	// If the following call pancis, it indicates UnimplementedSongLibraryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SongLibrary_ServiceDesc, srv)
+}
+
+func _SongLibrary_ListSongs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSongsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).ListSongs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_ListSongs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).ListSongs(ctx, req.(*ListSongsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_StreamSongs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamSongsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SongLibraryServer).StreamSongs(m, &grpc.GenericServerStream[StreamSongsRequest, Song]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SongLibrary_StreamSongsServer = grpc.ServerStreamingServer[Song]
+
+func _SongLibrary_GetSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).GetSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_GetSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).GetSong(ctx, req.(*GetSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_CreateSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).CreateSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_CreateSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).CreateSong(ctx, req.(*CreateSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_UpdateSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).UpdateSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_UpdateSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).UpdateSong(ctx, req.(*UpdateSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_DeleteSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).DeleteSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_DeleteSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).DeleteSong(ctx, req.(*DeleteSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_GetLyrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLyricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).GetLyrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_GetLyrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).GetLyrics(ctx, req.(*GetLyricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_ListRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).ListRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_ListRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).ListRevisions(ctx, req.(*ListRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_RestoreRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).RestoreRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_RestoreRevision_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).RestoreRevision(ctx, req.(*RestoreRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_DiffRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).DiffRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_DiffRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).DiffRevisions(ctx, req.(*DiffRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongLibrary_ListAuditEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongLibraryServer).ListAuditEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongLibrary_ListAuditEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongLibraryServer).ListAuditEntries(ctx, req.(*ListAuditEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SongLibrary_ServiceDesc is the grpc.ServiceDesc for SongLibrary service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SongLibrary_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "songlibrary.v1.SongLibrary",
+	HandlerType: (*SongLibraryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListSongs",
+			Handler:    _SongLibrary_ListSongs_Handler,
+		},
+		{
+			MethodName: "GetSong",
+			Handler:    _SongLibrary_GetSong_Handler,
+		},
+		{
+			MethodName: "CreateSong",
+			Handler:    _SongLibrary_CreateSong_Handler,
+		},
+		{
+			MethodName: "UpdateSong",
+			Handler:    _SongLibrary_UpdateSong_Handler,
+		},
+		{
+			MethodName: "DeleteSong",
+			Handler:    _SongLibrary_DeleteSong_Handler,
+		},
+		{
+			MethodName: "GetLyrics",
+			Handler:    _SongLibrary_GetLyrics_Handler,
+		},
+		{
+			MethodName: "ListRevisions",
+			Handler:    _SongLibrary_ListRevisions_Handler,
+		},
+		{
+			MethodName: "RestoreRevision",
+			Handler:    _SongLibrary_RestoreRevision_Handler,
+		},
+		{
+			MethodName: "DiffRevisions",
+			Handler:    _SongLibrary_DiffRevisions_Handler,
+		},
+		{
+			MethodName: "ListAuditEntries",
+			Handler:    _SongLibrary_ListAuditEntries_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSongs",
+			Handler:       _SongLibrary_StreamSongs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "songlibrary/v1/song_library.proto",
+}
@@ -1,12 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"song-library/config"
-	"song-library/internal/db"
+	"song-library/internal/cache"
+	"song-library/internal/events"
+	"song-library/internal/graphapi"
+	"song-library/internal/grpcserver"
+	"song-library/internal/linkcheck"
+	"song-library/internal/middleware"
+	"song-library/internal/outbox"
 	"song-library/internal/repository"
 	"song-library/internal/router"
 	"song-library/internal/service"
+	"song-library/internal/sse"
+	"song-library/internal/storage"
+	"song-library/internal/webhook"
 	"song-library/pkg/logger"
+	"syscall"
+	"time"
 
 	_ "song-library/docs"
 
@@ -24,37 +43,155 @@ func main() {
 	// Initialize logger
 	logger.Init()
 
-	// Load configuration
-	cfg, err := config.LoadConfig(".env")
+	// "config print" shows the effective configuration and exits
+	args := os.Args[1:]
+	printConfig := len(args) >= 2 && args[0] == "config" && args[1] == "print"
+	if printConfig {
+		args = args[2:]
+	}
+
+	// Load configuration from defaults, config file, environment and flags
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "Usage: server [config print] [flags]")
+		config.Usage(os.Stderr)
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to load configuration", logger.Fields{"error": err.Error()})
+		os.Exit(1)
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			os.Exit(1)
+		}
 		return
 	}
+	applyLogging(cfg)
 
-	// Initialize database connection
-	dbConn, err := db.Connect(cfg)
+	// Initialize storage for the configured driver
+	store, err := storage.Open(cfg)
 	if err != nil {
 		logger.Error("Failed to connect to database", logger.Fields{"error": err.Error()})
 		return
 	}
 	defer func() {
-		sqlDB, _ := dbConn.DB()
-		sqlDB.Close()
+		store.Close()
 		logger.Info("Database connection closed", nil)
 	}()
 
 	// Initialize repositories and services
-	songRepository := repository.NewSongRepository(dbConn)
+	songRepository := store.Songs
+	if cfg.CacheSize > 0 && cfg.CacheTTL > 0 {
+		songRepository = repository.NewCachedSongRepository(songRepository, cache.NewLRU(cfg.CacheSize), cfg.CacheTTL)
+	}
 	songService := service.NewSongService(songRepository)
+	if cfg.CursorSecret != "" {
+		songService.UseCursorSecret(cfg.CursorSecret)
+	} else {
+		logger.Warn("CURSOR_SECRET is not set, page cursors will not survive a restart", nil)
+	}
+	webhookRepository := store.Webhooks
+	webhookService := service.NewWebhookService(webhookRepository)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Apply runtime settings on SIGHUP or when the config files change
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit, cfg.RateLimitBurst)
+	reloader := config.NewReloader(cfg, args)
+	requestLogger := middleware.NewRequestLogger(cfg.LogSampling)
+	reloader.OnReload(applyLogging)
+	reloader.OnReload(func(cfg *config.Config) {
+		rateLimiter.SetLimit(cfg.RateLimit, cfg.RateLimitBurst)
+		requestLogger.SetSampling(cfg.LogSampling)
+	})
+	go reloader.Watch(ctx, config.DefaultWatchInterval)
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			logger.Info("Received SIGHUP, reloading configuration", nil)
+			reloader.Reload()
+		}
+	}()
+
+	// Serve song reads from the read replicas, if any
+	if store.Replicas != nil {
+		songService.UseReplicas(store.Replicas)
+		go store.Replicas.Run(ctx, cfg.DBReplicaHealthInterval)
+	}
+
+	// Deliver song events to in-process subscribers such as webhooks
+	bus := events.NewBus()
+	dispatcher := webhook.NewDispatcher(webhookRepository, &http.Client{Timeout: 10 * time.Second})
+	bus.Subscribe(dispatcher.HandleEvent)
+	go dispatcher.Run(ctx)
+	songEvents := sse.NewBroker(sse.DefaultBufferSize)
+	bus.Subscribe(songEvents.HandleEvent)
+
+	// Relay events from the outbox to the bus and the configured broker
+	publisher, closePublisher, err := newEventPublisher(cfg, bus)
+	if err != nil {
+		logger.Error("Failed to create event publisher", logger.Fields{"error": err.Error()})
+		return
+	}
+	defer closePublisher()
+	relay := outbox.NewRelay(songRepository, publisher)
+	songService.AfterCommit(relay.Wake)
+	go relay.Run(ctx)
+
+	// Check song links in the background
+	if cfg.LinkCheckInterval > 0 {
+		checker := linkcheck.NewChecker()
+		checker.Timeout = cfg.LinkCheckTimeout
+		linkJob := linkcheck.NewJob(songRepository, checker)
+		linkJob.Interval = cfg.LinkCheckInterval
+		linkJob.MaxAge = cfg.LinkCheckMaxAge
+		linkJob.Concurrency = cfg.LinkCheckConcurrency
+		go linkJob.Run(ctx)
+	}
+
+	// Keep responses for retries with the same Idempotency-Key
+	idempotency := middleware.NewIdempotency(store.Idempotency, cfg.IdempotencyTTL)
+	go idempotency.Run(ctx, middleware.DefaultIdempotencyPurgeInterval)
+
+	// Serve the gRPC API on its own port
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		logger.Error("Failed to listen for gRPC", logger.Fields{"port": cfg.GRPCPort, "error": err.Error()})
+		return
+	}
+	grpcServer := grpcserver.NewServer(songService)
+	defer grpcServer.GracefulStop()
+	go func() {
+		logger.Info("gRPC server is starting", logger.Fields{"port": cfg.GRPCPort})
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Error("Failed to serve gRPC", logger.Fields{"error": err.Error()})
+		}
+	}()
+
+	graphQL, err := graphapi.NewExecutor(songService, graphapi.Limits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+	})
+	if err != nil {
+		logger.Error("Failed to build GraphQL schema", logger.Fields{"error": err.Error()})
+		return
+	}
 
-	// Initialize Gin engine
-	r := gin.Default()
+	// Initialize Gin engine with request IDs and structured request logs
+	r := gin.New()
+	r.Use(gin.Recovery(), middleware.RequestID(), requestLogger.Handler())
 
 	// Setup Swagger
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Rate limit the API routes registered below
+	r.Use(middleware.RateLimit(rateLimiter))
+
 	// Setup routes with services
-	router.SetupRoutes(r, songService)
+	router.SetupRoutes(r, cfg, idempotency, songService, webhookService, songEvents, graphQL)
 
 	// Start the server
 	logger.Info("Server is starting", logger.Fields{"port": cfg.ServerPort})
@@ -62,3 +199,30 @@ func main() {
 		logger.Error("Failed to start server", logger.Fields{"error": err.Error()})
 	}
 }
+
+// applyLogging sets the log level and format of cfg
+func applyLogging(cfg *config.Config) {
+	if err := logger.Configure(cfg.LogLevel, cfg.LogFormat); err != nil {
+		logger.Error("Invalid logging configuration", logger.Fields{"error": err.Error()})
+	}
+}
+
+// newEventPublisher combines the in-process bus with the broker selected by
+// cfg.EventPublisher. The returned function closes the broker connection.
+func newEventPublisher(cfg *config.Config, bus *events.Bus) (events.Publisher, func(), error) {
+	switch cfg.EventPublisher {
+	case "", "memory":
+		return bus, func() {}, nil
+	case "nats":
+		nats, err := events.NewNATSPublisher(cfg.NATSURL, cfg.NATSSubjectPrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		return events.Fanout{nats, bus}, func() { nats.Close() }, nil
+	case "kafka":
+		kafka := events.NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic)
+		return events.Fanout{kafka, bus}, func() { kafka.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown event publisher %q", cfg.EventPublisher)
+	}
+}
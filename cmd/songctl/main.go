@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+	"song-library/internal/songctl"
+)
+
+// songctl manages the song library from the command line, either directly
+// against the database configured in .env or through a running server
+func main() {
+	os.Exit(songctl.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -1,45 +1,213 @@
+// Package config loads the application configuration
 package config
 
 import (
-	"path/filepath"
+	"net/url"
 	"song-library/pkg/logger"
-
-	"os"
-
-	"github.com/joho/godotenv"
-	"github.com/sirupsen/logrus"
+	"time"
 )
 
 type Config struct {
+	// DBDriver selects the storage backend: "postgres", "sqlite" or "memory"
+	DBDriver   string
+	DBPath     string // SQLite database file
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+	// DatabaseURL is a complete Postgres connection string, either a
+	// postgres:// URL or key=value pairs; it overrides DBHost to DBName
+	DatabaseURL string
+	// TLS settings of the Postgres connection
+	DBSSLMode     string
+	DBSSLRootCert string
+	DBSSLCert     string
+	DBSSLKey      string
+	// Connection pool settings; zero keeps the database/sql default
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	// DBConnectRetries is how many times connecting is retried at startup,
+	// waiting DBConnectBackoff, doubled after every attempt
+	DBConnectRetries int
+	DBConnectBackoff time.Duration
+	// DBReplicaURLs are connection strings of read replicas. Reads go to a
+	// healthy replica unless the client wrote within DBReadYourWritesWindow.
+	DBReplicaURLs           []string
+	DBReadYourWritesWindow  time.Duration
+	DBReplicaHealthInterval time.Duration
+
 	APIBaseURL string
 	ServerPort string
+	GRPCPort   string
+	CacheSize  int
+	CacheTTL   time.Duration
+
+	// EventPublisher selects where outbox events are published besides the
+	// in-process subscribers: "memory" (none), "nats" or "kafka"
+	EventPublisher    string
+	NATSURL           string
+	NATSSubjectPrefix string
+	KafkaBrokers      []string
+	KafkaTopic        string
+
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+
+	// LogLevel is the minimum level of logged messages
+	LogLevel string
+	// LogFormat is "json" or "text"
+	LogFormat string
+	// LogSampling logs one in every LogSampling successful requests
+	LogSampling int
+	// RateLimit is the number of requests per second allowed per client,
+	// with bursts of up to RateLimitBurst; zero disables rate limiting
+	RateLimit      int
+	RateLimitBurst int
+	// IdempotencyTTL is how long responses are kept for retries with the
+	// same Idempotency-Key
+	IdempotencyTTL time.Duration
+	// CursorSecret signs the page cursors of song listings. When empty, a
+	// random key is used and cursors stop working on restart.
+	CursorSecret string
+	// LinkCheckInterval is how often song links are checked; zero disables
+	// the link checker. A link is checked again once its last check is
+	// older than LinkCheckMaxAge.
+	LinkCheckInterval    time.Duration
+	LinkCheckMaxAge      time.Duration
+	LinkCheckTimeout     time.Duration
+	LinkCheckConcurrency int
 }
 
-func LoadConfig(file string) (*Config, error) {
-	dir, err := os.Getwd()
+// Defaults used when the corresponding variables are not set
+const (
+	DefaultDBDriver             = "postgres"
+	DefaultDBPath               = "song_library.db"
+	DefaultDBSSLMode            = "disable"
+	DefaultDBMaxOpenConns       = 25
+	DefaultDBMaxIdleConns       = 5
+	DefaultDBConnMaxLifetime    = 30 * time.Minute
+	DefaultDBConnectRetries     = 5
+	DefaultDBConnectBackoff     = time.Second
+	DefaultDBReadYourWrites     = 5 * time.Second
+	DefaultDBReplicaHealth      = 10 * time.Second
+	DefaultServerPort           = "8080"
+	DefaultGRPCPort             = "9090"
+	DefaultCacheSize            = 1000
+	DefaultCacheTTL             = 30 * time.Second
+	DefaultEventPublisher       = "memory"
+	DefaultNATSSubjectPrefix    = "songs"
+	DefaultKafkaTopic           = "song-events"
+	DefaultGraphQLMaxDepth      = 10
+	DefaultGraphQLMaxComplexity = 1000
+	DefaultLogLevel             = "info"
+	DefaultLogFormat            = "json"
+	DefaultLogSampling          = 1
+	DefaultIdempotencyTTL       = 24 * time.Hour
+	DefaultLinkCheckInterval    = time.Hour
+	DefaultLinkCheckMaxAge      = 24 * time.Hour
+	DefaultLinkCheckTimeout     = 10 * time.Second
+	DefaultLinkCheckConcurrency = 4
+)
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		DBDriver:          DefaultDBDriver,
+		DBPath:            DefaultDBPath,
+		DBSSLMode:         DefaultDBSSLMode,
+		DBMaxOpenConns:    DefaultDBMaxOpenConns,
+		DBMaxIdleConns:    DefaultDBMaxIdleConns,
+		DBConnMaxLifetime: DefaultDBConnMaxLifetime,
+		DBConnectRetries:  DefaultDBConnectRetries,
+		DBConnectBackoff:  DefaultDBConnectBackoff,
+
+		DBReadYourWritesWindow:  DefaultDBReadYourWrites,
+		DBReplicaHealthInterval: DefaultDBReplicaHealth,
+
+		ServerPort: DefaultServerPort,
+		GRPCPort:   DefaultGRPCPort,
+		CacheSize:  DefaultCacheSize,
+		CacheTTL:   DefaultCacheTTL,
+
+		EventPublisher:    DefaultEventPublisher,
+		NATSSubjectPrefix: DefaultNATSSubjectPrefix,
+		KafkaTopic:        DefaultKafkaTopic,
+
+		GraphQLMaxDepth:      DefaultGraphQLMaxDepth,
+		GraphQLMaxComplexity: DefaultGraphQLMaxComplexity,
+
+		LogLevel:    DefaultLogLevel,
+		LogFormat:   DefaultLogFormat,
+		LogSampling: DefaultLogSampling,
+
+		IdempotencyTTL: DefaultIdempotencyTTL,
+
+		LinkCheckInterval:    DefaultLinkCheckInterval,
+		LinkCheckMaxAge:      DefaultLinkCheckMaxAge,
+		LinkCheckTimeout:     DefaultLinkCheckTimeout,
+		LinkCheckConcurrency: DefaultLinkCheckConcurrency,
+	}
+}
+
+// Load builds the configuration from the following sources, each
+// overriding the previous ones:
+//
+//   - the defaults
+//   - a YAML or TOML file given by -config or CONFIG_FILE
+//   - environment variables, including those of the -env file (".env")
+//   - command-line flags such as -server-port
+//
+// The result is validated. A missing .env file is not an error, since the
+// variables are usually set directly in containers.
+func Load(args []string) (*Config, error) {
+	flags, err := parseFlags(args)
 	if err != nil {
-		logger.Error("Failed to get current directory", logrus.Fields{"error": err.Error()})
 		return nil, err
 	}
-	envPath := filepath.Join(dir, file)
 
-	if err := godotenv.Load(envPath); err != nil {
-		logger.Error("Failed to load .env file", logrus.Fields{"path": envPath, "error": err.Error()})
+	env, err := environment(flags.envFile)
+	if err != nil {
 		return nil, err
 	}
 
-	return &Config{
-		DBHost:     os.Getenv("DB_HOST"),
-		DBPort:     os.Getenv("DB_PORT"),
-		DBUser:     os.Getenv("DB_USER"),
-		DBPassword: os.Getenv("DB_PASSWORD"),
-		DBName:     os.Getenv("DB_NAME"),
-		APIBaseURL: os.Getenv("API_BASE_URL"),
-		ServerPort: os.Getenv("SERVER_PORT"),
-	}, nil
+	cfg := Default()
+	if configFile := flags.configFile; configFile != "" {
+		values, err := readFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.apply(values, configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.apply(env, "environment"); err != nil {
+		return nil, err
+	}
+	if err := cfg.apply(flags.values, "flags"); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Keep credentials out of the logs
+	logger.RegisterSecret(cfg.DBPassword)
+	for _, dsn := range append([]string{cfg.DatabaseURL}, cfg.DBReplicaURLs...) {
+		if u, err := url.Parse(dsn); err == nil && u.User != nil {
+			if password, ok := u.User.Password(); ok {
+				logger.RegisterSecret(password)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// LoadConfig loads the configuration with the given .env file and no
+// command-line flags
+func LoadConfig(file string) (*Config, error) {
+	return Load([]string{"-env", file})
 }
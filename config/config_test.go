@@ -0,0 +1,97 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Layers(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	require.Nil(t, os.WriteFile(file, []byte(`
+db:
+  host: file-host
+  name: songs
+server_port: 8000
+cache_ttl: 1m
+kafka_brokers: [a:9092, b:9092]
+`), 0o600))
+	t.Setenv("SERVER_PORT", "8001")
+	t.Setenv("GRPC_PORT", "")
+
+	cfg, err := Load([]string{"-env", filepath.Join(dir, ".env"), "-config", file, "-cache-size", "10"})
+	require.Nil(t, err, "A missing .env file should not be an error")
+	assert.Equal(t, "file-host", cfg.DBHost, "Nested file sections should map to settings")
+	assert.Equal(t, time.Minute, cfg.CacheTTL)
+	assert.Equal(t, []string{"a:9092", "b:9092"}, cfg.KafkaBrokers)
+	assert.Equal(t, "8001", cfg.ServerPort, "The environment should override the file")
+	assert.Equal(t, 10, cfg.CacheSize, "Flags should override everything")
+	assert.Equal(t, DefaultGRPCPort, cfg.GRPCPort, "Empty variables should keep the default")
+}
+
+func TestLoad_TOML(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	require.Nil(t, os.WriteFile(file, []byte("db_driver = \"memory\"\n[graphql]\nmax_depth = 4\n"), 0o600))
+
+	cfg, err := Load([]string{"-env", "missing.env", "-config", file})
+	require.Nil(t, err)
+	assert.Equal(t, "memory", cfg.DBDriver)
+	assert.Equal(t, 4, cfg.GraphQLMaxDepth)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := Load([]string{"-env", "missing.env", "-db-driver", "memory", "-cache-ttl", "soon"})
+	assert.EqualError(t, err, `flags: CACHE_TTL: "soon" is not a duration such as 30s or 5m`)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.Nil(t, os.WriteFile(file, []byte("sever_port: 80\n"), 0o600))
+	_, err = Load([]string{"-env", "missing.env", "-config", file})
+	assert.ErrorContains(t, err, `unknown setting "sever_port"`)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.ServerPort = ""
+	cfg.DBSSLMode = "on"
+	cfg.CacheSize = -1
+
+	err := cfg.Validate()
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{
+		"DB_HOST and DB_NAME are required for the postgres driver unless DATABASE_URL is set",
+		`DB_SSLMODE must be one of disable, allow, prefer, require, verify-ca, verify-full, got "on"`,
+		"SERVER_PORT is required",
+		"CACHE_SIZE must not be negative",
+	}, validationErr.Problems)
+
+	cfg = Default()
+	cfg.DBDriver = "memory"
+	assert.Nil(t, cfg.Validate())
+
+	cfg.LinkCheckMaxAge = 0
+	require.ErrorAs(t, cfg.Validate(), &validationErr)
+	assert.Equal(t, []string{"LINK_CHECK_MAX_AGE must be positive"}, validationErr.Problems)
+	cfg.LinkCheckInterval = 0
+	assert.Nil(t, cfg.Validate(), "The max age should not matter with the link checker disabled")
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.DBPassword = "hunter2"
+	cfg.DatabaseURL = "postgres://app:s3cret@db:5432/songs"
+
+	var out bytes.Buffer
+	require.Nil(t, cfg.Print(&out))
+	assert.Contains(t, out.String(), "DB_PASSWORD=***\n")
+	assert.Contains(t, out.String(), "DATABASE_URL=postgres://app:***@db:5432/songs\n")
+	assert.Contains(t, out.String(), "SERVER_PORT=8080\n")
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.NotContains(t, out.String(), "s3cret")
+}
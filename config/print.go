@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"song-library/pkg/logger"
+	"strings"
+	"time"
+)
+
+// Print writes the configuration to w as KEY=value lines, the format of a
+// .env file. Passwords and other secrets are redacted.
+func (c *Config) Print(w io.Writer) error {
+	for _, s := range c.settings() {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", s.key, s.display()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// display formats the value of s with secrets redacted
+func (s setting) display() string {
+	var v string
+	switch dst := s.dst.(type) {
+	case *string:
+		v = *dst
+	case *[]string:
+		v = strings.Join(*dst, ",")
+	case *int:
+		v = fmt.Sprint(*dst)
+	case *time.Duration:
+		v = dst.String()
+	}
+	if s.secret && v != "" {
+		v = redact(s.key, v)
+	}
+	return v
+}
+
+// redact hides secrets in v. Connection strings keep everything but their
+// password, so that they can still be checked.
+func redact(key, v string) string {
+	switch key {
+	case "DATABASE_URL", "DB_REPLICA_URLS", "NATS_URL":
+		if redacted := logger.Redact(v); redacted != v {
+			return redacted
+		}
+		if !strings.Contains(v, "://") && !strings.Contains(v, "=") {
+			return logger.Redacted
+		}
+		return v
+	default:
+		return logger.Redacted
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"song-library/pkg/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWatchInterval is how often the configuration files are checked
+// for changes
+const DefaultWatchInterval = 5 * time.Second
+
+// Change describes a setting whose value differs between two
+// configurations. Secret values are redacted.
+type Change struct {
+	Key  string
+	From string
+	To   string
+	// Reloadable reports whether the change can be applied at runtime
+	Reloadable bool
+}
+
+// Diff lists the settings that differ from c in other
+func (c *Config) Diff(other *Config) []Change {
+	var changes []Change
+	newSettings := other.settings()
+	for i, s := range c.settings() {
+		n := newSettings[i]
+		if reflect.DeepEqual(reflect.ValueOf(s.dst).Elem().Interface(), reflect.ValueOf(n.dst).Elem().Interface()) {
+			continue
+		}
+		changes = append(changes, Change{Key: s.key, From: s.display(), To: n.display(), Reloadable: s.reload})
+	}
+	return changes
+}
+
+// Reloader holds the current configuration and reloads the settings that
+// are safe to change at runtime, such as the log level and rate limits.
+// Other settings keep their startup values until the server restarts.
+type Reloader struct {
+	args     []string
+	current  atomic.Pointer[Config]
+	mu       sync.Mutex
+	onReload []func(*Config)
+}
+
+// NewReloader creates a Reloader for cfg, which was loaded from args
+func NewReloader(cfg *Config, args []string) *Reloader {
+	r := &Reloader{args: args}
+	r.current.Store(cfg)
+	return r
+}
+
+// Current returns the configuration in effect. It must not be modified.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after
+// every reload that changed a setting
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReload = append(r.onReload, fn)
+}
+
+// Reload loads the configuration sources again. An invalid configuration
+// is rejected and the current one kept. Otherwise the reloadable settings
+// are replaced all at once and every change is logged.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, err := Load(r.args)
+	if err != nil {
+		logger.Error("Configuration reload rejected, keeping the current configuration", logger.Fields{"error": err.Error()})
+		return err
+	}
+
+	old := r.Current()
+	next := *old
+	nextSettings, loadedSettings := next.settings(), loaded.settings()
+	changes := old.Diff(loaded)
+	applied := 0
+	for _, change := range changes {
+		if !change.Reloadable {
+			logger.Info("Configuration change requires a restart", logger.Fields{"key": change.Key, "from": change.From, "to": change.To})
+			continue
+		}
+		for i, s := range nextSettings {
+			if s.key == change.Key {
+				reflect.ValueOf(s.dst).Elem().Set(reflect.ValueOf(loadedSettings[i].dst).Elem())
+			}
+		}
+		logger.Info("Configuration changed", logger.Fields{"key": change.Key, "from": change.From, "to": change.To})
+		applied++
+	}
+	if applied == 0 {
+		logger.Info("Configuration reloaded without runtime changes", nil)
+		return nil
+	}
+
+	r.current.Store(&next)
+	for _, fn := range r.onReload {
+		fn(&next)
+	}
+	return nil
+}
+
+// Watch reloads the configuration whenever the config file or the .env
+// file changes, checking every interval until ctx is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	flags, err := parseFlags(r.args)
+	if err != nil {
+		return
+	}
+	files := []string{flags.envFile}
+	if flags.configFile != "" {
+		files = append(files, flags.configFile)
+	}
+
+	modified := func() []time.Time {
+		times := make([]time.Time, len(files))
+		for i, file := range files {
+			if info, err := os.Stat(file); err == nil {
+				times[i] = info.ModTime()
+			}
+		}
+		return times
+	}
+	last := modified()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if current := modified(); !reflect.DeepEqual(current, last) {
+				last = current
+				r.Reload()
+			}
+		}
+	}
+}
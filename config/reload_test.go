@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		require.Nil(t, os.WriteFile(file, []byte(content), 0o600))
+	}
+	write("db_driver: memory\nlog_level: info\nrate_limit: 10\n")
+	args := []string{"-env", "missing.env", "-config", file}
+	cfg, err := Load(args)
+	require.Nil(t, err)
+
+	reloader := NewReloader(cfg, args)
+	var applied []*Config
+	reloader.OnReload(func(cfg *Config) { applied = append(applied, cfg) })
+
+	write("db_driver: memory\nlog_level: debug\nrate_limit: 20\nserver_port: 9000\n")
+	require.Nil(t, reloader.Reload())
+	current := reloader.Current()
+	assert.Equal(t, "debug", current.LogLevel)
+	assert.Equal(t, 20, current.RateLimit)
+	assert.Equal(t, DefaultServerPort, current.ServerPort, "Settings that need a restart should keep their value")
+	assert.Equal(t, "info", cfg.LogLevel, "The previous configuration should not be modified")
+	assert.Equal(t, []*Config{current}, applied)
+
+	write("db_driver: memory\nlog_level: loud\n")
+	assert.NotNil(t, reloader.Reload(), "An invalid configuration should be rejected")
+	assert.Equal(t, current, reloader.Current(), "The current configuration should be kept")
+	assert.Len(t, applied, 1)
+}
+
+func TestDiff(t *testing.T) {
+	old, next := Default(), Default()
+	next.DBPassword = "secret"
+	next.LogLevel = "warn"
+
+	assert.Equal(t, []Change{
+		{Key: "DB_PASSWORD", From: "", To: "***"},
+		{Key: "LOG_LEVEL", From: "info", To: "warn", Reloadable: true},
+	}, old.Diff(next))
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// setting binds a configuration field to its environment variable. The
+// same name, lower-cased, is the key in config files, and lower-cased with
+// dashes it is the command-line flag: DB_HOST, db_host and -db-host.
+type setting struct {
+	key    string
+	dst    interface{}
+	usage  string
+	secret bool
+	// reload marks settings that can change while the server runs
+	reload bool
+}
+
+// settings lists the fields of c in the order they are printed
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "DB_DRIVER", dst: &c.DBDriver, usage: "storage backend: postgres, sqlite or memory"},
+		{key: "DB_PATH", dst: &c.DBPath, usage: "SQLite database file"},
+		{key: "DB_HOST", dst: &c.DBHost, usage: "Postgres host"},
+		{key: "DB_PORT", dst: &c.DBPort, usage: "Postgres port"},
+		{key: "DB_USER", dst: &c.DBUser, usage: "Postgres user"},
+		{key: "DB_PASSWORD", dst: &c.DBPassword, usage: "Postgres password", secret: true},
+		{key: "DB_NAME", dst: &c.DBName, usage: "Postgres database"},
+		{key: "DATABASE_URL", dst: &c.DatabaseURL, usage: "Postgres connection string; overrides DB_HOST to DB_NAME", secret: true},
+		{key: "DB_SSLMODE", dst: &c.DBSSLMode, usage: "Postgres sslmode"},
+		{key: "DB_SSLROOTCERT", dst: &c.DBSSLRootCert, usage: "CA certificate file"},
+		{key: "DB_SSLCERT", dst: &c.DBSSLCert, usage: "client certificate file"},
+		{key: "DB_SSLKEY", dst: &c.DBSSLKey, usage: "client key file"},
+		{key: "DB_MAX_OPEN_CONNS", dst: &c.DBMaxOpenConns, usage: "maximum open connections"},
+		{key: "DB_MAX_IDLE_CONNS", dst: &c.DBMaxIdleConns, usage: "maximum idle connections"},
+		{key: "DB_CONN_MAX_LIFETIME", dst: &c.DBConnMaxLifetime, usage: "maximum connection lifetime"},
+		{key: "DB_CONNECT_RETRIES", dst: &c.DBConnectRetries, usage: "connection retries at startup"},
+		{key: "DB_CONNECT_BACKOFF", dst: &c.DBConnectBackoff, usage: "wait before the first connection retry"},
+		{key: "DB_REPLICA_URLS", dst: &c.DBReplicaURLs, usage: "comma-separated read replica connection strings", secret: true},
+		{key: "DB_READ_YOUR_WRITES_WINDOW", dst: &c.DBReadYourWritesWindow, usage: "how long a client reads from the primary after writing"},
+		{key: "DB_REPLICA_HEALTH_INTERVAL", dst: &c.DBReplicaHealthInterval, usage: "replica health check interval"},
+		{key: "API_BASE_URL", dst: &c.APIBaseURL, usage: "base URL of the external API"},
+		{key: "SERVER_PORT", dst: &c.ServerPort, usage: "REST server port"},
+		{key: "GRPC_PORT", dst: &c.GRPCPort, usage: "gRPC server port"},
+		{key: "CACHE_SIZE", dst: &c.CacheSize, usage: "song cache entries, 0 disables the cache"},
+		{key: "CACHE_TTL", dst: &c.CacheTTL, usage: "song cache TTL, 0 disables the cache"},
+		{key: "EVENT_PUBLISHER", dst: &c.EventPublisher, usage: "event broker: memory, nats or kafka"},
+		{key: "NATS_URL", dst: &c.NATSURL, usage: "NATS server URL", secret: true},
+		{key: "NATS_SUBJECT_PREFIX", dst: &c.NATSSubjectPrefix, usage: "NATS subject prefix"},
+		{key: "KAFKA_BROKERS", dst: &c.KafkaBrokers, usage: "comma-separated Kafka brokers"},
+		{key: "KAFKA_TOPIC", dst: &c.KafkaTopic, usage: "Kafka topic"},
+		{key: "GRAPHQL_MAX_DEPTH", dst: &c.GraphQLMaxDepth, usage: "maximum GraphQL query depth, 0 for no limit"},
+		{key: "GRAPHQL_MAX_COMPLEXITY", dst: &c.GraphQLMaxComplexity, usage: "maximum GraphQL query complexity, 0 for no limit"},
+		{key: "LOG_LEVEL", dst: &c.LogLevel, usage: "minimum log level: debug, info, warn or error", reload: true},
+		{key: "LOG_FORMAT", dst: &c.LogFormat, usage: "log format: json or text", reload: true},
+		{key: "LOG_SAMPLING", dst: &c.LogSampling, usage: "log one in N successful requests", reload: true},
+		{key: "RATE_LIMIT", dst: &c.RateLimit, usage: "requests per second per client, 0 disables rate limiting", reload: true},
+		{key: "RATE_LIMIT_BURST", dst: &c.RateLimitBurst, usage: "request burst per client, defaults to RATE_LIMIT", reload: true},
+		{key: "IDEMPOTENCY_TTL", dst: &c.IdempotencyTTL, usage: "how long responses are kept for Idempotency-Key retries"},
+		{key: "CURSOR_SECRET", dst: &c.CursorSecret, usage: "key signing page cursors, random if unset", secret: true},
+		{key: "LINK_CHECK_INTERVAL", dst: &c.LinkCheckInterval, usage: "how often song links are checked, 0 disables the link checker"},
+		{key: "LINK_CHECK_MAX_AGE", dst: &c.LinkCheckMaxAge, usage: "age after which a link is checked again"},
+		{key: "LINK_CHECK_TIMEOUT", dst: &c.LinkCheckTimeout, usage: "timeout of a link check"},
+		{key: "LINK_CHECK_CONCURRENCY", dst: &c.LinkCheckConcurrency, usage: "links checked at the same time"},
+	}
+}
+
+// apply sets the fields named in values, keyed by environment variable.
+// Empty values are ignored.
+func (c *Config) apply(values map[string]string, source string) error {
+	byKey := make(map[string]setting)
+	for _, s := range c.settings() {
+		byKey[s.key] = s
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v := strings.TrimSpace(values[key])
+		s, ok := byKey[key]
+		if !ok {
+			return errors.Errorf("%s: unknown setting %q", source, strings.ToLower(key))
+		}
+		if v == "" {
+			continue
+		}
+		if err := set(s.dst, v); err != nil {
+			return errors.Errorf("%s: %s: %v", source, key, err)
+		}
+	}
+	return nil
+}
+
+func set(dst interface{}, v string) error {
+	switch dst := dst.(type) {
+	case *string:
+		*dst = v
+	case *[]string:
+		*dst = nil
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*dst = append(*dst, item)
+			}
+		}
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		*dst = n
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 5m", v)
+		}
+		*dst = d
+	default:
+		panic(fmt.Sprintf("config: unsupported setting type %T", dst))
+	}
+	return nil
+}
+
+// environment returns the variables of all settings from envFile and the
+// process environment, which takes precedence. A missing envFile is
+// ignored.
+func environment(envFile string) (map[string]string, error) {
+	fileValues, err := godotenv.Read(envFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "unable to load %s", envFile)
+	}
+
+	values := make(map[string]string)
+	for _, s := range (&Config{}).settings() {
+		if v, ok := os.LookupEnv(s.key); ok {
+			values[s.key] = v
+		} else if v, ok := fileValues[s.key]; ok {
+			values[s.key] = v
+		}
+	}
+	return values, nil
+}
+
+// readFile reads a YAML or TOML config file, chosen by its extension.
+// Nested sections are joined to their keys with underscores, so
+// "db: {host: x}" sets DB_HOST.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read config file")
+	}
+
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, errors.Errorf("%s: unsupported config file format, use .yaml or .toml", path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid config file %s", path)
+	}
+
+	values := make(map[string]string)
+	flatten(values, "", doc)
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, doc map[string]interface{}) {
+	for key, v := range doc {
+		key = strings.ToUpper(prefix + key)
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flatten(values, key+"_", v)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// flagValues holds the parsed command-line flags
+type flagValues struct {
+	configFile string
+	envFile    string
+	// values holds the settings given on the command line, keyed by
+	// environment variable
+	values map[string]string
+}
+
+// parseFlags parses the command-line flags. Every setting has a flag named
+// after its environment variable.
+func parseFlags(args []string) (*flagValues, error) {
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	result := &flagValues{values: make(map[string]string)}
+	flags.StringVar(&result.configFile, "config", "", "YAML or TOML config file")
+	flags.StringVar(&result.envFile, "env", ".env", "file with environment variables")
+	settings := (&Config{}).settings()
+	for _, s := range settings {
+		flags.String(flagName(s.key), "", s.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, errors.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	if result.configFile == "" {
+		result.configFile = os.Getenv("CONFIG_FILE")
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if flagName(s.key) == f.Name {
+				result.values[s.key] = f.Value.String()
+			}
+		}
+	})
+	return result, nil
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// Usage writes the command-line flags and their descriptions to w
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "  -config file\n    \tYAML or TOML config file (CONFIG_FILE)")
+	fmt.Fprintln(w, "  -env file\n    \tfile with environment variables (default \".env\")")
+	for _, s := range (&Config{}).settings() {
+		fmt.Fprintf(w, "  -%s\n    \t%s (%s)\n", flagName(s.key), s.usage, s.key)
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks that the configuration is complete and consistent. It
+// returns a *ValidationError.
+func (c *Config) Validate() error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	oneOf := func(key, v string, allowed ...string) {
+		for _, a := range allowed {
+			if v == a {
+				return
+			}
+		}
+		problem("%s must be one of %s, got %q", key, strings.Join(allowed, ", "), v)
+	}
+	port := func(key, v string, required bool) {
+		if v == "" {
+			if required {
+				problem("%s is required", key)
+			}
+			return
+		}
+		if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 65535 {
+			problem("%s must be a port between 1 and 65535, got %q", key, v)
+		}
+	}
+
+	oneOf("DB_DRIVER", c.DBDriver, "postgres", "sqlite", "memory")
+	switch c.DBDriver {
+	case "postgres":
+		if c.DatabaseURL == "" && (c.DBHost == "" || c.DBName == "") {
+			problem("DB_HOST and DB_NAME are required for the postgres driver unless DATABASE_URL is set")
+		}
+	case "sqlite":
+		if c.DBPath == "" {
+			problem("DB_PATH is required for the sqlite driver")
+		}
+	}
+	if len(c.DBReplicaURLs) > 0 && c.DBDriver != "postgres" {
+		problem("DB_REPLICA_URLS requires the postgres driver")
+	}
+	if len(c.DBReplicaURLs) > 0 && c.DBReplicaHealthInterval <= 0 {
+		problem("DB_REPLICA_HEALTH_INTERVAL must be positive")
+	}
+	port("DB_PORT", c.DBPort, false)
+	oneOf("DB_SSLMODE", c.DBSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	if (c.DBSSLCert == "") != (c.DBSSLKey == "") {
+		problem("DB_SSLCERT and DB_SSLKEY must be set together")
+	}
+
+	port("SERVER_PORT", c.ServerPort, true)
+	port("GRPC_PORT", c.GRPCPort, true)
+	if c.ServerPort != "" && c.ServerPort == c.GRPCPort {
+		problem("SERVER_PORT and GRPC_PORT must differ")
+	}
+
+	oneOf("EVENT_PUBLISHER", c.EventPublisher, "memory", "nats", "kafka")
+	if c.EventPublisher == "nats" && c.NATSURL == "" {
+		problem("NATS_URL is required for the nats event publisher")
+	}
+	if c.EventPublisher == "kafka" && len(c.KafkaBrokers) == 0 {
+		problem("KAFKA_BROKERS is required for the kafka event publisher")
+	}
+
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		problem("LOG_LEVEL must be one of debug, info, warn or error, got %q", c.LogLevel)
+	}
+	oneOf("LOG_FORMAT", c.LogFormat, "json", "text")
+	if c.LogSampling < 1 {
+		problem("LOG_SAMPLING must be at least 1")
+	}
+
+	if c.IdempotencyTTL == 0 {
+		problem("IDEMPOTENCY_TTL must be positive")
+	}
+	if c.LinkCheckInterval > 0 {
+		if c.LinkCheckTimeout == 0 {
+			problem("LINK_CHECK_TIMEOUT must be positive")
+		}
+		if c.LinkCheckConcurrency == 0 {
+			problem("LINK_CHECK_CONCURRENCY must be at least 1")
+		}
+		// Otherwise every link is due again as soon as it was checked
+		if c.LinkCheckMaxAge == 0 {
+			problem("LINK_CHECK_MAX_AGE must be positive")
+		}
+	}
+
+	for _, s := range c.settings() {
+		var negative bool
+		switch v := s.dst.(type) {
+		case *int:
+			negative = *v < 0
+		case *time.Duration:
+			negative = *v < 0
+		}
+		if negative {
+			problem("%s must not be negative", s.key)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU(2)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+	c.Get("a")
+	c.Set("c", []byte("3"), 0)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "Least recently used entry should be evicted")
+	value, ok := c.Get("a")
+	assert.True(t, ok, "Recently used entry should be kept")
+	assert.Equal(t, "1", string(value))
+	assert.Equal(t, 2, c.Len(), "Cache should not exceed its capacity")
+}
+
+func TestLRU_Expires(t *testing.T) {
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+	c.Set("a", []byte("1"), time.Minute)
+
+	_, ok := c.Get("a")
+	assert.True(t, ok, "Entry should be available before its TTL")
+
+	now = now.Add(time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok, "Entry should expire after its TTL")
+	assert.Equal(t, 0, c.Len(), "Expired entry should be dropped")
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"song-library/config"
+	"song-library/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Database drivers selectable with DB_DRIVER
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	// DriverMemory keeps all data in process memory; it has no database
+	// connection and is handled by the storage package
+	DriverMemory = "memory"
+)
+
+// maxConnectBackoff caps the wait between connection attempts
+const maxConnectBackoff = 30 * time.Second
+
+// sleep is replaced in tests
+var sleep = time.Sleep
+
+// Connect initializes the database connection and applies migrations.
+// Connecting is retried with exponential backoff while the database is
+// starting up.
+func Connect(cfg *config.Config) (*gorm.DB, error) {
+	var open func() gorm.Dialector
+	switch cfg.DBDriver {
+	case DriverPostgres, "":
+		dsn, err := PostgresDSN(cfg)
+		if err != nil {
+			return nil, err
+		}
+		open = func() gorm.Dialector { return postgres.Open(dsn) }
+	case DriverSQLite:
+		open = func() gorm.Dialector { return sqlite.Open(cfg.DBPath + "?_busy_timeout=5000") }
+	default:
+		return nil, errors.Errorf("unsupported database driver %q", cfg.DBDriver)
+	}
+
+	db, err := connectWithRetry(cfg, func() (*gorm.DB, error) {
+		return gorm.Open(open(), &gorm.Config{Logger: NewLogger(), TranslateError: true})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to database")
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DBDriver == DriverSQLite {
+		// SQLite allows a single writer; sharing one connection avoids
+		// "database is locked" errors and keeps :memory: databases intact
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		configurePool(sqlDB, cfg)
+	}
+
+	// Run migrations
+	err = Migrate(db)
+	if err != nil {
+		logger.Error("Failed to run migrations", logrus.Fields{"error": err.Error()})
+		return nil, errors.Wrap(err, "migrations failed")
+	}
+
+	logger.Info("Database connected and migrations applied successfully", logrus.Fields{
+		"driver": cfg.DBDriver,
+		"host":   cfg.DBHost,
+		"port":   cfg.DBPort,
+	})
+	return db, nil
+}
+
+// ConnectReplicas opens a connection to every read replica in
+// cfg.DBReplicaURLs. Replicas are not pinged, so one that is down at startup
+// is only taken out of rotation by the health checks.
+func ConnectReplicas(cfg *config.Config) ([]*gorm.DB, error) {
+	if len(cfg.DBReplicaURLs) > 0 && cfg.DBDriver != DriverPostgres && cfg.DBDriver != "" {
+		return nil, errors.Errorf("read replicas are not supported by the %s driver", cfg.DBDriver)
+	}
+
+	var replicas []*gorm.DB
+	for i, replicaURL := range cfg.DBReplicaURLs {
+		dsn, err := postgresDSN(cfg, strings.TrimSpace(replicaURL))
+		if err != nil {
+			return nil, errors.Wrapf(err, "replica %d", i)
+		}
+		replica, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: NewLogger(), TranslateError: true, DisableAutomaticPing: true})
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to open replica %d", i)
+		}
+		sqlDB, err := replica.DB()
+		if err != nil {
+			return nil, err
+		}
+		configurePool(sqlDB, cfg)
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
+}
+
+func configurePool(sqlDB *sql.DB, cfg *config.Config) {
+	if cfg.DBMaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	}
+	if cfg.DBMaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	}
+	if cfg.DBConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	}
+}
+
+// connectWithRetry calls open until it succeeds or cfg.DBConnectRetries
+// retries have failed
+func connectWithRetry(cfg *config.Config, open func() (*gorm.DB, error)) (*gorm.DB, error) {
+	backoff := cfg.DBConnectBackoff
+	for attempt := 0; ; attempt++ {
+		db, err := open()
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= cfg.DBConnectRetries {
+			logger.Error("Failed to connect to the database", logrus.Fields{
+				"driver":   cfg.DBDriver,
+				"attempts": attempt + 1,
+				"error":    err.Error(),
+			})
+			return nil, err
+		}
+
+		logger.Error("Database not reachable, retrying", logrus.Fields{
+			"driver":   cfg.DBDriver,
+			"attempt":  attempt + 1,
+			"retry_in": backoff.String(),
+			"error":    err.Error(),
+		})
+		sleep(backoff)
+		if backoff *= 2; backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+// PostgresDSN returns the connection string for cfg. DatabaseURL is used as
+// is when set, with the TLS settings added unless it already has them.
+func PostgresDSN(cfg *config.Config) (string, error) {
+	return postgresDSN(cfg, cfg.DatabaseURL)
+}
+
+// postgresDSN applies the TLS settings of cfg to databaseURL, or builds the
+// connection string from the DB_* settings when it is empty
+func postgresDSN(cfg *config.Config, databaseURL string) (string, error) {
+	tls := [][2]string{
+		{"sslmode", cfg.DBSSLMode},
+		{"sslrootcert", cfg.DBSSLRootCert},
+		{"sslcert", cfg.DBSSLCert},
+		{"sslkey", cfg.DBSSLKey},
+	}
+
+	if strings.HasPrefix(databaseURL, "postgres://") || strings.HasPrefix(databaseURL, "postgresql://") {
+		u, err := url.Parse(databaseURL)
+		if err != nil {
+			// The error would echo the URL and its password
+			return "", errors.New("invalid database URL")
+		}
+		query := u.Query()
+		for _, opt := range tls {
+			if opt[1] != "" && query.Get(opt[0]) == "" {
+				query.Set(opt[0], opt[1])
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	var pairs []string
+	if databaseURL != "" {
+		pairs = append(pairs, databaseURL)
+	} else {
+		for _, opt := range [][2]string{
+			{"host", cfg.DBHost},
+			{"port", cfg.DBPort},
+			{"user", cfg.DBUser},
+			{"password", cfg.DBPassword},
+			{"dbname", cfg.DBName},
+		} {
+			if opt[1] != "" {
+				pairs = append(pairs, opt[0]+"="+quoteDSNValue(opt[1]))
+			}
+		}
+	}
+	for _, opt := range tls {
+		if opt[1] != "" && !strings.Contains(databaseURL, opt[0]+"=") {
+			pairs = append(pairs, opt[0]+"="+quoteDSNValue(opt[1]))
+		}
+	}
+	return strings.Join(pairs, " "), nil
+}
+
+// quoteDSNValue quotes a key=value DSN value when it contains spaces or
+// quotes
+func quoteDSNValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return fmt.Sprintf("'%s'", strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v))
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"song-library/config"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	dsn, err := PostgresDSN(&config.Config{
+		DBHost:        "db",
+		DBPort:        "5432",
+		DBUser:        "app",
+		DBPassword:    "it's secret",
+		DBName:        "songs",
+		DBSSLMode:     "verify-full",
+		DBSSLRootCert: "/certs/ca.pem",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `host=db port=5432 user=app password='it\'s secret' dbname=songs sslmode=verify-full sslrootcert=/certs/ca.pem`, dsn)
+
+	dsn, err = PostgresDSN(&config.Config{
+		DatabaseURL: "postgres://app:pw@db:5432/songs?sslmode=require",
+		DBSSLMode:   "disable",
+		DBSSLCert:   "/certs/client.pem",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres://app:pw@db:5432/songs?sslcert=%2Fcerts%2Fclient.pem&sslmode=require", dsn, "Settings in the URL should win")
+
+	dsn, _ = PostgresDSN(&config.Config{DatabaseURL: "host=db sslmode=require", DBSSLMode: "disable"})
+	assert.Equal(t, "host=db sslmode=require", dsn)
+
+	_, err = PostgresDSN(&config.Config{DatabaseURL: "postgres://app:p%zz@db"})
+	assert.NotContains(t, err.Error(), "p%zz", "Errors should not echo the URL")
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	defer func() { sleep = time.Sleep }()
+
+	cfg := &config.Config{DBConnectRetries: 3, DBConnectBackoff: 10 * time.Second}
+	calls := 0
+	_, err := connectWithRetry(cfg, func() (*gorm.DB, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return &gorm.DB{}, nil
+	})
+	assert.Nil(t, err, "Connecting should succeed once the database is up")
+	assert.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second}, waits, "The backoff should double")
+
+	waits, calls = nil, 0
+	_, err = connectWithRetry(cfg, func() (*gorm.DB, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 4, calls, "Connecting should stop after the configured retries")
+	assert.Equal(t, 30*time.Second, waits[2], "The backoff should be capped")
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"song-library/pkg/logger"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// SlowQueryThreshold is the duration above which queries are logged as
+// slow
+const SlowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger sends gorm's log output through pkg/logger, so that queries
+// run with a request context carry its request ID, route and user
+type gormLogger struct {
+	level gormlogger.LogLevel
+}
+
+// NewLogger returns a gorm logger that logs failed and slow queries, and
+// every query at the debug level
+func NewLogger() gormlogger.Interface {
+	return &gormLogger{level: gormlogger.Info}
+}
+
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &gormLogger{level: level}
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		logger.InfoContext(ctx, fmt.Sprintf(msg, args...), nil)
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		logger.WarnContext(ctx, fmt.Sprintf(msg, args...), nil)
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		logger.ErrorContext(ctx, fmt.Sprintf(msg, args...), nil)
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	query := func() logger.Fields {
+		sql, rows := fc()
+		return logger.Fields{"sql": sql, "rows": rows, "query_ms": logger.Milliseconds(elapsed)}
+	}
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		fields := query()
+		fields["error"] = err.Error()
+		logger.ErrorContext(ctx, "Query failed", fields)
+	case elapsed > SlowQueryThreshold && l.level >= gormlogger.Warn:
+		logger.WarnContext(ctx, "Slow query", query())
+	case l.level >= gormlogger.Info && logger.DebugEnabled():
+		logger.DebugContext(ctx, "Query", query())
+	}
+}
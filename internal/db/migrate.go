@@ -0,0 +1,197 @@
+package db
+
+import (
+	"fmt"
+	"song-library/internal/dedupe"
+	"song-library/internal/language"
+	"song-library/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// models lists every model with a database table
+var models = []interface{}{
+	&model.Song{},
+	&model.SongRevision{},
+	&model.SongTranslation{},
+	&model.LinkCheck{},
+	&model.AuditEntry{},
+	&model.Webhook{},
+	&model.WebhookDelivery{},
+	&model.OutboxEvent{},
+	&model.IdempotencyKey{},
+}
+
+// Migrate creates or updates the tables of all models
+func Migrate(conn *gorm.DB) error {
+	if err := conn.AutoMigrate(models...); err != nil {
+		return err
+	}
+	if err := migrateNormalizedKeys(conn); err != nil {
+		return err
+	}
+	if err := migrateLanguages(conn); err != nil {
+		return err
+	}
+	if err := migrateSearchColumns(conn); err != nil {
+		return err
+	}
+	if err := migrateSortIndexes(conn); err != nil {
+		return err
+	}
+	if err := migrateDeliveryEventIndex(conn); err != nil {
+		return err
+	}
+	return migrateAuditLogAppendOnly(conn)
+}
+
+// auditLogTriggers make the audit log append-only: updates and deletes of
+// entries fail
+var auditLogTriggers = map[string][]string{
+	"postgres": {
+		`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END
+$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log",
+		"CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log FOR EACH ROW EXECUTE PROCEDURE audit_log_append_only()",
+		"DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log",
+		"CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log FOR EACH STATEMENT EXECUTE PROCEDURE audit_log_append_only()",
+	},
+	"sqlite": {
+		"CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END",
+		"CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END",
+	},
+}
+
+// migrateAuditLogAppendOnly installs the triggers that reject changes to
+// audit log entries
+func migrateAuditLogAppendOnly(conn *gorm.DB) error {
+	for _, sql := range auditLogTriggers[conn.Dialector.Name()] {
+		if err := conn.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateLanguages detects the lyrics language of songs stored before
+// language detection
+func migrateLanguages(conn *gorm.DB) error {
+	var songs []model.Song
+	err := conn.Select("id", "text").Where("(language IS NULL OR language = '') AND text <> ''").Find(&songs).Error
+	if err != nil {
+		return err
+	}
+	for _, song := range songs {
+		err := conn.Model(&model.Song{}).Where("id = ?", song.ID).UpdateColumn("language", language.Detect(song.Text)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSearchColumns fills in the search columns of songs stored before
+// they were added
+func migrateSearchColumns(conn *gorm.DB) error {
+	var songs []model.Song
+	err := conn.Select("id", "group_name", "song_name", "text").Where("search_group_name IS NULL").Find(&songs).Error
+	if err != nil {
+		return err
+	}
+	for _, song := range songs {
+		err := conn.Model(&model.Song{}).Where("id = ?", song.ID).UpdateColumns(map[string]interface{}{
+			"search_group_name": language.Searchable(song.GroupName),
+			"search_song_name":  language.Searchable(song.SongName),
+			"search_text":       language.Searchable(song.Text),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortColumns are the song columns that listings can be sorted by, besides
+// the ID
+var sortColumns = []string{"group_name", "song_name", "release_date", "created_at", "updated_at"}
+
+// migrateSortIndexes indexes every sort column together with the ID, the
+// tiebreaker of every sort
+func migrateSortIndexes(conn *gorm.DB) error {
+	for _, column := range sortColumns {
+		sql := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_songs_%s_id ON songs (%s, id)", column, column)
+		if err := conn.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliveryEventIndex keeps a webhook from getting an event twice when the
+// event is published again
+const deliveryEventIndex = "idx_webhook_deliveries_webhook_id_event_id"
+
+// migrateDeliveryEventIndex makes deliveries unique per webhook and event.
+// Duplicates queued before are dropped first, keeping the oldest.
+func migrateDeliveryEventIndex(conn *gorm.DB) error {
+	if conn.Migrator().HasIndex(&model.WebhookDelivery{}, deliveryEventIndex) {
+		return nil
+	}
+	err := conn.Exec(`DELETE FROM webhook_deliveries WHERE id NOT IN
+		(SELECT MIN(id) FROM webhook_deliveries GROUP BY webhook_id, event_id)`).Error
+	if err != nil {
+		return err
+	}
+	return conn.Exec("CREATE UNIQUE INDEX " + deliveryEventIndex + " ON webhook_deliveries (webhook_id, event_id)").Error
+}
+
+// migrateNormalizedKeys fills in the normalized keys of songs stored before
+// duplicate detection and then makes the keys unique. Songs that already
+// duplicate another get their ID appended to the key, so that they can be
+// found in the duplicates report and merged.
+func migrateNormalizedKeys(conn *gorm.DB) error {
+	var songs []model.Song
+	if err := conn.Select("id", "group_name", "song_name", "normalized_key").Order("id").Find(&songs).Error; err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(songs))
+	for _, song := range songs {
+		taken[song.NormalizedKey] = true
+	}
+	for _, song := range songs {
+		if song.NormalizedKey != "" {
+			continue
+		}
+		key := dedupe.Key(song.GroupName, song.SongName)
+		if taken[key] {
+			key = fmt.Sprintf("%s#%d", key, song.ID)
+		}
+		taken[key] = true
+		err := conn.Model(&model.Song{}).Where("id = ?", song.ID).UpdateColumn("normalized_key", key).Error
+		if err != nil {
+			return err
+		}
+	}
+	return conn.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_normalized_key ON songs (normalized_key)").Error
+}
+
+// Reindex rebuilds the indexes of all model tables
+func Reindex(conn *gorm.DB) error {
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: conn}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		sql := "REINDEX " + stmt.Quote(stmt.Schema.Table)
+		if conn.Dialector.Name() == "postgres" {
+			sql = "REINDEX TABLE " + stmt.Quote(stmt.Schema.Table)
+		}
+		if err := conn.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"os"
+	"song-library/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrate_AuditLogIsAppendOnly(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, Migrate(conn))
+	testAuditLogAppendOnly(t, conn)
+}
+
+func TestMigrate_AuditLogIsAppendOnlyPostgres(t *testing.T) {
+	// The database of the repository conformance tests
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, conn.Migrator().DropTable("audit_log"))
+	require.NoError(t, Migrate(conn))
+	require.NoError(t, Migrate(conn), "Migrating again should keep working")
+	testAuditLogAppendOnly(t, conn)
+}
+
+func testAuditLogAppendOnly(t *testing.T, conn *gorm.DB) {
+	entry := &model.AuditEntry{Action: model.AuditActionCreate, SongID: 1, Actor: "alice"}
+	require.NoError(t, conn.Create(entry).Error, "Entries should be appended")
+
+	err := conn.Model(entry).Update("actor", "mallory").Error
+	assert.ErrorContains(t, err, "append-only", "Entries should not be updated")
+	err = conn.Delete(entry).Error
+	assert.ErrorContains(t, err, "append-only", "Entries should not be deleted")
+
+	var stored model.AuditEntry
+	require.NoError(t, conn.First(&stored, entry.ID).Error)
+	assert.Equal(t, "alice", stored.Actor)
+}
+
+func TestMigrate_FillsSearchColumns(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, Migrate(conn))
+	require.NoError(t, conn.Exec("INSERT INTO songs (group_name, song_name, text) VALUES (?, ?, ?)", "Кино", "Звезда", "").Error)
+
+	require.NoError(t, Migrate(conn))
+	var song model.Song
+	require.NoError(t, conn.First(&song).Error)
+	assert.Equal(t, "кино\nkino", song.SearchGroupName)
+	assert.Equal(t, "звезда\nzvezda", song.SearchSongName)
+	assert.Equal(t, "", song.SearchText)
+}
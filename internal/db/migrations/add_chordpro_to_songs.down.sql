@@ -0,0 +1,2 @@
+ALTER TABLE song_revisions DROP COLUMN IF EXISTS chordpro;
+ALTER TABLE songs DROP COLUMN IF EXISTS chordpro;
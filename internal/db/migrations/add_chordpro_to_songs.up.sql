@@ -0,0 +1,2 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS chordpro TEXT;
+ALTER TABLE song_revisions ADD COLUMN IF NOT EXISTS chordpro TEXT;
@@ -0,0 +1,5 @@
+-- A webhook gets each event once, even when the event is published again
+DELETE FROM webhook_deliveries WHERE id NOT IN
+    (SELECT MIN(id) FROM webhook_deliveries GROUP BY webhook_id, event_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id_event_id ON webhook_deliveries (webhook_id, event_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_songs_language;
+
+ALTER TABLE songs DROP COLUMN IF EXISTS language;
@@ -0,0 +1,4 @@
+-- Languages of existing songs are detected by the application on startup
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+
+CREATE INDEX IF NOT EXISTS idx_songs_language ON songs (language);
@@ -0,0 +1 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS lrc TEXT;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_songs_normalized_key;
+
+ALTER TABLE songs DROP COLUMN IF EXISTS normalized_key;
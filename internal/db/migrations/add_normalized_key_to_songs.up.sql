@@ -0,0 +1,4 @@
+-- Keys of existing songs are filled in by the application on startup
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS normalized_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_normalized_key ON songs (normalized_key);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_outbox_dead_at;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS dead_at;
+ALTER TABLE outbox DROP COLUMN IF EXISTS next_attempt_at;
@@ -0,0 +1,3 @@
+ALTER TABLE songs DROP COLUMN IF EXISTS search_text;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_song_name;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_group_name;
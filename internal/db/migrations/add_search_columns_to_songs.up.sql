@@ -0,0 +1,4 @@
+-- Search columns of existing songs are filled in by the application on startup
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_group_name TEXT;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_song_name TEXT;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_text TEXT;
@@ -0,0 +1,7 @@
+-- Each index ends with the ID so that sorted pages can continue from the
+-- last song of the previous page
+CREATE INDEX IF NOT EXISTS idx_songs_group_name_id ON songs (group_name, id);
+CREATE INDEX IF NOT EXISTS idx_songs_song_name_id ON songs (song_name, id);
+CREATE INDEX IF NOT EXISTS idx_songs_release_date_id ON songs (release_date, id);
+CREATE INDEX IF NOT EXISTS idx_songs_created_at_id ON songs (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_songs_updated_at_id ON songs (updated_at, id);
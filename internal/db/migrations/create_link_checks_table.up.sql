@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS link_checks (
+    song_id INTEGER PRIMARY KEY,
+    url TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    status_code INTEGER,
+    final_url TEXT,
+    content_type TEXT,
+    provider VARCHAR(32),
+    media_id TEXT,
+    error TEXT,
+    checked_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_checks_status ON link_checks (status);
+CREATE INDEX IF NOT EXISTS idx_link_checks_checked_at ON link_checks (checked_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS song_revisions (
+    id SERIAL PRIMARY KEY,
+    song_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    group_name VARCHAR(255) NOT NULL,
+    song_name VARCHAR(255) NOT NULL,
+    release_date DATE,
+    text TEXT,
+    lrc TEXT,
+    link TEXT,
+    author VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_song_revisions_song_revision UNIQUE (song_id, revision)
+);
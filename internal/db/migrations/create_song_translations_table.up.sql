@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS song_translations (
+    song_id INTEGER NOT NULL,
+    language VARCHAR(35) NOT NULL,
+    verses TEXT NOT NULL,
+    translator VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (song_id, language)
+);
@@ -0,0 +1,271 @@
+// Package dedupe normalizes song names and finds likely duplicate songs
+package dedupe
+
+import (
+	"song-library/internal/model"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultThreshold is the similarity above which two songs are reported as
+// duplicates
+const DefaultThreshold = 0.85
+
+// Normalize folds s for comparison: lower case, without diacritics and
+// punctuation, with single spaces between words
+func Normalize(s string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		folded = s
+	}
+	return strings.Join(strings.FieldsFunc(strings.ToLower(folded), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// Key is the normalized artist and title of a song. No two songs may share
+// a key.
+func Key(group, song string) string {
+	return Normalize(group) + "|" + Normalize(song)
+}
+
+// maxCandidates bounds how many songs sharing a title trigram each song is
+// compared with
+const maxCandidates = 64
+
+// Similarity scores how alike two normalized strings are, from 0 to 1. It
+// is the better of the Levenshtein ratio, which catches typos, and the
+// trigram similarity, which catches reordered or missing words.
+func Similarity(a, b string) float64 {
+	na, nb := newName(a), newName(b)
+	return similarity(&na, &nb, 0)
+}
+
+// name is a normalized name with the runes and trigrams that similarity
+// needs, computed once
+type name struct {
+	text     string
+	runes    []rune
+	trigrams map[string]bool
+}
+
+func newName(text string) name {
+	return name{text: text, runes: []rune(text), trigrams: trigrams(text)}
+}
+
+// similarity is Similarity for names. Scores below threshold may come out
+// lower than Similarity's, since the Levenshtein ratio is skipped when the
+// difference in length alone keeps it below threshold.
+func similarity(a, b *name, threshold float64) float64 {
+	if a.text == b.text {
+		return 1
+	}
+	longest := max(len(a.runes), len(b.runes))
+	score := trigramSimilarity(a.trigrams, b.trigrams)
+	// The Levenshtein distance is at least the difference in length
+	bound := 1 - float64(abs(len(a.runes)-len(b.runes)))/float64(longest)
+	if bound <= score || bound < threshold {
+		return score
+	}
+	return max(score, 1-float64(levenshtein(a.runes, b.runes))/float64(longest))
+}
+
+// Levenshtein returns the number of single-rune insertions, deletions and
+// substitutions that turn a into b
+func Levenshtein(a, b string) int {
+	return levenshtein([]rune(a), []rune(b))
+}
+
+func levenshtein(ra, rb []rune) int {
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// trigrams returns the set of three-rune substrings of s, padded like
+// PostgreSQL's pg_trgm so that short words still have trigrams
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(s) {
+		r := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+func trigramSimilarity(ta, tb map[string]bool) float64 {
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// blockingTrigrams returns the distinct unpadded trigrams of a title, or
+// the title itself when it is too short to have any. Unlike the padded
+// ones, they are not shared by every title with a word starting the same.
+func blockingTrigrams(title []rune) []string {
+	if len(title) < 3 {
+		if len(title) == 0 {
+			return nil
+		}
+		return []string{string(title)}
+	}
+	seen := make(map[string]bool)
+	var set []string
+	for i := 0; i+3 <= len(title); i++ {
+		if t := string(title[i : i+3]); !seen[t] {
+			seen[t] = true
+			set = append(set, t)
+		}
+	}
+	return set
+}
+
+// prefixLength returns how many of the rarest trigrams of a title of n
+// runes are looked up. An edit changes at most three trigrams, so a title
+// within the Levenshtein distance that threshold allows keeps at least
+// one of them.
+func prefixLength(n int, threshold float64) int {
+	edits := int((1 - threshold) * float64(n))
+	return 3*edits + 1
+}
+
+// Pair is a song and a likely duplicate of it
+type Pair struct {
+	Song      model.Song `json:"song"`
+	Duplicate model.Song `json:"duplicate"`
+	// Similarity is the lower of the artist and title similarities
+	Similarity float64 `json:"similarity"`
+}
+
+// Find returns the pairs of songs whose artists and titles are both at
+// least threshold similar, most similar first.
+//
+// A song is only compared with the songs whose titles contain one of the
+// rarest trigrams of its own title, and with at most maxCandidates of them
+// per trigram. When more songs share a trigram, they are sorted by artist
+// and each song is compared with its neighbours, where songs by similar
+// artists end up.
+func Find(songs []model.Song, threshold float64) []Pair {
+	type entry struct {
+		group, title name
+		// blocking are the trigrams the song is looked up by, rarest first
+		blocking []string
+	}
+	entries := make([]entry, len(songs))
+	byTrigram := make(map[string][]int)
+	for i, song := range songs {
+		e := &entries[i]
+		e.group, e.title = newName(Normalize(song.GroupName)), newName(Normalize(song.SongName))
+		e.blocking = blockingTrigrams(e.title.runes)
+		for _, t := range e.blocking {
+			byTrigram[t] = append(byTrigram[t], i)
+		}
+	}
+	for i := range entries {
+		blocking := entries[i].blocking
+		sort.Slice(blocking, func(a, b int) bool {
+			if na, nb := len(byTrigram[blocking[a]]), len(byTrigram[blocking[b]]); na != nb {
+				return na < nb
+			}
+			return blocking[a] < blocking[b]
+		})
+		entries[i].blocking = blocking[:min(len(blocking), prefixLength(len(entries[i].title.runes), threshold))]
+	}
+
+	pairs := []Pair{}
+	compared := make(map[[2]int]bool)
+	compare := func(i, j int) {
+		if i == j {
+			return
+		}
+		if i > j {
+			i, j = j, i
+		}
+		if compared[[2]int{i, j}] {
+			return
+		}
+		compared[[2]int{i, j}] = true
+		group := similarity(&entries[i].group, &entries[j].group, threshold)
+		if group < threshold {
+			return
+		}
+		if title := similarity(&entries[i].title, &entries[j].title, threshold); title >= threshold {
+			pairs = append(pairs, Pair{Song: songs[i], Duplicate: songs[j], Similarity: min(group, title)})
+		}
+	}
+
+	// positions holds where each song is in the large buckets, which are
+	// sorted by artist on first use
+	positions := make(map[string]map[int]int)
+	for i := range entries {
+		for _, t := range entries[i].blocking {
+			bucket := byTrigram[t]
+			if len(bucket) <= maxCandidates {
+				for _, j := range bucket {
+					compare(i, j)
+				}
+				continue
+			}
+			position, ok := positions[t]
+			if !ok {
+				sort.SliceStable(bucket, func(a, b int) bool {
+					return entries[bucket[a]].group.text < entries[bucket[b]].group.text
+				})
+				position = make(map[int]int, len(bucket))
+				for p, j := range bucket {
+					position[j] = p
+				}
+				positions[t] = position
+			}
+			p := position[i]
+			for _, j := range bucket[max(p-maxCandidates/2, 0):min(p+maxCandidates/2+1, len(bucket))] {
+				compare(i, j)
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		if pairs[a].Similarity != pairs[b].Similarity {
+			return pairs[a].Similarity > pairs[b].Similarity
+		}
+		if pairs[a].Song.ID != pairs[b].Song.ID {
+			return pairs[a].Song.ID < pairs[b].Song.ID
+		}
+		return pairs[a].Duplicate.ID < pairs[b].Duplicate.ID
+	})
+	return pairs
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
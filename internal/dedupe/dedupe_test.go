@@ -0,0 +1,56 @@
+package dedupe
+
+import (
+	"fmt"
+	"song-library/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "beyonce halo", Normalize("  Beyoncé – HALO!! "))
+	assert.Equal(t, "motorhead ace of spades", Normalize("Motörhead: Ace-of-Spades"))
+	assert.Equal(t, Key("Sigur Rós", "Hoppípolla"), Key("sigur ros", "hoppipolla"))
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, Levenshtein("muse", "muse"))
+	assert.Equal(t, 3, Levenshtein("kitten", "sitting"))
+	assert.Equal(t, 4, Levenshtein("", "abba"))
+}
+
+func TestFind(t *testing.T) {
+	songs := []model.Song{
+		{ID: 1, GroupName: "Muse", SongName: "Supermassive Black Hole"},
+		{ID: 2, GroupName: "muse", SongName: "Supermasive Black Hole"},
+		{ID: 3, GroupName: "Muse", SongName: "Uprising"},
+		{ID: 4, GroupName: "The Muse", SongName: "Uprising"},
+		{ID: 5, GroupName: "Metallica", SongName: "Supermassive Black Hole"},
+	}
+
+	pairs := Find(songs, DefaultThreshold)
+	if assert.Len(t, pairs, 1) {
+		assert.Equal(t, uint(1), pairs[0].Song.ID)
+		assert.Equal(t, uint(2), pairs[0].Duplicate.ID)
+		assert.InDelta(t, 0.95, pairs[0].Similarity, 0.01)
+	}
+	assert.Len(t, Find(songs, 0.5), 2, "A lower threshold should report the group name variant")
+}
+
+func TestFind_CommonTitles(t *testing.T) {
+	// Every song shares its title, so the bucket exceeds maxCandidates
+	var songs []model.Song
+	for i := 0; i < 500; i++ {
+		songs = append(songs, model.Song{ID: uint(i + 1), GroupName: fmt.Sprintf("Band %03d", i), SongName: "Intro"})
+	}
+	songs = append(songs,
+		model.Song{ID: 501, GroupName: "Muse", SongName: "Intro"},
+		model.Song{ID: 502, GroupName: "Muse!", SongName: "intro"})
+
+	pairs := Find(songs, 0.95)
+	if assert.Len(t, pairs, 1) {
+		assert.Equal(t, uint(501), pairs[0].Song.ID)
+		assert.Equal(t, uint(502), pairs[0].Duplicate.ID)
+	}
+}
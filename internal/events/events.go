@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"song-library/internal/model"
+	"sync"
+	"time"
+)
+
+// Song lifecycle event types
+const (
+	SongCreated = "song.created"
+	SongUpdated = "song.updated"
+	SongDeleted = "song.deleted"
+)
+
+// Types lists every event type that can be published
+var Types = []string{SongCreated, SongUpdated, SongDeleted}
+
+// Event describes a change to a song. Data holds the song after the change,
+// or the deleted song for song.deleted.
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	SongID     uint        `json:"song_id"`
+	Data       *model.Song `json:"data,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// NewEvent creates an event of the given type for song
+func NewEvent(eventType string, song *model.Song) Event {
+	return Event{
+		ID:         NewID(),
+		Type:       eventType,
+		SongID:     song.ID,
+		Data:       song,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// IsType reports whether t is a known event type
+func IsType(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NewID returns a random 128-bit identifier in hex
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Publisher delivers events to subscribers or a message broker. Publish
+// returns only once the event has been accepted; events may be delivered
+// more than once, so consumers should deduplicate by event ID.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Handler receives published events. Handlers run synchronously in the
+// publisher's goroutine and must not block. An error means the event was
+// not handled and should be published again.
+type Handler func(Event) error
+
+// Bus is an in-process Publisher that fans events out to subscribers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus creates an empty Bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers h for every event published afterwards
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish delivers e to all subscribers and returns the first error they
+// return. Subscribers that succeeded see e again when it is republished.
+func (b *Bus) Publish(ctx context.Context, e Event) error {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	var first error
+	for _, h := range handlers {
+		if err := h(e); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Fanout is a Publisher that publishes every event to all of its publishers
+// in order, stopping at the first error
+type Fanout []Publisher
+
+func (f Fanout) Publish(ctx context.Context, e Event) error {
+	for _, p := range f {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
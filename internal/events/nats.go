@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes events to NATS subjects named
+// "<prefix>.<event type>", e.g. "songs.song.created". The event ID is sent
+// as Nats-Msg-Id so JetStream streams can drop duplicates.
+type NATSPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSPublisher connects to the NATS server at url
+func NewNATSPublisher(url, prefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("song-library"))
+	if err != nil {
+		return nil, err
+	}
+	return &NATSPublisher{conn: conn, prefix: prefix}, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(p.prefix + "." + e.Type)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, e.ID)
+	if err := p.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// Flush so the event is known to have reached the server before the
+	// outbox marks it as published
+	return p.conn.FlushWithContext(ctx)
+}
+
+// Close drains pending messages and closes the connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
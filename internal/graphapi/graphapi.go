@@ -0,0 +1,60 @@
+// Package graphapi serves songs, artists and lyrics over GraphQL
+package graphapi
+
+import (
+	"context"
+	"song-library/internal/service"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Executor runs GraphQL requests against the song schema
+type Executor struct {
+	schema      graphql.Schema
+	songService *service.SongService
+	limits      Limits
+}
+
+// NewExecutor builds the schema and returns an Executor enforcing limits
+func NewExecutor(songService *service.SongService, limits Limits) (*Executor, error) {
+	schema, err := newSchema(songService)
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{schema: schema, songService: songService, limits: limits}, nil
+}
+
+// Execute parses, validates and runs req. Queries exceeding the limits are
+// rejected before any resolver runs.
+func (e *Executor) Execute(ctx context.Context, req Request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if result := graphql.ValidateDocument(&e.schema, doc, nil); !result.IsValid {
+		return &graphql.Result{Errors: result.Errors}
+	}
+	if err := checkLimits(doc, req.OperationName, req.Variables, e.limits); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoaders(ctx, newLoaders(ctx, e.songService)),
+	})
+}
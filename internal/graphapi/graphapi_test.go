@@ -0,0 +1,323 @@
+package graphapi
+
+import (
+	"context"
+	"encoding/json"
+	"song-library/internal/db"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"sync/atomic"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// countingRepository counts batched loads and counts that reach the
+// database, and records the last listing query
+type countingRepository struct {
+	repository.SongRepository
+	groupLoads int32
+	songCounts int32
+	lastQuery  repository.SongQuery
+}
+
+func (r *countingRepository) ListSongs(query repository.SongQuery) ([]model.Song, error) {
+	r.lastQuery = query
+	return r.SongRepository.ListSongs(query)
+}
+
+func (r *countingRepository) CountSongs(query repository.SongQuery) (int, error) {
+	atomic.AddInt32(&r.songCounts, 1)
+	return r.SongRepository.CountSongs(query)
+}
+
+func (r *countingRepository) GetSongsByGroups(groups []string) ([]model.Song, error) {
+	atomic.AddInt32(&r.groupLoads, 1)
+	return r.SongRepository.GetSongsByGroups(groups)
+}
+
+func setupTestExecutor(t *testing.T, limits Limits) (*Executor, *service.SongService, *countingRepository) {
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
+	repo := &countingRepository{SongRepository: repository.NewSongRepository(conn)}
+	songService := service.NewSongService(repo)
+	executor, err := NewExecutor(songService, limits)
+	assert.Nil(t, err, "Building the schema should not return an error")
+	return executor, songService, repo
+}
+
+// execute runs query and decodes the data into dst
+func execute(t *testing.T, executor *Executor, query string, variables map[string]interface{}, dst interface{}) *graphql.Result {
+	result := executor.Execute(context.Background(), Request{Query: query, Variables: variables})
+	if dst != nil {
+		raw, _ := json.Marshal(result.Data)
+		assert.Nil(t, json.Unmarshal(raw, dst))
+	}
+	return result
+}
+
+func TestExecutor_MutationsAndLyrics(t *testing.T) {
+	executor, _, _ := setupTestExecutor(t, Limits{})
+
+	var created struct {
+		CreateSong struct {
+			ID   string `json:"id"`
+			Text string `json:"text"`
+		} `json:"createSong"`
+	}
+	result := execute(t, executor, `mutation($input: SongInput!) { createSong(input: $input) { id text } }`,
+		map[string]interface{}{"input": map[string]interface{}{
+			"groupName": "Muse",
+			"songName":  "Starlight",
+			"lrc":       "[ar:Muse]\n[00:01.00]Far away\n[00:02.00]\n[00:03.00]Our hopes and expectations",
+		}}, &created)
+	assert.Empty(t, result.Errors, "Creating a song should not return errors")
+	assert.Equal(t, "1", created.CreateSong.ID)
+	assert.Equal(t, "Far away\n\nOur hopes and expectations", created.CreateSong.Text, "Text should be derived from LRC")
+
+	var song struct {
+		Song struct {
+			Lyrics struct {
+				Synced bool `json:"synced"`
+				Tags   []struct {
+					Name string `json:"name"`
+				} `json:"tags"`
+				Lines []struct {
+					TimeMs int `json:"timeMs"`
+				} `json:"lines"`
+			} `json:"lyrics"`
+			Verses struct {
+				TotalCount int `json:"totalCount"`
+				Edges      []struct {
+					Node struct {
+						Number int    `json:"number"`
+						Text   string `json:"text"`
+					} `json:"node"`
+				} `json:"edges"`
+				PageInfo struct {
+					HasNextPage bool `json:"hasNextPage"`
+				} `json:"pageInfo"`
+			} `json:"verses"`
+		} `json:"song"`
+	}
+	result = execute(t, executor, `{ song(id: "1") {
+		lyrics { synced tags { name } lines { timeMs } }
+		verses(first: 1) { totalCount edges { node { number text } } pageInfo { hasNextPage } }
+	} }`, nil, &song)
+	assert.Empty(t, result.Errors)
+	assert.True(t, song.Song.Lyrics.Synced)
+	assert.Equal(t, "ar", song.Song.Lyrics.Tags[0].Name)
+	assert.Equal(t, 3000, song.Song.Lyrics.Lines[2].TimeMs)
+	assert.Equal(t, 2, song.Song.Verses.TotalCount)
+	assert.Equal(t, "Far away", song.Song.Verses.Edges[0].Node.Text)
+	assert.True(t, song.Song.Verses.PageInfo.HasNextPage)
+
+	result = execute(t, executor, `mutation { updateSong(id: "42", input: {songName: "Uprising"}) { id } }`, nil, nil)
+	if assert.Len(t, result.Errors, 1, "Updating a missing song should fail") {
+		assert.Equal(t, codeNotFound, result.Errors[0].Extensions["code"])
+	}
+
+	result = execute(t, executor, `mutation { createSong(input: {lrc: "not lrc"}) { id } }`, nil, nil)
+	if assert.Len(t, result.Errors, 1, "Invalid LRC should fail") {
+		assert.Equal(t, codeBadUserInput, result.Errors[0].Extensions["code"])
+	}
+}
+
+func TestExecutor_ConnectionsAndBatching(t *testing.T) {
+	executor, songService, repo := setupTestExecutor(t, Limits{})
+	for _, song := range []model.Song{
+		{GroupName: "Muse", SongName: "Starlight"},
+		{GroupName: "Queen", SongName: "Bohemian Rhapsody"},
+		{GroupName: "Muse", SongName: "Uprising"},
+		{GroupName: "Radiohead", SongName: "Creep"},
+	} {
+		s := song
+		songService.AddSong(context.Background(), &s)
+	}
+
+	var artists struct {
+		Artists struct {
+			Edges []struct {
+				Node struct {
+					Name  string `json:"name"`
+					Songs struct {
+						TotalCount int `json:"totalCount"`
+					} `json:"songs"`
+				} `json:"node"`
+			} `json:"edges"`
+		} `json:"artists"`
+	}
+	result := execute(t, executor, `{ artists { edges { node { name songs { totalCount } } } } }`, nil, &artists)
+	assert.Empty(t, result.Errors)
+	assert.Len(t, artists.Artists.Edges, 3)
+	assert.Equal(t, "Muse", artists.Artists.Edges[0].Node.Name, "Artists should be sorted by name")
+	assert.Equal(t, 2, artists.Artists.Edges[0].Node.Songs.TotalCount)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&repo.groupLoads), "Songs of all artists should be loaded in one batch")
+
+	type page struct {
+		Songs struct {
+			Edges []struct {
+				Cursor string `json:"cursor"`
+				Node   struct {
+					SongName string `json:"songName"`
+				} `json:"node"`
+			} `json:"edges"`
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"songs"`
+	}
+	query := `query($after: String) { songs(filter: {groupName: "muse"}, first: 1, after: $after) {
+		edges { cursor node { songName } } pageInfo { hasNextPage endCursor }
+	} }`
+	var first page
+	execute(t, executor, query, nil, &first)
+	assert.Equal(t, "Starlight", first.Songs.Edges[0].Node.SongName, "Filter should be case-insensitive")
+	assert.True(t, first.Songs.PageInfo.HasNextPage)
+
+	var second page
+	execute(t, executor, query, map[string]interface{}{"after": first.Songs.PageInfo.EndCursor}, &second)
+	assert.Equal(t, "Uprising", second.Songs.Edges[0].Node.SongName, "The next page should start after the cursor")
+	assert.False(t, second.Songs.PageInfo.HasNextPage)
+
+	var sorted page
+	execute(t, executor, `{ songs(filter: {groupName: "muse"}, sort: "-song_name") { edges { node { songName } } } }`, nil, &sorted)
+	assert.Equal(t, "Uprising", sorted.Songs.Edges[0].Node.SongName, "Songs should follow the sort")
+	result = execute(t, executor, `{ songs(sort: "lyrics") { totalCount } }`, nil, nil)
+	assert.Equal(t, codeBadUserInput, result.Errors[0].Extensions["code"], "Unknown sort columns should be rejected")
+
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Кино", SongName: "Звезда по имени Солнце", Text: "Белый снег, серый лёд"})
+	var found struct {
+		Songs struct {
+			Edges []struct {
+				Node struct {
+					Language        string `json:"language"`
+					Transliteration string `json:"transliteration"`
+				} `json:"node"`
+			} `json:"edges"`
+		} `json:"songs"`
+	}
+	execute(t, executor, `{ songs(filter: {songName: "zvezda", language: "ru"}) { edges { node { language transliteration } } } }`, nil, &found)
+	require.Len(t, found.Songs.Edges, 1, "Filters should match the transliteration")
+	assert.Equal(t, "Belyi sneg, seryi led", found.Songs.Edges[0].Node.Transliteration)
+}
+
+func TestExecutor_PagesInTheRepository(t *testing.T) {
+	executor, songService, repo := setupTestExecutor(t, Limits{})
+	for _, song := range []model.Song{
+		{GroupName: "Radiohead", SongName: "Creep"},
+		{GroupName: "Muse", SongName: "Starlight", Text: "Far away"},
+		{GroupName: "Queen", SongName: "Bohemian Rhapsody"},
+		{GroupName: "Muse", SongName: "Uprising", Text: "They will not force us"},
+	} {
+		s := song
+		songService.AddSong(context.Background(), &s)
+	}
+
+	var songs struct {
+		Songs struct {
+			TotalCount int `json:"totalCount"`
+			Edges      []struct {
+				Node struct {
+					SongName string `json:"songName"`
+				} `json:"node"`
+			} `json:"edges"`
+		} `json:"songs"`
+	}
+	result := execute(t, executor, `{ songs(first: 1, filter: {groupName: "MUSE"}) { edges { node { songName } } } }`, nil, &songs)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, repository.SongQuery{GroupName: "MUSE", Sort: repository.SongSort{{Column: "id"}}, Limit: 2}, repo.lastQuery,
+		"The filter and page size should reach the repository")
+	assert.Zero(t, atomic.LoadInt32(&repo.songCounts), "Songs should only be counted when totalCount is requested")
+
+	var counted struct {
+		Songs struct {
+			TotalCount int           `json:"totalCount"`
+			Edges      []interface{} `json:"edges"`
+		} `json:"songs"`
+	}
+	execute(t, executor, `{ songs(first: 0, filter: {text: "force"}) { totalCount edges { cursor } } }`, nil, &counted)
+	assert.Equal(t, 1, counted.Songs.TotalCount)
+	assert.Empty(t, counted.Songs.Edges)
+
+	type page struct {
+		Artists struct {
+			TotalCount int `json:"totalCount"`
+			Edges      []struct {
+				Node struct {
+					Name string `json:"name"`
+				} `json:"node"`
+			} `json:"edges"`
+			PageInfo struct {
+				HasNextPage     bool   `json:"hasNextPage"`
+				HasPreviousPage bool   `json:"hasPreviousPage"`
+				EndCursor       string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"artists"`
+	}
+	query := `query($after: String) { artists(first: 2, after: $after) {
+		totalCount edges { node { name } } pageInfo { hasNextPage hasPreviousPage endCursor }
+	} }`
+	var first, second page
+	execute(t, executor, query, nil, &first)
+	require.Len(t, first.Artists.Edges, 2)
+	assert.Equal(t, "Muse", first.Artists.Edges[0].Node.Name)
+	assert.Equal(t, 3, first.Artists.TotalCount)
+	assert.True(t, first.Artists.PageInfo.HasNextPage)
+	assert.False(t, first.Artists.PageInfo.HasPreviousPage)
+
+	execute(t, executor, query, map[string]interface{}{"after": first.Artists.PageInfo.EndCursor}, &second)
+	require.Len(t, second.Artists.Edges, 1)
+	assert.Equal(t, "Radiohead", second.Artists.Edges[0].Node.Name)
+	assert.False(t, second.Artists.PageInfo.HasNextPage)
+	assert.True(t, second.Artists.PageInfo.HasPreviousPage)
+
+	result = execute(t, executor, `{ artists(after: "nonsense") { totalCount } }`, nil, nil)
+	require.NotEmpty(t, result.Errors)
+	assert.Equal(t, codeBadUserInput, result.Errors[0].Extensions["code"], "Invalid cursors should be rejected")
+}
+
+func TestExecutor_Limits(t *testing.T) {
+	executor, _, _ := setupTestExecutor(t, Limits{MaxDepth: 5, MaxComplexity: 200})
+
+	result := execute(t, executor, `{ song(id: "1") { artist { songs { edges { node { id } } } } } }`, nil, nil)
+	if assert.Len(t, result.Errors, 1, "Deep queries should be rejected") {
+		assert.Contains(t, result.Errors[0].Message, "depth 6")
+	}
+
+	result = execute(t, executor, `query($n: Int) { songs(first: $n) { edges { node { id songName text } } } }`,
+		map[string]interface{}{"n": float64(100)}, nil)
+	if assert.Len(t, result.Errors, 1, "Expensive queries should be rejected") {
+		assert.Contains(t, result.Errors[0].Message, "complexity")
+	}
+
+	result = execute(t, executor, `{ songs(first: 10) { edges { node { id } } } }`, nil, nil)
+	assert.Empty(t, result.Errors, "Queries within the limits should run")
+}
+
+func TestExecutor_LimitsRejectInvalidPageSizes(t *testing.T) {
+	executor, _, _ := setupTestExecutor(t, Limits{MaxDepth: 5, MaxComplexity: 200})
+
+	// A negative page size must not offset the cost of another field
+	result := execute(t, executor, `{ a: songs(first: 100) { edges { node { id songName text } } } b: songs(first: -100000) { edges { node { id } } } }`, nil, nil)
+	if assert.Len(t, result.Errors, 1, "Negative page sizes should be rejected") {
+		assert.Contains(t, result.Errors[0].Message, "first must be between 0 and 100")
+	}
+	assert.Nil(t, result.Data, "Rejected queries should not run")
+
+	result = execute(t, executor, `query($n: Int) { songs(first: $n) { edges { node { id } } } }`,
+		map[string]interface{}{"n": float64(-1)}, nil)
+	if assert.Len(t, result.Errors, 1, "Negative page size variables should be rejected") {
+		assert.Contains(t, result.Errors[0].Message, "first must be between 0 and 100")
+	}
+
+	result = execute(t, executor, `query($n: Int) { songs(first: $n) { edges { node { id } } } }`,
+		map[string]interface{}{"n": float64(1e20)}, nil)
+	assert.Len(t, result.Errors, 1, "Page sizes above the maximum should be rejected")
+}
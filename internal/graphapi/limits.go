@@ -0,0 +1,148 @@
+package graphapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Default query limits
+const (
+	DefaultMaxDepth      = 10
+	DefaultMaxComplexity = 1000
+)
+
+// Limits bounds the cost of a single query. Zero values disable a limit.
+type Limits struct {
+	// MaxDepth is the maximum nesting of selection sets
+	MaxDepth int
+	// MaxComplexity is the maximum estimated number of resolved fields. The
+	// cost of a paginated field's selection is multiplied by its page size.
+	MaxComplexity int
+}
+
+// analysis walks the operations of a document to estimate their cost
+type analysis struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// err is the first invalid page size found
+	err error
+}
+
+// checkLimits returns an error when an operation of doc exceeds limits
+func checkLimits(doc *ast.Document, operationName string, variables map[string]interface{}, limits Limits) error {
+	a := &analysis{fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	var operations []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operations = append(operations, def)
+			}
+		}
+	}
+
+	for _, op := range operations {
+		if depth := a.depth(op.SelectionSet); limits.MaxDepth > 0 && depth > limits.MaxDepth {
+			return fmt.Errorf("query depth %d exceeds the limit of %d", depth, limits.MaxDepth)
+		}
+		complexity := a.complexity(op.SelectionSet)
+		// A negative page size would lower the complexity
+		if a.err != nil {
+			return a.err
+		}
+		if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity)
+		}
+	}
+	return nil
+}
+
+// depth returns the deepest field nesting below set
+func (a *analysis) depth(set *ast.SelectionSet) int {
+	max := 0
+	a.eachField(set, func(field *ast.Field) {
+		if d := 1 + a.depth(field.SelectionSet); d > max {
+			max = d
+		}
+	})
+	return max
+}
+
+// complexity counts the fields below set, multiplying the selections of
+// paginated fields by the number of items they may return
+func (a *analysis) complexity(set *ast.SelectionSet) int {
+	total := 0
+	a.eachField(set, func(field *ast.Field) {
+		total += 1 + a.pageSize(field)*a.complexity(field.SelectionSet)
+	})
+	return total
+}
+
+// eachField calls fn for the fields of set, expanding fragments and
+// skipping introspection fields
+func (a *analysis) eachField(set *ast.SelectionSet, fn func(field *ast.Field)) {
+	if set == nil {
+		return
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if !strings.HasPrefix(selection.Name.Value, "__") {
+				fn(selection)
+			}
+		case *ast.InlineFragment:
+			a.eachField(selection.SelectionSet, fn)
+		case *ast.FragmentSpread:
+			if fragment, ok := a.fragments[selection.Name.Value]; ok {
+				a.eachField(fragment.SelectionSet, fn)
+			}
+		}
+	}
+}
+
+// pageSize returns how many items field may return. A first argument
+// outside 0..MaxPageSize is recorded in a.err.
+func (a *analysis) pageSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value == "first" {
+			if n, ok := a.intValue(arg.Value); ok {
+				if n < 0 || n > MaxPageSize {
+					if a.err == nil {
+						a.err = &codedError{err: fmt.Errorf("first must be between 0 and %d", MaxPageSize), code: codeBadUserInput}
+					}
+					return 0
+				}
+				return n
+			}
+		}
+	}
+	if connectionFields[field.Name.Value] {
+		return DefaultPageSize
+	}
+	return 1
+}
+
+func (a *analysis) intValue(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(value.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch v := a.variables[value.Name.Value].(type) {
+		case int:
+			return v, true
+		case float64:
+			// Out of range values must not wrap around
+			if v < 0 || v > MaxPageSize {
+				return -1, true
+			}
+			return int(v), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,114 @@
+package graphapi
+
+import (
+	"context"
+	"song-library/internal/model"
+	"song-library/internal/service"
+	"strconv"
+	"sync"
+)
+
+// batchFunc loads the values for keys in one call. Keys without a value are
+// left out of the returned map.
+type batchFunc func(keys []string) (map[string]interface{}, error)
+
+// loader collects the keys requested by sibling resolvers and loads them with
+// a single batchFunc call. Resolvers return the thunk from Load; the executor
+// calls thunks only after every resolver of the same depth has run, so the
+// first thunk dispatches the whole batch.
+type loader struct {
+	fetch batchFunc
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]*loadResult
+}
+
+type loadResult struct {
+	value interface{}
+	err   error
+}
+
+func newLoader(fetch batchFunc) *loader {
+	return &loader{fetch: fetch, results: make(map[string]*loadResult)}
+}
+
+// Load schedules key for the next batch and returns a thunk yielding its value
+func (l *loader) Load(key string) func() (interface{}, error) {
+	l.mu.Lock()
+	result, ok := l.results[key]
+	if !ok {
+		result = &loadResult{}
+		l.results[key] = result
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.dispatch()
+		return result.value, result.err
+	}
+}
+
+// dispatch loads all pending keys
+func (l *loader) dispatch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := l.pending
+	l.pending = nil
+	if len(keys) == 0 {
+		return
+	}
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		l.results[key].value, l.results[key].err = values[key], err
+	}
+}
+
+// loaders holds the per-request loaders
+type loaders struct {
+	songs      *loader
+	groupSongs *loader
+}
+
+type loadersKey struct{}
+
+func newLoaders(ctx context.Context, songService *service.SongService) *loaders {
+	return &loaders{
+		songs: newLoader(func(ids []string) (map[string]interface{}, error) {
+			songs, err := songService.GetSongsByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[string]interface{}, len(songs))
+			for i := range songs {
+				values[strconv.FormatUint(uint64(songs[i].ID), 10)] = &songs[i]
+			}
+			return values, nil
+		}),
+		groupSongs: newLoader(func(groups []string) (map[string]interface{}, error) {
+			songs, err := songService.GetSongsByGroups(ctx, groups)
+			if err != nil {
+				return nil, err
+			}
+			byGroup := make(map[string][]model.Song, len(groups))
+			for _, song := range songs {
+				byGroup[song.GroupName] = append(byGroup[song.GroupName], song)
+			}
+			values := make(map[string]interface{}, len(byGroup))
+			for group, songs := range byGroup {
+				values[group] = songs
+			}
+			return values, nil
+		}),
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
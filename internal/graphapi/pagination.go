@@ -0,0 +1,192 @@
+package graphapi
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Page sizes of connection fields
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Prefixes of the opaque cursors of list items and artists
+const (
+	cursorPrefix       = "cursor:"
+	artistCursorPrefix = "artist:"
+)
+
+// connectionFields are the fields returning connections. Their page size
+// counts towards the query complexity even when first is omitted.
+var connectionFields = map[string]bool{
+	"songs":   true,
+	"artists": true,
+	"verses":  true,
+}
+
+// connection is the source of the <Type>Connection types
+type connection struct {
+	Edges      []edge
+	PageInfo   pageInfo
+	TotalCount int
+	// count, when set, is called for the total count instead, so that it
+	// is only queried when requested
+	count func() (int, error)
+}
+
+type edge struct {
+	Cursor string
+	Node   interface{}
+}
+
+type pageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     *string
+	EndCursor       *string
+}
+
+// connectionType returns a Relay-style connection type for nodes of nodeType
+func connectionType(name string, nodeType *graphql.Object, pageInfoType *graphql.Object) *graphql.Object {
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Edge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(nodeType)},
+		},
+	})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Connection",
+		Fields: graphql.Fields{
+			"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType)))},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					conn := p.Source.(*connection)
+					if conn.count == nil {
+						return conn.TotalCount, nil
+					}
+					total, err := conn.count()
+					if err != nil {
+						return nil, toError(err)
+					}
+					return total, nil
+				},
+			},
+		},
+	})
+}
+
+// pageArgs returns the first/after arguments of a connection field together
+// with extra arguments
+func pageArgs(extra ...graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{
+			Type:        graphql.Int,
+			Description: fmt.Sprintf("Page size, %d by default and at most %d", DefaultPageSize, MaxPageSize),
+		},
+		"after": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "Cursor of the item after which the page starts",
+		},
+	}
+	for _, e := range extra {
+		for name, arg := range e {
+			args[name] = arg
+		}
+	}
+	return args
+}
+
+// paginate returns the page of total items selected by the first and after
+// arguments. node returns the item at an index.
+func paginate(total int, args map[string]interface{}, node func(i int) interface{}) (*connection, error) {
+	first, err := firstArg(args)
+	if err != nil {
+		return nil, err
+	}
+	start := 0
+	if after, ok := args["after"].(string); ok && after != "" {
+		i, err := decodeCursor(after)
+		if err != nil {
+			return nil, &codedError{err: err, code: codeBadUserInput}
+		}
+		start = i + 1
+	}
+	if start > total {
+		start = total
+	}
+	end := start + first
+	if end > total {
+		end = total
+	}
+
+	conn := &connection{
+		Edges:      make([]edge, 0, end-start),
+		TotalCount: total,
+		PageInfo: pageInfo{
+			HasNextPage:     end < total,
+			HasPreviousPage: start > 0,
+		},
+	}
+	for i := start; i < end; i++ {
+		conn.Edges = append(conn.Edges, edge{Cursor: encodeCursor(i), Node: node(i)})
+	}
+	conn.setPageCursors()
+	return conn, nil
+}
+
+// firstArg returns the page size selected by the first argument
+func firstArg(args map[string]interface{}) (int, error) {
+	first, ok := args["first"].(int)
+	if !ok {
+		return DefaultPageSize, nil
+	}
+	if first < 0 || first > MaxPageSize {
+		return 0, &codedError{err: fmt.Errorf("first must be between 0 and %d", MaxPageSize), code: codeBadUserInput}
+	}
+	return first, nil
+}
+
+// setPageCursors sets the start and end cursors of conn from its edges
+func (conn *connection) setPageCursors() {
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+}
+
+// encodeCursor returns the opaque cursor of the item at index i
+func encodeCursor(i int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(i)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(raw), cursorPrefix) {
+		if i, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix)); err == nil && i >= 0 {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor %q", cursor)
+}
+
+// encodeArtistCursor returns the opaque cursor of an artist. Artists are
+// paged by name, so the cursor stays valid when artists come and go.
+func encodeArtistCursor(name string) string {
+	return base64.StdEncoding.EncodeToString([]byte(artistCursorPrefix + name))
+}
+
+func decodeArtistCursor(cursor string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), artistCursorPrefix) {
+		return "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return strings.TrimPrefix(string(raw), artistCursorPrefix), nil
+}
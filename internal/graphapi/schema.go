@@ -0,0 +1,487 @@
+package graphapi
+
+import (
+	"context"
+	"song-library/internal/language"
+	"song-library/internal/lyrics"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
+)
+
+// artist is the source of the Artist type; artists are the distinct group
+// names of the songs
+type artist struct {
+	Name string
+}
+
+// verse is the source of the Verse type
+type verse struct {
+	Number int
+	Text   string
+}
+
+// newSchema builds the GraphQL schema on top of songService
+func newSchema(songService *service.SongService) (graphql.Schema, error) {
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"hasPreviousPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"startCursor":     &graphql.Field{Type: graphql.String},
+			"endCursor":       &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	lyricsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Lyrics",
+		Description: "Lyrics of a song, with time-coded lines when LRC lyrics are stored",
+		Fields: graphql.Fields{
+			"synced": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"text":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"tags": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+				Name: "LyricsTag",
+				Fields: graphql.Fields{
+					"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+					"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				},
+			}))))},
+			"lines": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+				Name: "LyricsLine",
+				Fields: graphql.Fields{
+					"timeMs":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+					"timestamp": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+					"text":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				},
+			}))))},
+		},
+	})
+
+	verseType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Verse",
+		Description: "A verse of the song text; verses are separated by blank lines",
+		Fields: graphql.Fields{
+			"number": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"text":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	artistType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Artist",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	songType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Song",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return formatID(p.Source.(*model.Song).ID), nil
+				},
+			},
+			"groupName": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"songName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"releaseDate": &graphql.Field{
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return optionalTime(p.Source.(*model.Song).ReleaseDate), nil
+				},
+			},
+			"text": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"lrc":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"chordpro": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Chord sheet in ChordPro format",
+			},
+			"link": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"language": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: `ISO 639-1 code of the lyrics language, "und" when unknown`,
+			},
+			"transliteration": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "The text with Cyrillic and Greek letters written in Latin script",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					song := p.Source.(*model.Song)
+					return language.Transliterate(song.Text, song.Language), nil
+				},
+			},
+			"createdAt": &graphql.Field{Type: graphql.DateTime},
+			"updatedAt": &graphql.Field{Type: graphql.DateTime},
+			"artist": &graphql.Field{
+				Type: graphql.NewNonNull(artistType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return &artist{Name: p.Source.(*model.Song).GroupName}, nil
+				},
+			},
+			"lyrics": &graphql.Field{
+				Type:    graphql.NewNonNull(lyricsType),
+				Resolve: resolveLyrics,
+			},
+			"verses": &graphql.Field{
+				Type: graphql.NewNonNull(connectionType("Verse", verseType, pageInfoType)),
+				Args: pageArgs(),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					texts := lyrics.Verses(p.Source.(*model.Song).Text)
+					return paginate(len(texts), p.Args, func(i int) interface{} {
+						return &verse{Number: i + 1, Text: texts[i]}
+					})
+				},
+			},
+		},
+	})
+	songConnectionType := connectionType("Song", songType, pageInfoType)
+
+	artistType.AddFieldConfig("songs", &graphql.Field{
+		Type: graphql.NewNonNull(songConnectionType),
+		Args: pageArgs(),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			thunk := loadersFrom(p.Context).groupSongs.Load(p.Source.(*artist).Name)
+			return func() (interface{}, error) {
+				value, err := thunk()
+				if err != nil {
+					return nil, toError(err)
+				}
+				songs, _ := value.([]model.Song)
+				return songConnection(songs, p.Args)
+			}, nil
+		},
+	})
+
+	songInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "SongInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"groupName":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"songName":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"releaseDate": &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"text":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"lrc":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"chordpro":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"link":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	songFilter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "SongFilter",
+		Description: "Text filters match case-insensitive substrings, also of the Latin transliteration of Cyrillic and Greek names and lyrics",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"language":       &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "ISO 639-1 code of the lyrics language"},
+			"groupName":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"songName":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"text":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"releasedAfter":  &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"releasedBefore": &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"song": &graphql.Field{
+				Type: songType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					thunk := loadersFrom(p.Context).songs.Load(p.Args["id"].(string))
+					return func() (interface{}, error) {
+						value, err := thunk()
+						if err != nil {
+							return nil, toError(err)
+						}
+						return value, nil
+					}, nil
+				},
+			},
+			"songs": &graphql.Field{
+				Type: graphql.NewNonNull(songConnectionType),
+				Args: pageArgs(graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: songFilter},
+					"sort": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: `Columns to sort by, each prefixed with "-" for descending order, such as "release_date,-song_name"; ties are broken by ID`,
+					},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					q := service.SongPageQuery{}
+					q.Sort, _ = p.Args["sort"].(string)
+					q.Cursor, _ = p.Args["after"].(string)
+					filter, _ := p.Args["filter"].(map[string]interface{})
+					q.Language, _ = filter["language"].(string)
+					q.Filter.GroupName, _ = filter["groupName"].(string)
+					q.Filter.SongName, _ = filter["songName"].(string)
+					q.Filter.Text, _ = filter["text"].(string)
+					q.Filter.ReleasedAfter, _ = filter["releasedAfter"].(time.Time)
+					q.Filter.ReleasedBefore, _ = filter["releasedBefore"].(time.Time)
+					return songPageConnection(p.Context, songService, q, p.Args)
+				},
+			},
+			"artist": &graphql.Field{
+				Type: artistType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name := p.Args["name"].(string)
+					thunk := loadersFrom(p.Context).groupSongs.Load(name)
+					return func() (interface{}, error) {
+						value, err := thunk()
+						if err != nil {
+							return nil, toError(err)
+						}
+						if value == nil {
+							return nil, nil
+						}
+						return &artist{Name: name}, nil
+					}, nil
+				},
+			},
+			"artists": &graphql.Field{
+				Type: graphql.NewNonNull(connectionType("Artist", artistType, pageInfoType)),
+				Args: pageArgs(),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return artistConnection(p.Context, songService, p.Args)
+				},
+			},
+		},
+	})
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createSong": &graphql.Field{
+				Type: graphql.NewNonNull(songType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(songInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					song := songFromInput(p.Args["input"].(map[string]interface{}))
+					if err := songService.AddSong(p.Context, song); err != nil {
+						return nil, toError(err)
+					}
+					return song, nil
+				},
+			},
+			"updateSong": &graphql.Field{
+				Type:        graphql.NewNonNull(songType),
+				Description: "Updates the non-empty fields of the input",
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(songInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id := p.Args["id"].(string)
+					song := songFromInput(p.Args["input"].(map[string]interface{}))
+					if err := songService.UpdateSong(p.Context, id, song); err != nil {
+						return nil, toError(err)
+					}
+					updated, err := songService.GetSongByID(p.Context, id)
+					if err != nil {
+						return nil, toError(err)
+					}
+					return updated, nil
+				},
+			},
+			"deleteSong": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := songService.DeleteSong(p.Context, p.Args["id"].(string)); err != nil {
+						return nil, toError(err)
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queryType,
+		Mutation: mutationType,
+	})
+}
+
+func resolveLyrics(p graphql.ResolveParams) (interface{}, error) {
+	song := p.Source.(*model.Song)
+	result := map[string]interface{}{
+		"synced": false,
+		"text":   song.Text,
+		"tags":   []map[string]interface{}{},
+		"lines":  []map[string]interface{}{},
+	}
+	if song.LRC == "" {
+		return result, nil
+	}
+	lrc, err := lyrics.ParseLRC(song.LRC)
+	if err != nil {
+		return nil, toError(err)
+	}
+	tags := make([]map[string]interface{}, 0, len(lrc.Tags))
+	for name, value := range lrc.Tags {
+		tags = append(tags, map[string]interface{}{"name": name, "value": value})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i]["name"].(string) < tags[j]["name"].(string) })
+	lines := make([]map[string]interface{}, 0, len(lrc.Lines))
+	for _, line := range lrc.Lines {
+		lines = append(lines, map[string]interface{}{
+			"timeMs":    line.TimeMS,
+			"timestamp": line.Timestamp,
+			"text":      line.Text,
+		})
+	}
+	result["synced"] = true
+	result["tags"] = tags
+	result["lines"] = lines
+	return result, nil
+}
+
+// songConnection returns the requested page of songs in their given order
+func songConnection(songs []model.Song, args map[string]interface{}) (interface{}, error) {
+	return paginate(len(songs), args, func(i int) interface{} {
+		return &songs[i]
+	})
+}
+
+// songPageConnection returns the page of songs selected by q and the first
+// argument
+func songPageConnection(ctx context.Context, songService *service.SongService, q service.SongPageQuery, args map[string]interface{}) (*connection, error) {
+	first, err := firstArg(args)
+	if err != nil {
+		return nil, err
+	}
+	// A page of one song tells whether there are any when first is 0
+	q.Limit = max(first, 1)
+	page, err := songService.ListSongsPage(ctx, q)
+	if err != nil {
+		return nil, toError(err)
+	}
+	conn := &connection{
+		Edges: make([]edge, 0, first),
+		PageInfo: pageInfo{
+			HasNextPage:     page.Next != "",
+			HasPreviousPage: page.Prev != "",
+		},
+		count: func() (int, error) { return songService.CountSongs(ctx, q) },
+	}
+	if first == 0 {
+		conn.PageInfo.HasNextPage = len(page.Songs) > 0
+		return conn, nil
+	}
+	for i := range page.Songs {
+		conn.Edges = append(conn.Edges, edge{Cursor: page.Cursors[i], Node: &page.Songs[i]})
+	}
+	conn.setPageCursors()
+	return conn, nil
+}
+
+// artistConnection returns the page of artists selected by the first and
+// after arguments, in alphabetical order
+func artistConnection(ctx context.Context, songService *service.SongService, args map[string]interface{}) (*connection, error) {
+	first, err := firstArg(args)
+	if err != nil {
+		return nil, err
+	}
+	after := ""
+	if cursor, ok := args["after"].(string); ok && cursor != "" {
+		if after, err = decodeArtistCursor(cursor); err != nil {
+			return nil, &codedError{err: err, code: codeBadUserInput}
+		}
+	}
+	// One more name tells whether there is a next page
+	names, err := songService.ListArtists(ctx, after, first+1)
+	if err != nil {
+		return nil, toError(err)
+	}
+	conn := &connection{
+		Edges: make([]edge, 0, first),
+		PageInfo: pageInfo{
+			HasNextPage:     len(names) > first,
+			HasPreviousPage: after != "",
+		},
+		count: func() (int, error) { return songService.CountArtists(ctx) },
+	}
+	for _, name := range names[:min(first, len(names))] {
+		conn.Edges = append(conn.Edges, edge{Cursor: encodeArtistCursor(name), Node: &artist{Name: name}})
+	}
+	conn.setPageCursors()
+	return conn, nil
+}
+
+func songFromInput(input map[string]interface{}) *model.Song {
+	str := func(key string) string {
+		s, _ := input[key].(string)
+		return s
+	}
+	song := &model.Song{
+		GroupName: str("groupName"),
+		SongName:  str("songName"),
+		Text:      str("text"),
+		LRC:       str("lrc"),
+		ChordPro:  str("chordpro"),
+		Link:      str("link"),
+	}
+	if date, ok := input["releaseDate"].(time.Time); ok {
+		song.ReleaseDate = date
+	}
+	return song
+}
+
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func optionalTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// Error codes reported in the extensions of GraphQL errors
+const (
+	codeNotFound     = "NOT_FOUND"
+	codeBadUserInput = "BAD_USER_INPUT"
+	codeConflict     = "CONFLICT"
+	codeInternal     = "INTERNAL"
+)
+
+// codedError adds an error code to the extensions of a GraphQL error
+type codedError struct {
+	err  error
+	code string
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+
+func (e *codedError) Unwrap() error { return e.err }
+
+func (e *codedError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// toError maps domain errors to coded GraphQL errors
+func toError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound):
+		return &codedError{err: err, code: codeNotFound}
+	case errors.Is(err, repository.ErrDuplicateSong):
+		return &codedError{err: err, code: codeConflict}
+	case errors.Is(err, service.ErrValidation),
+		errors.Is(err, lyrics.ErrInvalidLRC),
+		errors.Is(err, lyrics.ErrInvalidChordPro):
+		return &codedError{err: err, code: codeBadUserInput}
+	default:
+		return &codedError{err: err, code: codeInternal}
+	}
+}
@@ -0,0 +1,317 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	pb "song-library/api/songlibrary/v1"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Metadata keys identifying the caller, matching the REST headers
+const (
+	userKey      = "x-user"
+	requestIDKey = "x-request-id"
+)
+
+// server implements pb.SongLibraryServer on top of SongService
+type server struct {
+	pb.UnimplementedSongLibraryServer
+	songService *service.SongService
+}
+
+// NewServer creates a gRPC server exposing the SongLibrary service
+func NewServer(songService *service.SongService, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	pb.RegisterSongLibraryServer(s, &server{songService: songService})
+	return s
+}
+
+func (s *server) ListSongs(ctx context.Context, req *pb.ListSongsRequest) (*pb.ListSongsResponse, error) {
+	songs, err := s.songService.ListSongs(requestContext(ctx), service.SongListQuery{Language: req.GetLanguage()})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListSongsResponse{Songs: make([]*pb.Song, 0, len(songs))}
+	for i := range songs {
+		resp.Songs = append(resp.Songs, toProto(&songs[i]))
+	}
+	return resp, nil
+}
+
+func (s *server) StreamSongs(req *pb.StreamSongsRequest, stream pb.SongLibrary_StreamSongsServer) error {
+	songs, err := s.songService.GetSongs(requestContext(stream.Context()))
+	if err != nil {
+		return toStatus(err)
+	}
+	for i := range songs {
+		if err := stream.Send(toProto(&songs[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) GetSong(ctx context.Context, req *pb.GetSongRequest) (*pb.Song, error) {
+	song, err := s.songService.GetSongByID(requestContext(ctx), formatID(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(song), nil
+}
+
+func (s *server) CreateSong(ctx context.Context, req *pb.CreateSongRequest) (*pb.Song, error) {
+	if req.GetSong() == nil {
+		return nil, status.Error(codes.InvalidArgument, "song is required")
+	}
+	song := fromProto(req.GetSong())
+	if err := s.songService.AddSong(requestContext(ctx), song); err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(song), nil
+}
+
+func (s *server) UpdateSong(ctx context.Context, req *pb.UpdateSongRequest) (*pb.Song, error) {
+	if req.GetSong() == nil {
+		return nil, status.Error(codes.InvalidArgument, "song is required")
+	}
+	ctx = requestContext(ctx)
+	id := formatID(req.GetId())
+	if err := s.songService.UpdateSong(ctx, id, fromProto(req.GetSong())); err != nil {
+		return nil, toStatus(err)
+	}
+	song, err := s.songService.GetSongByID(ctx, id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(song), nil
+}
+
+func (s *server) DeleteSong(ctx context.Context, req *pb.DeleteSongRequest) (*pb.DeleteSongResponse, error) {
+	if err := s.songService.DeleteSong(requestContext(ctx), formatID(req.GetId())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DeleteSongResponse{}, nil
+}
+
+func (s *server) GetLyrics(ctx context.Context, req *pb.GetLyricsRequest) (*pb.Lyrics, error) {
+	song, lrc, err := s.songService.GetLyrics(requestContext(ctx), formatID(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.Lyrics{SongId: uint64(song.ID), Text: song.Text}
+	if lrc != nil {
+		resp.Synced = true
+		resp.Tags = lrc.Tags
+		for _, line := range lrc.Lines {
+			resp.Lines = append(resp.Lines, &pb.LyricsLine{
+				TimeMs:    line.TimeMS,
+				Timestamp: line.Timestamp,
+				Text:      line.Text,
+			})
+		}
+	}
+	return resp, nil
+}
+
+func (s *server) ListRevisions(ctx context.Context, req *pb.ListRevisionsRequest) (*pb.ListRevisionsResponse, error) {
+	revs, err := s.songService.GetRevisions(requestContext(ctx), formatID(req.GetSongId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListRevisionsResponse{Revisions: make([]*pb.Revision, 0, len(revs))}
+	for i := range revs {
+		rev := &revs[i]
+		song := &model.Song{ID: rev.SongID}
+		rev.ApplyTo(song)
+		resp.Revisions = append(resp.Revisions, &pb.Revision{
+			Id:        uint64(rev.ID),
+			SongId:    uint64(rev.SongID),
+			Revision:  int32(rev.Revision),
+			Song:      toProto(song),
+			Author:    rev.Author,
+			CreatedAt: toTimestamp(rev.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+func (s *server) RestoreRevision(ctx context.Context, req *pb.RestoreRevisionRequest) (*pb.Song, error) {
+	if req.GetRevision() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "revision must be positive")
+	}
+	song, err := s.songService.RestoreRevision(requestContext(ctx), formatID(req.GetSongId()), int(req.GetRevision()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(song), nil
+}
+
+func (s *server) DiffRevisions(ctx context.Context, req *pb.DiffRevisionsRequest) (*pb.RevisionDiff, error) {
+	if req.GetFrom() < 1 || req.GetTo() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "revisions must be positive")
+	}
+	diff, err := s.songService.DiffRevisions(requestContext(ctx), formatID(req.GetSongId()), int(req.GetFrom()), int(req.GetTo()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.RevisionDiff{
+		From:   int32(diff.From),
+		To:     int32(diff.To),
+		Fields: make(map[string]*pb.FieldChange, len(diff.Fields)),
+		Text:   make([]*pb.DiffLine, 0, len(diff.Text)),
+	}
+	for name, change := range diff.Fields {
+		resp.Fields[name] = &pb.FieldChange{From: fieldValue(change.From), To: fieldValue(change.To)}
+	}
+	for _, line := range diff.Text {
+		resp.Text = append(resp.Text, &pb.DiffLine{
+			Op:      line.Op,
+			OldLine: int32(line.OldLine),
+			NewLine: int32(line.NewLine),
+			Text:    line.Text,
+		})
+	}
+	return resp, nil
+}
+
+func (s *server) ListAuditEntries(ctx context.Context, req *pb.ListAuditEntriesRequest) (*pb.ListAuditEntriesResponse, error) {
+	filter := repository.AuditFilter{
+		Actor:     req.GetActor(),
+		Action:    req.GetAction(),
+		RequestID: req.GetRequestId(),
+		Limit:     int(req.GetLimit()),
+		Offset:    int(req.GetOffset()),
+	}
+	if req.GetSongId() != 0 {
+		filter.SongID = formatID(req.GetSongId())
+	}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+	entries, err := s.songService.GetAuditLog(requestContext(ctx), filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.ListAuditEntriesResponse{Entries: make([]*pb.AuditEntry, 0, len(entries))}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, &pb.AuditEntry{
+			Id:        uint64(entry.ID),
+			Action:    entry.Action,
+			SongId:    uint64(entry.SongID),
+			Actor:     entry.Actor,
+			RequestId: entry.RequestID,
+			ClientIp:  entry.ClientIP,
+			Before:    string(entry.Before),
+			After:     string(entry.After),
+			CreatedAt: toTimestamp(entry.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+// toStatus maps domain errors to gRPC status codes
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound),
+		errors.Is(err, repository.ErrRevisionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrDuplicateSong):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrValidation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// requestContext attaches the acting user from the call metadata
+func requestContext(ctx context.Context) context.Context {
+	actor := service.Actor{}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(userKey); len(v) > 0 {
+			actor.Name = v[0]
+		}
+		if v := md.Get(requestIDKey); len(v) > 0 {
+			actor.RequestID = v[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			actor.ClientIP = host
+		}
+	}
+	return service.WithActor(ctx, actor)
+}
+
+func formatID(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
+func toProto(song *model.Song) *pb.Song {
+	return &pb.Song{
+		Id:          uint64(song.ID),
+		GroupName:   song.GroupName,
+		SongName:    song.SongName,
+		ReleaseDate: toTimestamp(song.ReleaseDate),
+		Text:        song.Text,
+		Lrc:         song.LRC,
+		Chordpro:    song.ChordPro,
+		Link:        song.Link,
+		Language:    song.Language,
+		CreatedAt:   toTimestamp(song.CreatedAt),
+		UpdatedAt:   toTimestamp(song.UpdatedAt),
+	}
+}
+
+func fromProto(song *pb.Song) *model.Song {
+	s := &model.Song{
+		GroupName: song.GetGroupName(),
+		SongName:  song.GetSongName(),
+		Text:      song.GetText(),
+		LRC:       song.GetLrc(),
+		ChordPro:  song.GetChordpro(),
+		Link:      song.GetLink(),
+	}
+	if song.GetReleaseDate() != nil {
+		s.ReleaseDate = song.GetReleaseDate().AsTime()
+	}
+	return s
+}
+
+// fieldValue formats a value of a revision field change
+func fieldValue(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,172 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	pb "song-library/api/songlibrary/v1"
+	"song-library/internal/db"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestClient serves the SongLibrary service over an in-memory
+// connection and returns a client for it
+func setupTestClient(t *testing.T) (pb.SongLibraryClient, *service.SongService) {
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
+	songService := service.NewSongService(repository.NewSongRepository(conn))
+
+	listener := bufconn.Listen(1 << 20)
+	srv := NewServer(songService)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	client, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err, "Creating the client should not return an error")
+	t.Cleanup(func() { client.Close() })
+	return pb.NewSongLibraryClient(client), songService
+}
+
+func TestSongLibrary_CRUD(t *testing.T) {
+	client, songService := setupTestClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user", "alice")
+
+	created, err := client.CreateSong(ctx, &pb.CreateSongRequest{Song: &pb.Song{
+		GroupName: "Muse",
+		SongName:  "Starlight",
+		Lrc:       "[00:05.00]Far away",
+	}})
+	assert.Nil(t, err, "Creating a song should not return an error")
+	assert.Equal(t, "Far away", created.GetText(), "Text should be derived from LRC")
+
+	updated, err := client.UpdateSong(ctx, &pb.UpdateSongRequest{Id: created.GetId(), Song: &pb.Song{SongName: "Uprising"}})
+	assert.Nil(t, err, "Updating a song should not return an error")
+	assert.Equal(t, "Uprising", updated.GetSongName())
+	assert.Equal(t, "Muse", updated.GetGroupName(), "Empty fields should be kept")
+
+	revs, _ := songService.GetRevisions(context.Background(), "1")
+	assert.Equal(t, "alice", revs[len(revs)-1].Author, "Metadata should identify the actor")
+
+	lyrics, err := client.GetLyrics(ctx, &pb.GetLyricsRequest{Id: created.GetId()})
+	assert.Nil(t, err, "Getting lyrics should not return an error")
+	assert.True(t, lyrics.GetSynced())
+	assert.Equal(t, int64(5000), lyrics.GetLines()[0].GetTimeMs())
+
+	withChords, err := client.UpdateSong(ctx, &pb.UpdateSongRequest{Id: created.GetId(), Song: &pb.Song{Chordpro: "[Am]Far a[C]way"}})
+	assert.Nil(t, err, "Updating the chords should not return an error")
+	assert.Equal(t, "[Am]Far a[C]way", withChords.GetChordpro(), "ChordPro should round-trip")
+
+	_, err = client.DeleteSong(ctx, &pb.DeleteSongRequest{Id: created.GetId()})
+	assert.Nil(t, err, "Deleting a song should not return an error")
+}
+
+func TestSongLibrary_StreamSongs(t *testing.T) {
+	client, songService := setupTestClient(t)
+	for _, name := range []string{"Starlight", "Uprising", "Madness"} {
+		songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: name})
+	}
+
+	stream, err := client.StreamSongs(context.Background(), &pb.StreamSongsRequest{})
+	assert.Nil(t, err, "Opening the stream should not return an error")
+	var names []string
+	for {
+		song, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		names = append(names, song.GetSongName())
+	}
+	assert.Equal(t, []string{"Starlight", "Uprising", "Madness"}, names, "All songs should be streamed")
+}
+
+func TestSongLibrary_ErrorCodes(t *testing.T) {
+	client, _ := setupTestClient(t)
+
+	_, err := client.GetSong(context.Background(), &pb.GetSongRequest{Id: 42})
+	assert.Equal(t, codes.NotFound, status.Code(err), "Missing song should map to NotFound")
+
+	_, err = client.CreateSong(context.Background(), &pb.CreateSongRequest{Song: &pb.Song{Lrc: "not lrc"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Validation errors should map to InvalidArgument")
+	_, err = client.CreateSong(context.Background(), &pb.CreateSongRequest{Song: &pb.Song{GroupName: "Muse", SongName: "Starlight", Chordpro: "[Am"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Invalid ChordPro should map to InvalidArgument")
+
+	_, err = client.RestoreRevision(context.Background(), &pb.RestoreRevisionRequest{SongId: 1, Revision: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err), "Missing song should map to NotFound")
+}
+
+func TestSongLibrary_RevisionsAndAudit(t *testing.T) {
+	client, _ := setupTestClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user", "alice", "x-request-id", "req-1")
+
+	created, err := client.CreateSong(ctx, &pb.CreateSongRequest{Song: &pb.Song{GroupName: "Muse", SongName: "Starlight", Text: "Far away"}})
+	assert.Nil(t, err, "Creating a song should not return an error")
+	_, err = client.UpdateSong(ctx, &pb.UpdateSongRequest{Id: created.GetId(), Song: &pb.Song{
+		SongName: "Uprising",
+		Text:     "Far away\nThis ship is taking me",
+	}})
+	assert.Nil(t, err, "Updating a song should not return an error")
+
+	diff, err := client.DiffRevisions(ctx, &pb.DiffRevisionsRequest{SongId: created.GetId(), From: 1, To: 2})
+	assert.Nil(t, err, "Diffing revisions should not return an error")
+	assert.Equal(t, "Starlight", diff.GetFields()["song_name"].GetFrom())
+	assert.Equal(t, "Uprising", diff.GetFields()["song_name"].GetTo())
+	if assert.Len(t, diff.GetText(), 2) {
+		assert.Equal(t, "insert", diff.GetText()[1].GetOp(), "Added line should be reported as insert")
+		assert.Equal(t, int32(2), diff.GetText()[1].GetNewLine())
+	}
+
+	_, err = client.DiffRevisions(ctx, &pb.DiffRevisionsRequest{SongId: created.GetId(), From: 1, To: 5})
+	assert.Equal(t, codes.NotFound, status.Code(err), "Missing revision should map to NotFound")
+	_, err = client.DiffRevisions(ctx, &pb.DiffRevisionsRequest{SongId: created.GetId(), From: 0, To: 2})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Revisions should be positive")
+
+	audit, err := client.ListAuditEntries(ctx, &pb.ListAuditEntriesRequest{SongId: created.GetId(), Action: "update"})
+	assert.Nil(t, err, "Listing audit entries should not return an error")
+	if assert.Len(t, audit.GetEntries(), 1) {
+		entry := audit.GetEntries()[0]
+		assert.Equal(t, "alice", entry.GetActor())
+		assert.Equal(t, "req-1", entry.GetRequestId())
+		assert.Contains(t, entry.GetBefore(), `"song_name":"Starlight"`)
+		assert.Contains(t, entry.GetAfter(), `"song_name":"Uprising"`)
+	}
+	audit, _ = client.ListAuditEntries(ctx, &pb.ListAuditEntriesRequest{Actor: "alice", Limit: 1})
+	assert.Len(t, audit.GetEntries(), 1, "The limit should cap the entries")
+
+	_, err = client.ListAuditEntries(ctx, &pb.ListAuditEntriesRequest{Offset: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "A negative offset should map to InvalidArgument")
+}
+
+func TestSongLibrary_Language(t *testing.T) {
+	client, songService := setupTestClient(t)
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Кино", SongName: "Звезда по имени Солнце", Text: "Белый снег, серый лёд, на растрескавшейся земле"})
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: "Starlight", Text: "Far away, this ship is taking me far away"})
+
+	song, err := client.GetSong(context.Background(), &pb.GetSongRequest{Id: 1})
+	assert.Nil(t, err, "Getting a song should not return an error")
+	assert.Equal(t, "ru", song.GetLanguage())
+
+	resp, err := client.ListSongs(context.Background(), &pb.ListSongsRequest{Language: "en"})
+	assert.Nil(t, err, "Listing songs should not return an error")
+	if assert.Len(t, resp.GetSongs(), 1, "Songs should be filtered by language") {
+		assert.Equal(t, "Starlight", resp.GetSongs()[0].GetSongName())
+	}
+	resp, _ = client.ListSongs(context.Background(), &pb.ListSongsRequest{})
+	assert.Len(t, resp.GetSongs(), 2, "Without a language every song should be listed")
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLog retrieves audit log entries
+// @Summary Retrieve the audit log
+// @Description Get write operations on songs, newest first
+// @Tags audit
+// @Produce json
+// @Param song_id query string false "Song ID"
+// @Param actor query string false "Actor name"
+// @Param action query string false "Action" Enums(create, update, delete, restore, merge)
+// @Param request_id query string false "Request ID"
+// @Param since query string false "Only entries at or after this time (RFC 3339)"
+// @Param until query string false "Only entries before this time (RFC 3339)"
+// @Param limit query int false "Maximum number of entries" default(100)
+// @Param offset query int false "Number of entries to skip"
+// @Success 200 {object} SuccessResponse{data=[]model.AuditEntry}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audit [get]
+func GetAuditLog(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseAuditFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid audit filter",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		entries, err := songService.GetAuditLog(requestContext(c), filter)
+		if err != nil {
+			respondError(c, "Failed to retrieve audit log", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: entries})
+	}
+}
+
+func parseAuditFilter(c *gin.Context) (repository.AuditFilter, error) {
+	filter := repository.AuditFilter{
+		SongID:    c.Query("song_id"),
+		Actor:     c.Query("actor"),
+		Action:    c.Query("action"),
+		RequestID: c.Query("request_id"),
+	}
+
+	var err error
+	if v := c.Query("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, err
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, err
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, err
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Batch modes
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+)
+
+// BatchRequest is a list of song writes
+type BatchRequest struct {
+	// Mode is "atomic", the default, to apply all operations or none, or
+	// "best_effort" to apply the ones that succeed
+	Mode       string                   `json:"mode" enums:"atomic,best_effort"`
+	Operations []service.BatchOperation `json:"operations" binding:"required"`
+}
+
+// BatchOperationResult is the outcome of one operation of a batch
+type BatchOperationResult struct {
+	service.BatchResult
+	// Code is the HTTP status the operation would have had on its own, or
+	// 424 when it was rolled back because another operation failed
+	Code int `json:"code"`
+}
+
+// BatchResponse reports the outcome of every operation of a batch
+type BatchResponse struct {
+	Mode      string                 `json:"mode"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+// BatchSongs creates, updates and deletes many songs at once
+// @Summary Batch song operations
+// @Description Run a list of create, update and delete operations in order, all or nothing ("atomic", the default) or skipping the ones that fail ("best_effort"). Responds with 200 when every operation succeeded, 207 when some failed in best-effort mode and 422 when an atomic batch was rolled back.
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Param batch body BatchRequest true "Operations"
+// @Param Idempotency-Key header string false "Retries with the same key return the first response"
+// @Success 200 {object} SuccessResponse{data=BatchResponse}
+// @Success 207 {object} SuccessResponse{data=BatchResponse}
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} SuccessResponse{data=BatchResponse}
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/batch [post]
+func BatchSongs(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid request payload",
+				Details: err.Error(),
+			})
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = BatchModeAtomic
+		}
+		if req.Mode != BatchModeAtomic && req.Mode != BatchModeBestEffort {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid batch mode",
+				Details: `mode must be "atomic" or "best_effort"`,
+			})
+			return
+		}
+
+		results, err := songService.BatchSongs(requestContext(c), req.Operations, req.Mode == BatchModeAtomic)
+		if err != nil {
+			respondError(c, "Failed to run batch", err)
+			return
+		}
+
+		resp := BatchResponse{Mode: req.Mode, Results: make([]BatchOperationResult, len(results))}
+		for i, result := range results {
+			resp.Results[i] = BatchOperationResult{BatchResult: result, Code: batchResultStatus(result)}
+			if result.Status == service.BatchSucceeded {
+				resp.Succeeded++
+			} else if result.Status == service.BatchFailed {
+				resp.Failed++
+			}
+		}
+		status := http.StatusOK
+		switch {
+		case resp.Failed > 0 && req.Mode == BatchModeAtomic:
+			status = http.StatusUnprocessableEntity
+		case resp.Failed > 0:
+			status = http.StatusMultiStatus
+		}
+		c.JSON(status, SuccessResponse{Data: resp})
+	}
+}
+
+// batchResultStatus returns the HTTP status of one batch operation
+func batchResultStatus(result service.BatchResult) int {
+	switch {
+	case result.Status == service.BatchRolledBack:
+		return http.StatusFailedDependency
+	case result.Err != nil:
+		return errorStatus(result.Err)
+	case result.Op == service.BatchCreate:
+		return http.StatusCreated
+	default:
+		return http.StatusOK
+	}
+}
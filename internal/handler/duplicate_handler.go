@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/dedupe"
+	"song-library/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Duplicate report sizes
+const (
+	defaultDuplicateLimit = 100
+	maxDuplicateLimit     = 1000
+)
+
+// MergeRequest names the songs to merge
+type MergeRequest struct {
+	// TargetID is the song that is kept
+	TargetID uint `json:"target_id" binding:"required"`
+	// SourceID is the song merged into the target and deleted
+	SourceID uint `json:"source_id" binding:"required"`
+}
+
+// GetDuplicateSongs reports likely duplicate songs
+// @Summary Find duplicate songs
+// @Description List pairs of songs whose normalized artists and titles are similar, most similar first
+// @Tags songs
+// @Produce json
+// @Param threshold query number false "Minimum similarity between 0 and 1 (default 0.85)"
+// @Param limit query int false "Maximum number of pairs" default(100)
+// @Success 200 {object} SuccessResponse{data=[]dedupe.Pair}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/duplicates [get]
+func GetDuplicateSongs(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threshold := dedupe.DefaultThreshold
+		if value := c.Query("threshold"); value != "" {
+			var err error
+			if threshold, err = strconv.ParseFloat(value, 64); err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "Invalid threshold",
+					Details: err.Error(),
+				})
+				return
+			}
+		}
+
+		limit := defaultDuplicateLimit
+		if value := c.Query("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "Invalid limit",
+					Details: err.Error(),
+				})
+				return
+			}
+			if limit < 1 {
+				limit = defaultDuplicateLimit
+			}
+			limit = min(limit, maxDuplicateLimit)
+		}
+
+		pairs, err := songService.FindDuplicates(requestContext(c), threshold, limit)
+		if err != nil {
+			respondError(c, "Failed to find duplicate songs", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: pairs})
+	}
+}
+
+// MergeSongs merges two songs
+// @Summary Merge two songs
+// @Description Fold the source song into the target song. The target keeps its artist and title, takes missing fields and the longer lyrics from the source and gains its revision history and its translations into other languages; the source is deleted.
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Param merge body MergeRequest true "Songs to merge"
+// @Param Idempotency-Key header string false "Retries with the same key return the first response"
+// @Success 200 {object} SuccessResponse{data=model.Song}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/merge [post]
+func MergeSongs(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MergeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid request payload",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		song, err := songService.MergeSongs(requestContext(c),
+			strconv.FormatUint(uint64(req.TargetID), 10), strconv.FormatUint(uint64(req.SourceID), 10))
+		if err != nil {
+			respondError(c, "Failed to merge songs", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: song})
+	}
+}
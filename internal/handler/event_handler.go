@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"song-library/internal/sse"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamSongEvents streams song changes as server-sent events
+// @Summary Stream song events
+// @Description Server-Sent Events stream of song.created, song.updated and song.deleted events. Send Last-Event-ID to resume after a reconnect; a "reset" event means some events were missed. Comment lines are sent as heartbeats.
+// @Tags songs
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {string} string "event stream"
+// @Router /songs/events [get]
+func StreamSongEvents(broker *sse.Broker, heartbeat time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		replay, complete, messages, cancel := broker.Subscribe(c.GetHeader("Last-Event-ID"))
+		defer cancel()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		w := c.Writer
+		fmt.Fprintf(w, "retry: %d\n\n", 3000)
+		if !complete {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, msg := range replay {
+			writeMessage(w, msg)
+		}
+		w.Flush()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					// Dropped for falling behind; the client resumes on reconnect
+					return
+				}
+				writeMessage(w, msg)
+				w.Flush()
+			case <-ticker.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				w.Flush()
+			}
+		}
+	}
+}
+
+func writeMessage(w gin.ResponseWriter, msg sse.Message) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Event, msg.Data)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/graphapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQL executes a GraphQL request. Errors of a valid request are reported
+// in the response body with status 200, as GraphQL clients expect.
+func GraphQL(executor *graphapi.Executor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req graphapi.Request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid GraphQL request",
+				Details: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, executor.Execute(requestContext(c), req))
+	}
+}
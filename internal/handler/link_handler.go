@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSongLinkCheck retrieves the latest check of a song's link
+// @Summary Retrieve the link check of a song
+// @Description Get the result of the latest background check of a song's link: whether it works, the HTTP status, where redirects led and, for known providers such as YouTube, the media ID. Returns 404 while the current link has not been checked.
+// @Tags songs
+// @Produce json
+// @Param id path string true "Song ID"
+// @Success 200 {object} SuccessResponse{data=model.LinkCheck}
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/link [get]
+func GetSongLinkCheck(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		check, err := songService.GetLinkCheck(requestContext(c), c.Param("id"))
+		if err != nil {
+			respondError(c, "Failed to retrieve link check", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: check})
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/lyrics"
+	"song-library/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LyricsResponse represents song lyrics with per-line timestamps
+type LyricsResponse struct {
+	SongID uint              `json:"song_id"`
+	Synced bool              `json:"synced"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	Lines  []lyrics.Line     `json:"lines"`
+	Text   string            `json:"text"`
+}
+
+// GetSongLyrics retrieves the lyrics of a song
+// @Summary Retrieve song lyrics
+// @Description Get the lyrics of a song as LRC, plain text or JSON with per-line timestamps
+// @Tags songs
+// @Produce json,plain
+// @Param id path string true "Song ID"
+// @Param format query string false "Output format" Enums(json, lrc, plain) default(json)
+// @Success 200 {object} SuccessResponse{data=LyricsResponse}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/lyrics [get]
+func GetSongLyrics(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "lrc" && format != "plain" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid format",
+				Details: "format must be one of json, lrc, plain",
+			})
+			return
+		}
+
+		song, lrc, err := songService.GetLyrics(requestContext(c), c.Param("id"))
+		if err != nil {
+			respondError(c, "Failed to retrieve lyrics", err)
+			return
+		}
+
+		switch format {
+		case "lrc":
+			if lrc == nil {
+				c.JSON(http.StatusNotFound, ErrorResponse{Error: "Song has no synchronized lyrics"})
+				return
+			}
+			c.String(http.StatusOK, song.LRC)
+		case "plain":
+			c.String(http.StatusOK, song.Text)
+		default:
+			resp := LyricsResponse{SongID: song.ID, Text: song.Text, Lines: []lyrics.Line{}}
+			if lrc != nil {
+				resp.Synced = true
+				resp.Tags = lrc.Tags
+				resp.Lines = lrc.Lines
+			}
+			c.JSON(http.StatusOK, SuccessResponse{Data: resp})
+		}
+	}
+}
+
+// GetSongTransliteration retrieves a song in Latin script
+// @Summary Transliterate a song
+// @Description Get the artist, title and lyrics of a song with Cyrillic and Greek letters written in Latin script, as JSON or the plain lyrics
+// @Tags songs
+// @Produce json,plain
+// @Param id path string true "Song ID"
+// @Param format query string false "Output format" Enums(json, plain) default(json)
+// @Success 200 {object} SuccessResponse{data=service.Transliteration}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/transliteration [get]
+func GetSongTransliteration(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "plain" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid format",
+				Details: "format must be one of json, plain",
+			})
+			return
+		}
+
+		latin, err := songService.Transliterate(requestContext(c), c.Param("id"))
+		if err != nil {
+			respondError(c, "Failed to transliterate song", err)
+			return
+		}
+		if format == "plain" {
+			c.String(http.StatusOK, latin.Text)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: latin})
+	}
+}
+
+// ChordsResponse represents the chord sheet of a song
+type ChordsResponse struct {
+	SongID uint `json:"song_id"`
+	// Transpose is the number of semitones the chords were moved by
+	Transpose int `json:"transpose"`
+	lyrics.ChordSheet
+}
+
+// GetSongChords retrieves the chord sheet of a song
+// @Summary Retrieve a chord sheet
+// @Description Get the ChordPro chord sheet of a song as JSON with chord positions per line, ChordPro, plain text with the chords above the lyrics, or HTML. The chords can be transposed and rewritten for a capo.
+// @Tags songs
+// @Produce json,plain,html
+// @Param id path string true "Song ID"
+// @Param format query string false "Output format" Enums(json, chordpro, plain, html) default(json)
+// @Param transpose query int false "Semitones to transpose by, from -12 to 12, e.g. +2 or -3"
+// @Param capo query int false "Rewrite the chords for a capo on this fret, keeping the sound"
+// @Success 200 {object} SuccessResponse{data=ChordsResponse}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/chords [get]
+func GetSongChords(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "chordpro" && format != "plain" && format != "html" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid format",
+				Details: "format must be one of json, chordpro, plain, html",
+			})
+			return
+		}
+		var opts service.ChordOptions
+		// An unescaped "+2" arrives as " 2"
+		if value := strings.TrimSpace(c.Query("transpose")); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid transpose", Details: "transpose must be a number of semitones"})
+				return
+			}
+			opts.Transpose = n
+		}
+		if value := c.Query("capo"); value != "" {
+			capo, err := strconv.Atoi(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid capo", Details: "capo must be a fret number"})
+				return
+			}
+			opts.Capo = &capo
+		}
+
+		song, sheet, err := songService.GetChordSheet(requestContext(c), c.Param("id"), opts)
+		if err != nil {
+			respondError(c, "Failed to retrieve chords", err)
+			return
+		}
+		if sheet == nil {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Song has no chord sheet"})
+			return
+		}
+
+		switch format {
+		case "chordpro":
+			c.String(http.StatusOK, sheet.String())
+		case "plain":
+			c.String(http.StatusOK, sheet.Chart())
+		case "html":
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(sheet.HTML()))
+		default:
+			c.JSON(http.StatusOK, SuccessResponse{Data: ChordsResponse{SongID: song.ID, Transpose: opts.Transpose, ChordSheet: *sheet}})
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSongRevisions retrieves the revision history of a song
+// @Summary Retrieve song revisions
+// @Description Get every stored revision of a song, oldest first. The history of a deleted song is kept.
+// @Tags revisions
+// @Produce json
+// @Param id path string true "Song ID"
+// @Success 200 {object} SuccessResponse{data=[]model.SongRevision}
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/revisions [get]
+func GetSongRevisions(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		revs, err := songService.GetRevisions(requestContext(c), c.Param("id"))
+		if err != nil {
+			respondError(c, "Failed to retrieve revisions", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: revs})
+	}
+}
+
+// DiffSongRevisions compares two revisions of a song
+// @Summary Compare two revisions
+// @Description Get the changed fields and a line-level lyrics diff between two revisions
+// @Tags revisions
+// @Produce json
+// @Param id path string true "Song ID"
+// @Param from query int true "Old revision number"
+// @Param to query int true "New revision number"
+// @Success 200 {object} SuccessResponse{data=service.RevisionDiff}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/revisions/diff [get]
+func DiffSongRevisions(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, errFrom := parseRevision(c.Query("from"))
+		to, errTo := parseRevision(c.Query("to"))
+		if errFrom != nil || errTo != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid revision numbers",
+				Details: "from and to must be positive integers",
+			})
+			return
+		}
+
+		diff, err := songService.DiffRevisions(requestContext(c), c.Param("id"), from, to)
+		if err != nil {
+			respondError(c, "Failed to compare revisions", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: diff})
+	}
+}
+
+// RestoreSongRevision restores a song to one of its revisions
+// @Summary Restore a revision
+// @Description Overwrite a song with the contents of an earlier revision
+// @Tags revisions
+// @Produce json
+// @Param id path string true "Song ID"
+// @Param rev path int true "Revision number"
+// @Param Idempotency-Key header string false "Retries with the same key return the first response"
+// @Success 200 {object} SuccessResponse{data=model.Song}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/revisions/{rev}/restore [post]
+func RestoreSongRevision(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rev, err := parseRevision(c.Param("rev"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid revision number",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		song, err := songService.RestoreRevision(requestContext(c), c.Param("id"), rev)
+		if err != nil {
+			respondError(c, "Failed to restore revision", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: song})
+	}
+}
+
+func parseRevision(value string) (int, error) {
+	rev, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if rev < 1 {
+		return 0, strconv.ErrRange
+	}
+	return rev, nil
+}
@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"net/url"
+	"song-library/internal/middleware"
 	"song-library/internal/model"
+	"song-library/internal/repository"
 	"song-library/internal/service"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
 // ErrorResponse represents a standard error response
@@ -19,26 +25,150 @@ type SuccessResponse struct {
 	Data interface{} `json:"data"`
 }
 
+// requestContext returns the request context carrying the acting user
+func requestContext(c *gin.Context) context.Context {
+	return service.WithActor(c.Request.Context(), service.Actor{
+		Name:      c.GetHeader(middleware.UserHeader),
+		RequestID: c.GetHeader(middleware.RequestIDHeader),
+		ClientIP:  c.ClientIP(),
+	})
+}
+
+// respondError writes an ErrorResponse with a status code derived from err
+func respondError(c *gin.Context, message string, err error) {
+	// Recorded for the request log
+	c.Error(err)
+	c.JSON(errorStatus(err), ErrorResponse{
+		Error:   message,
+		Details: err.Error(),
+	})
+}
+
+// errorStatus maps domain errors to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrSongNotFound),
+		errors.Is(err, repository.ErrRevisionNotFound),
+		errors.Is(err, repository.ErrTranslationNotFound),
+		errors.Is(err, repository.ErrLinkCheckNotFound),
+		errors.Is(err, repository.ErrWebhookNotFound),
+		errors.Is(err, repository.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrDuplicateSong):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrNoAcceptableTranslation):
+		return http.StatusNotAcceptable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// PageLinks are the URLs of the pages around a page of a listing
+type PageLinks struct {
+	// Next and Prev are omitted at either end of the listing, and when it
+	// is not paginated
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// SongPageResponse is a page of songs
+type SongPageResponse struct {
+	Data  []model.Song `json:"data"`
+	Links PageLinks    `json:"links"`
+}
+
 // GetSongs retrieves all songs
 // @Summary Retrieve all songs
-// @Description Get a list of all songs
+// @Description Get a list of all songs. Songs are sorted by ID unless sort lists columns to sort by, each prefixed with "-" for descending order; ties are broken by ID. language selects the songs whose lyrics are in that language, as detected when they were written. broken_links=true selects the songs whose link failed its latest check. With limit or cursor the songs are returned a page at a time, with links to the next and previous pages; the links keep working when songs are added or deleted.
 // @Tags songs
 // @Produce json
-// @Success 200 {object} SuccessResponse
+// @Param sort query string false "Sort columns: id, group_name, song_name, release_date, created_at, updated_at" example(release_date,-song_name)
+// @Param language query string false "ISO 639-1 code of the lyrics language" example(ru)
+// @Param broken_links query bool false "Only songs whose link is broken"
+// @Param limit query int false "Page size, at most 100" default(20)
+// @Param cursor query string false "Cursor from the links of a previous page"
+// @Success 200 {object} SongPageResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /songs [get]
 func GetSongs(songService *service.SongService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		songs, err := songService.GetSongs()
+		brokenLinks, ok := boolQuery(c, "broken_links")
+		if !ok {
+			return
+		}
+		limit, cursor := c.Query("limit"), c.Query("cursor")
+		if limit != "" || cursor != "" {
+			getSongsPage(c, songService, limit, cursor, brokenLinks)
+			return
+		}
+
+		songs, err := songService.ListSongs(requestContext(c), service.SongListQuery{
+			Sort:        c.Query("sort"),
+			Language:    c.Query("language"),
+			BrokenLinks: brokenLinks,
+		})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error:   "Failed to retrieve songs",
+			respondError(c, "Failed to retrieve songs", err)
+			return
+		}
+		c.JSON(http.StatusOK, SongPageResponse{Data: songs})
+	}
+}
+
+// boolQuery parses an optional boolean query parameter. It responds with
+// 400 and returns false when the value is not a boolean.
+func boolQuery(c *gin.Context, name string) (value, ok bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return false, true
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid " + name,
+			Details: err.Error(),
+		})
+		return false, false
+	}
+	return value, true
+}
+
+// getSongsPage responds with one page of songs
+func getSongsPage(c *gin.Context, songService *service.SongService, limit, cursor string, brokenLinks bool) {
+	q := service.SongPageQuery{Sort: c.Query("sort"), Language: c.Query("language"), BrokenLinks: brokenLinks, Cursor: cursor}
+	if limit != "" {
+		var err error
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid limit",
 				Details: err.Error(),
 			})
 			return
 		}
-		c.JSON(http.StatusOK, SuccessResponse{Data: songs})
 	}
+
+	page, err := songService.ListSongsPage(requestContext(c), q)
+	if err != nil {
+		respondError(c, "Failed to retrieve songs", err)
+		return
+	}
+	link := func(cursor string) string {
+		if cursor == "" {
+			return ""
+		}
+		query := url.Values{"cursor": {cursor}}
+		if limit != "" {
+			query.Set("limit", limit)
+		}
+		return c.Request.URL.Path + "?" + query.Encode()
+	}
+	c.JSON(http.StatusOK, SongPageResponse{
+		Data:  page.Songs,
+		Links: PageLinks{Next: link(page.Next), Prev: link(page.Prev)},
+	})
 }
 
 // GetSongByID retrieves a song by its ID
@@ -54,12 +184,9 @@ func GetSongs(songService *service.SongService) gin.HandlerFunc {
 func GetSongByID(songService *service.SongService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		song, err := songService.GetSongByID(id)
+		song, err := songService.GetSongByID(requestContext(c), id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error:   "Failed to retrieve song",
-				Details: err.Error(),
-			})
+			respondError(c, "Failed to retrieve song", err)
 			return
 		}
 		c.JSON(http.StatusOK, SuccessResponse{Data: song})
@@ -73,8 +200,10 @@ func GetSongByID(songService *service.SongService) gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param song body model.Song true "Song data"
+// @Param Idempotency-Key header string false "Retries with the same key return the first response"
 // @Success 201 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /songs [post]
 func AddSong(songService *service.SongService) gin.HandlerFunc {
@@ -88,11 +217,8 @@ func AddSong(songService *service.SongService) gin.HandlerFunc {
 			return
 		}
 
-		if err := songService.AddSong(&song); err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error:   "Failed to add song",
-				Details: err.Error(),
-			})
+		if err := songService.AddSong(requestContext(c), &song); err != nil {
+			respondError(c, "Failed to add song", err)
 			return
 		}
 		c.JSON(http.StatusCreated, SuccessResponse{Data: song})
@@ -109,6 +235,8 @@ func AddSong(songService *service.SongService) gin.HandlerFunc {
 // @Param song body model.Song true "Updated song data"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /songs/{id} [put]
 func UpdateSong(songService *service.SongService) gin.HandlerFunc {
@@ -123,11 +251,8 @@ func UpdateSong(songService *service.SongService) gin.HandlerFunc {
 			return
 		}
 
-		if err := songService.UpdateSong(id, &song); err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error:   "Failed to update song",
-				Details: err.Error(),
-			})
+		if err := songService.UpdateSong(requestContext(c), id, &song); err != nil {
+			respondError(c, "Failed to update song", err)
 			return
 		}
 		c.JSON(http.StatusOK, SuccessResponse{Data: song})
@@ -141,16 +266,14 @@ func UpdateSong(songService *service.SongService) gin.HandlerFunc {
 // @Produce json
 // @Param id path string true "Song ID"
 // @Success 200 {object} SuccessResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /songs/{id} [delete]
 func DeleteSong(songService *service.SongService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		if err := songService.DeleteSong(id); err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Error:   "Failed to delete song",
-				Details: err.Error(),
-			})
+		if err := songService.DeleteSong(requestContext(c), id); err != nil {
+			respondError(c, "Failed to delete song", err)
 			return
 		}
 		c.JSON(http.StatusOK, SuccessResponse{Data: gin.H{"message": "Song deleted successfully"}})
@@ -1,16 +1,26 @@
 package handler
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"song-library/internal/db"
+	"song-library/internal/events"
+	"song-library/internal/middleware"
 	"song-library/internal/model"
 	"song-library/internal/repository"
 	"song-library/internal/service"
+	"song-library/internal/sse"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -18,11 +28,11 @@ import (
 // setupTestHandler creates a test SongService and Gin engine
 func setupTestHandler() (*service.SongService, *gin.Engine) {
 	// Initialize in-memory database
-	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&model.Song{})
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
 
 	// Create repository and service
-	repo := repository.NewSongRepository(db)
+	repo := repository.NewSongRepository(conn)
 	songService := service.NewSongService(repo)
 
 	// Initialize Gin engine
@@ -50,3 +60,361 @@ func TestAddSongHandler(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, w.Code, "HTTP status should be 201")
 	assert.Contains(t, w.Body.String(), "Supermassive Black Hole", "Response should contain the song name")
 }
+
+func TestGetSongByIDHandler(t *testing.T) {
+	songService, r := setupTestHandler()
+	r.GET("/songs/:id", GetSongByID(songService))
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: "Starlight"})
+
+	req, _ := http.NewRequest("GET", "/songs/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Starlight")
+
+	req, _ = http.NewRequest("GET", "/songs/999", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code, "A missing song should return 404")
+}
+
+func TestGetSongLyricsHandler(t *testing.T) {
+	songService, r := setupTestHandler()
+	r.GET("/songs/:id/lyrics", GetSongLyrics(songService))
+
+	songService.AddSong(context.Background(), &model.Song{
+		GroupName: "Muse",
+		SongName:  "Starlight",
+		LRC:       "[00:05.00]Far away\n[00:09.00]This ship is taking me",
+	})
+
+	req, _ := http.NewRequest("GET", "/songs/1/lyrics", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, "HTTP status should be 200")
+	assert.Contains(t, w.Body.String(), `"timestamp":"00:09.00"`, "Response should contain line timestamps")
+
+	req, _ = http.NewRequest("GET", "/songs/1/lyrics?format=plain", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "Far away\nThis ship is taking me", w.Body.String(), "Plain format should return derived text")
+
+	req, _ = http.NewRequest("GET", "/songs/2/lyrics", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code, "Missing song should return 404")
+}
+
+func TestGetSongChordsHandler(t *testing.T) {
+	songService, r := setupTestHandler()
+	r.GET("/songs/:id/chords", GetSongChords(songService))
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: "Starlight", ChordPro: "{key: G}\n[G]Far a[D]way"})
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: "Uprising"})
+
+	req, _ := http.NewRequest("GET", "/songs/1/chords?transpose=+2&format=plain", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Key: A\n\nA    E\nFar away", w.Body.String(), "An unescaped + should be accepted")
+
+	req, _ = http.NewRequest("GET", "/songs/1/chords?capo=2", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"key":"F","capo":2`)
+	assert.Contains(t, w.Body.String(), `{"chord":"C","position":5}`)
+
+	for url, code := range map[string]int{
+		"/songs/1/chords?transpose=13": http.StatusBadRequest,
+		"/songs/1/chords?format=pdf":   http.StatusBadRequest,
+		"/songs/2/chords":              http.StatusNotFound,
+	} {
+		req, _ = http.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, code, w.Code, url)
+	}
+}
+
+func TestGetSongTransliterationHandler(t *testing.T) {
+	songService, r := setupTestHandler()
+	r.GET("/songs", GetSongs(songService))
+	r.GET("/songs/:id/transliteration", GetSongTransliteration(songService))
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Кино", SongName: "Кукушка", Text: "Песен ещё ненаписанных сколько"})
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: "Starlight", Text: "Far away, this ship is taking me far away"})
+
+	req, _ := http.NewRequest("GET", "/songs/1/transliteration?format=plain", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Pesen eshche nenapisannykh skolko", w.Body.String())
+
+	req, _ = http.NewRequest("GET", "/songs?language=ru", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp SongPageResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 1, "The language filter should select Russian songs")
+	assert.Equal(t, "ru", resp.Data[0].Language)
+}
+
+func TestGetAuditLogHandler(t *testing.T) {
+	songService, r := setupTestHandler()
+	r.POST("/songs", AddSong(songService))
+	r.GET("/audit", GetAuditLog(songService))
+
+	req, _ := http.NewRequest("POST", "/songs", bytes.NewBufferString(`{"group_name":"Muse","song_name":"Starlight"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.UserHeader, "alice")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest("GET", "/audit?actor=alice&action=create", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, "HTTP status should be 200")
+	assert.Contains(t, w.Body.String(), `"actor":"alice"`, "Response should contain the audit entry")
+	assert.Contains(t, w.Body.String(), `"before":null`, "Create should have no previous state")
+
+	req, _ = http.NewRequest("GET", "/audit?since=yesterday", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Invalid time should return 400")
+
+	req, _ = http.NewRequest("GET", "/audit?offset=-1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "A negative offset should return 400")
+}
+
+func TestBatchSongsHandler(t *testing.T) {
+	songService, r := setupTestHandler()
+	r.POST("/songs/batch", BatchSongs(songService))
+	batch := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/songs/batch", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := batch(`{"operations":[{"op":"create","song":{"group_name":"Muse","song_name":"Starlight"}},{"op":"delete","id":"7"}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "A failed atomic batch should return 422")
+	assert.Contains(t, w.Body.String(), `"status":"rolled_back","code":424`)
+	assert.Contains(t, w.Body.String(), `"code":404`)
+
+	w = batch(`{"mode":"best_effort","operations":[{"op":"create","song":{"group_name":"Muse","song_name":"Starlight"}},{"op":"delete","id":"7"}]}`)
+	assert.Equal(t, http.StatusMultiStatus, w.Code, "A partly failed best-effort batch should return 207")
+	assert.Contains(t, w.Body.String(), `"succeeded":1,"failed":1`)
+
+	w = batch(`{"operations":[{"op":"update","id":"1","song":{"text":"Far away"}}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = batch(`{"mode":"sometimes","operations":[]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Unknown modes should be rejected")
+}
+
+func TestGetSongsHandler_Pages(t *testing.T) {
+	songService, r := setupTestHandler()
+	r.GET("/songs", GetSongs(songService))
+	for _, name := range []string{"Starlight", "Uprising", "Madness"} {
+		songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: name})
+	}
+	get := func(target string) (int, SongPageResponse) {
+		req, _ := http.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp SongPageResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, page := get("/songs?sort=-song_name&limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, page.Data, 2)
+	assert.Equal(t, "Uprising", page.Data[0].SongName)
+	assert.Empty(t, page.Links.Prev)
+	require.True(t, strings.HasPrefix(page.Links.Next, "/songs?cursor="), "The next link should carry the cursor")
+
+	code, page = get(page.Links.Next)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Madness", page.Data[0].SongName, "The next link should keep the sort and limit")
+	assert.Empty(t, page.Links.Next)
+	assert.NotEmpty(t, page.Links.Prev)
+
+	_, page = get("/songs")
+	assert.Len(t, page.Data, 3, "Without limit or cursor all songs should be returned")
+	code, _ = get("/songs?cursor=bogus")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/songs?limit=many")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(conn))
+	r := gin.Default()
+	r.POST("/webhooks", AddWebhook(webhookService))
+	r.GET("/webhooks", GetWebhooks(webhookService))
+
+	reqBody := []byte(`{"url":"https://partner.example/hook","events":["song.created"]}`)
+	req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code, "HTTP status should be 201")
+	assert.Contains(t, w.Body.String(), `"secret":"`, "Generated secret should be returned on create")
+
+	reqBody = []byte(`{"url":"https://partner.example/hook","events":["song.played"]}`)
+	req, _ = http.NewRequest("POST", "/webhooks", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Unknown events should be rejected")
+
+	req, _ = http.NewRequest("GET", "/webhooks", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "partner.example", "Webhook should be listed")
+	assert.NotContains(t, w.Body.String(), `"secret"`, "Secrets should not be listed")
+}
+
+func TestStreamSongEventsHandler(t *testing.T) {
+	broker := sse.NewBroker(sse.DefaultBufferSize)
+	broker.HandleEvent(events.NewEvent(events.SongCreated, &model.Song{ID: 1, SongName: "Starlight"}))
+
+	r := gin.New()
+	r.GET("/songs/events", StreamSongEvents(broker, 10*time.Millisecond))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/songs/events", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err, "Opening the stream should not return an error")
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	broker.HandleEvent(events.NewEvent(events.SongDeleted, &model.Song{ID: 1}))
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 20 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		lines = append(lines, strings.TrimSpace(line))
+		if strings.HasPrefix(line, ": heartbeat") {
+			break
+		}
+	}
+	stream := strings.Join(lines, "\n")
+	assert.Contains(t, stream, "id: 1\nevent: song.created", "Buffered event should be replayed")
+	assert.Contains(t, stream, "id: 2\nevent: song.deleted", "New event should be streamed")
+	assert.Contains(t, stream, ": heartbeat", "Heartbeats should be sent")
+
+	cancel()
+	resp.Body.Close()
+	assert.Eventually(t, func() bool { return broker.Clients() == 0 }, time.Second, 10*time.Millisecond,
+		"Client should be removed after disconnecting")
+}
+
+func TestSongTranslationHandlers(t *testing.T) {
+	songService, r := setupTestHandler()
+	r.GET("/songs/:id/translations", GetSongTranslations(songService))
+	r.GET("/songs/:id/translations/:lang", GetSongTranslation(songService))
+	r.PUT("/songs/:id/translations/:lang", PutSongTranslation(songService))
+	r.DELETE("/songs/:id/translations/:lang", DeleteSongTranslation(songService))
+	r.GET("/songs/:id/side-by-side", GetSongSideBySide(songService))
+	songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: "Starlight", Text: "Far away\n\nMy life"})
+
+	put := func(lang, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/songs/1/translations/"+lang, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusCreated, put("es", `{"text":"Muy lejos\n\nMi vida"}`).Code)
+	assert.Equal(t, http.StatusOK, put("es", `{"verses":["Lejos","Mi vida"]}`).Code, "Replacing a translation should return 200")
+	assert.Equal(t, http.StatusBadRequest, put("de", `{"verses":["Weit weg"]}`).Code)
+
+	req, _ := http.NewRequest("GET", "/songs/1/side-by-side", nil)
+	req.Header.Set("Accept-Language", "es-MX, en;q=0.5")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "es", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+	assert.Contains(t, w.Body.String(), `{"number":1,"original":"Far away","translation":"Lejos"}`)
+
+	req, _ = http.NewRequest("GET", "/songs/1/side-by-side", nil)
+	req.Header.Set("Accept-Language", "ja")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+
+	req, _ = http.NewRequest("DELETE", "/songs/1/translations/es", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	req, _ = http.NewRequest("GET", "/songs/1/translations/es", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSongLinkHandlers(t *testing.T) {
+	repo := repository.NewMemorySongRepository()
+	songService := service.NewSongService(repo)
+	r := gin.Default()
+	r.GET("/songs", GetSongs(songService))
+	r.GET("/songs/:id/link", GetSongLinkCheck(songService))
+	for i, name := range []string{"Starlight", "Uprising", "Madness"} {
+		song := &model.Song{GroupName: "Muse", SongName: name, Link: "https://example.com/" + name}
+		songService.AddSong(context.Background(), song)
+		status := model.LinkBroken
+		if i == 1 {
+			status = model.LinkOK
+		}
+		repo.SaveLinkCheck(&model.LinkCheck{SongID: song.ID, URL: song.Link, Status: status, CheckedAt: time.Now()})
+	}
+	get := func(target string) (int, SongPageResponse) {
+		req, _ := http.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp SongPageResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, page := get("/songs?broken_links=true")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Starlight", "Madness"}, songNames(page.Data))
+	code, page = get("/songs?broken_links=true&limit=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Starlight"}, songNames(page.Data))
+	_, page = get(page.Links.Next)
+	assert.Equal(t, []string{"Madness"}, songNames(page.Data), "The cursor should keep the filter")
+	code, _ = get("/songs?broken_links=maybe")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	req, _ := http.NewRequest("GET", "/songs/2/link", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"ok"`)
+
+	songService.UpdateSong(context.Background(), "2", &model.Song{GroupName: "Muse", SongName: "Uprising", Link: "https://example.com/live"})
+	req, _ = http.NewRequest("GET", "/songs/2/link", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code, "A changed link has not been checked")
+}
+
+func songNames(songs []model.Song) []string {
+	names := make([]string, len(songs))
+	for i, song := range songs {
+		names[i] = song.SongName
+	}
+	return names
+}
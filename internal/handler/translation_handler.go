@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSongTranslations retrieves the translations of a song
+// @Summary Retrieve song translations
+// @Description Get every translation of a song's lyrics, ordered by language
+// @Tags translations
+// @Produce json
+// @Param id path string true "Song ID"
+// @Success 200 {object} SuccessResponse{data=[]model.SongTranslation}
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/translations [get]
+func GetSongTranslations(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		translations, err := songService.GetTranslations(requestContext(c), c.Param("id"))
+		if err != nil {
+			respondError(c, "Failed to retrieve translations", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: translations})
+	}
+}
+
+// GetSongTranslation retrieves the translation of a song into a language
+// @Summary Retrieve a song translation
+// @Description Get the translated verses of a song's lyrics in a language
+// @Tags translations
+// @Produce json
+// @Param id path string true "Song ID"
+// @Param lang path string true "BCP 47 language tag, e.g. en or pt-BR"
+// @Success 200 {object} SuccessResponse{data=model.SongTranslation}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/translations/{lang} [get]
+func GetSongTranslation(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		translation, err := songService.GetTranslation(requestContext(c), c.Param("id"), c.Param("lang"))
+		if err != nil {
+			respondError(c, "Failed to retrieve translation", err)
+			return
+		}
+		c.Header("Content-Language", translation.Language)
+		c.JSON(http.StatusOK, SuccessResponse{Data: translation})
+	}
+}
+
+// PutSongTranslation creates or replaces the translation of a song
+// @Summary Create or replace a song translation
+// @Description Store the translation of a song's lyrics into a language, as a list of verses or as text with verses separated by blank lines. It must have as many verses as the lyrics.
+// @Tags translations
+// @Accept json
+// @Produce json
+// @Param id path string true "Song ID"
+// @Param lang path string true "BCP 47 language tag, e.g. en or pt-BR"
+// @Param translation body service.TranslationInput true "Translated verses"
+// @Success 200 {object} SuccessResponse{data=model.SongTranslation}
+// @Success 201 {object} SuccessResponse{data=model.SongTranslation}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/translations/{lang} [put]
+func PutSongTranslation(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input service.TranslationInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid request payload",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		translation, created, err := songService.SaveTranslation(requestContext(c), c.Param("id"), c.Param("lang"), input)
+		if err != nil {
+			respondError(c, "Failed to save translation", err)
+			return
+		}
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		c.Header("Content-Language", translation.Language)
+		c.JSON(status, SuccessResponse{Data: translation})
+	}
+}
+
+// DeleteSongTranslation deletes the translation of a song into a language
+// @Summary Delete a song translation
+// @Description Remove the translation of a song's lyrics into a language
+// @Tags translations
+// @Produce json
+// @Param id path string true "Song ID"
+// @Param lang path string true "BCP 47 language tag, e.g. en or pt-BR"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/translations/{lang} [delete]
+func DeleteSongTranslation(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := songService.DeleteTranslation(requestContext(c), c.Param("id"), c.Param("lang")); err != nil {
+			respondError(c, "Failed to delete translation", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: gin.H{"message": "Translation deleted successfully"}})
+	}
+}
+
+// GetSongSideBySide retrieves a song's lyrics paired with a translation
+// @Summary Compare lyrics with a translation
+// @Description Get the verses of a song's lyrics each next to its translation. The lang parameter selects the translation; without it the translation is negotiated from the Accept-Language header.
+// @Tags translations
+// @Produce json
+// @Param id path string true "Song ID"
+// @Param lang query string false "BCP 47 language tag of the translation"
+// @Param Accept-Language header string false "Preferred translation languages, e.g. pt-BR, en;q=0.8"
+// @Success 200 {object} SuccessResponse{data=service.SideBySide}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 406 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /songs/{id}/side-by-side [get]
+func GetSongSideBySide(songService *service.SongService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := c.Query("lang")
+		if lang == "" {
+			c.Header("Vary", "Accept-Language")
+		}
+		view, err := songService.SideBySide(requestContext(c), c.Param("id"), lang, c.GetHeader("Accept-Language"))
+		if err != nil {
+			respondError(c, "Failed to retrieve translation", err)
+			return
+		}
+		c.Header("Content-Language", view.TranslationLanguage)
+		c.JSON(http.StatusOK, SuccessResponse{Data: view})
+	}
+}
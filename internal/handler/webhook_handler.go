@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"net/http"
+	"song-library/internal/model"
+	"song-library/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookRequest is the payload for creating or updating a webhook
+type WebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+	Secret string   `json:"secret,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+func (r WebhookRequest) toModel() *model.Webhook {
+	webhook := &model.Webhook{
+		URL:    r.URL,
+		Events: r.Events,
+		Secret: r.Secret,
+		Active: true,
+	}
+	if r.Active != nil {
+		webhook.Active = *r.Active
+	}
+	return webhook
+}
+
+// GetWebhooks retrieves all webhook subscriptions
+// @Summary Retrieve all webhooks
+// @Description Get all webhook subscriptions. Secrets are not included.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=[]model.Webhook}
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks [get]
+func GetWebhooks(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhooks, err := webhookService.GetWebhooks(requestContext(c))
+		if err != nil {
+			respondError(c, "Failed to retrieve webhooks", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: webhooks})
+	}
+}
+
+// GetWebhookByID retrieves a webhook subscription by its ID
+// @Summary Retrieve a webhook by ID
+// @Description Get a webhook subscription by its ID. The secret is not included.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} SuccessResponse{data=model.Webhook}
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [get]
+func GetWebhookByID(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhook, err := webhookService.GetWebhookByID(requestContext(c), c.Param("id"))
+		if err != nil {
+			respondError(c, "Failed to retrieve webhook", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: webhook})
+	}
+}
+
+// AddWebhook creates a webhook subscription
+// @Summary Add a webhook
+// @Description Subscribe a URL to song events (song.created, song.updated, song.deleted). The signing secret is generated when omitted and only returned in this response.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body WebhookRequest true "Webhook data"
+// @Param Idempotency-Key header string false "Retries with the same key return the first response"
+// @Success 201 {object} SuccessResponse{data=model.Webhook}
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks [post]
+func AddWebhook(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req WebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid request payload",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		webhook := req.toModel()
+		if err := webhookService.AddWebhook(requestContext(c), webhook); err != nil {
+			respondError(c, "Failed to add webhook", err)
+			return
+		}
+		c.JSON(http.StatusCreated, SuccessResponse{Data: webhook})
+	}
+}
+
+// UpdateWebhook updates a webhook subscription
+// @Summary Update a webhook
+// @Description Replace the URL, events and active flag of a webhook. The secret is rotated when given.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param webhook body WebhookRequest true "Webhook data"
+// @Success 200 {object} SuccessResponse{data=model.Webhook}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [put]
+func UpdateWebhook(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req WebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "Invalid request payload",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		webhook, err := webhookService.UpdateWebhook(requestContext(c), c.Param("id"), req.toModel())
+		if err != nil {
+			respondError(c, "Failed to update webhook", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: webhook})
+	}
+}
+
+// DeleteWebhook deletes a webhook subscription
+// @Summary Delete a webhook
+// @Description Remove a webhook subscription and its delivery log
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := webhookService.DeleteWebhook(requestContext(c), c.Param("id")); err != nil {
+			respondError(c, "Failed to delete webhook", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: gin.H{"message": "Webhook deleted successfully"}})
+	}
+}
+
+// GetWebhookDeliveries retrieves the delivery log of a webhook
+// @Summary Retrieve webhook deliveries
+// @Description Get the deliveries of a webhook, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param status query string false "Delivery status" Enums(pending, succeeded, dead)
+// @Success 200 {object} SuccessResponse{data=[]model.WebhookDelivery}
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deliveries, err := webhookService.GetDeliveries(requestContext(c), c.Param("id"), c.Query("status"))
+		if err != nil {
+			respondError(c, "Failed to retrieve deliveries", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: deliveries})
+	}
+}
+
+// GetWebhookDeadLetters retrieves deliveries that exhausted their retries
+// @Summary Retrieve dead letters
+// @Description Get webhook deliveries of all subscriptions that ran out of attempts
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=[]model.WebhookDelivery}
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/dead-letters [get]
+func GetWebhookDeadLetters(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deliveries, err := webhookService.GetDeadLetters(requestContext(c))
+		if err != nil {
+			respondError(c, "Failed to retrieve dead letters", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: deliveries})
+	}
+}
+
+// RedeliverWebhook requeues a dead delivery
+// @Summary Redeliver a dead letter
+// @Description Move a dead delivery back to the queue with a fresh set of attempts
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Delivery ID"
+// @Param Idempotency-Key header string false "Retries with the same key return the first response"
+// @Success 200 {object} SuccessResponse{data=model.WebhookDelivery}
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /webhooks/dead-letters/{id}/redeliver [post]
+func RedeliverWebhook(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery, err := webhookService.Redeliver(requestContext(c), c.Param("id"))
+		if err != nil {
+			respondError(c, "Failed to redeliver", err)
+			return
+		}
+		c.JSON(http.StatusOK, SuccessResponse{Data: delivery})
+	}
+}
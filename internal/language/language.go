@@ -0,0 +1,241 @@
+// Package language detects the language of lyrics and transliterates
+// Cyrillic and Greek text to Latin script
+package language
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Undetermined is the ISO 639-2 code for text whose language could not be
+// detected
+const Undetermined = "und"
+
+// scripts lists the scripts that identify a language on their own, after
+// Cyrillic, Greek and Latin which are handled separately. Kana is checked
+// before Han, since Japanese mixes both.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Georgian, "ka"},
+	{unicode.Armenian, "hy"},
+	{unicode.Thai, "th"},
+}
+
+// stopwords are frequent short words of languages written in Latin script
+var stopwords = map[string][]string{
+	"en": {"the", "and", "you", "i", "to", "of", "in", "is", "it", "my", "me", "your", "that", "on", "with", "for", "all", "are", "be", "this"},
+	"de": {"der", "die", "das", "und", "ich", "du", "nicht", "ist", "ein", "eine", "mit", "mich", "dich", "zu", "auf", "es", "wir", "sie", "mein", "dein"},
+	"fr": {"le", "la", "les", "et", "je", "tu", "de", "des", "un", "une", "est", "pas", "que", "qui", "mon", "ton", "dans", "pour", "moi", "toi"},
+	"es": {"el", "la", "los", "las", "y", "yo", "tu", "de", "que", "en", "un", "una", "es", "no", "mi", "por", "con", "para", "como", "del"},
+	"it": {"il", "la", "le", "e", "io", "tu", "di", "che", "non", "un", "una", "è", "per", "mi", "ti", "con", "sono", "del", "della", "ma"},
+	"pt": {"o", "a", "os", "as", "e", "eu", "voce", "você", "de", "que", "não", "um", "uma", "é", "meu", "minha", "com", "para", "em", "do"},
+	"nl": {"de", "het", "een", "en", "ik", "je", "jij", "niet", "is", "van", "dat", "met", "mijn", "op", "voor", "zijn", "wij", "maar", "ook", "naar"},
+	"pl": {"i", "w", "nie", "na", "się", "to", "że", "jak", "ja", "ty", "mnie", "cię", "jest", "do", "tak", "mój", "moja", "co", "ale", "bo"},
+}
+
+// latinLanguages fixes the order in which stopword scores are compared, so
+// that ties are broken the same way every time
+var latinLanguages = []string{"en", "es", "fr", "de", "it", "pt", "nl", "pl"}
+
+// Detect returns the ISO 639-1 code of the language text is written in,
+// Undetermined when it cannot tell, or "" for text without letters. The
+// script decides most languages; languages sharing the Cyrillic or Latin
+// script are told apart by their distinctive letters or frequent words.
+func Detect(text string) string {
+	var cyrillic, greek, latin, letters int
+	other := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			for _, s := range scripts {
+				if unicode.Is(s.table, r) {
+					other[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese is written with kana and Han, so any kana marks it
+	if other["ja"] > 0 && other["ja"]+other["zh"] >= cyrillic+greek+latin {
+		return "ja"
+	}
+	best, count := "", 0
+	for _, s := range scripts {
+		if other[s.lang] > count {
+			best, count = s.lang, other[s.lang]
+		}
+	}
+	switch {
+	case cyrillic >= greek && cyrillic >= latin && cyrillic > count:
+		return detectCyrillic(text)
+	case greek >= latin && greek > count:
+		return "el"
+	case latin > count:
+		return detectLatin(text)
+	}
+	return best
+}
+
+// detectCyrillic tells Cyrillic languages apart by their own letters
+func detectCyrillic(text string) string {
+	has := func(letters string) bool { return strings.ContainsAny(strings.ToLower(text), letters) }
+	switch {
+	case has("ђћџљњј"):
+		return "sr"
+	case has("ў"):
+		return "be"
+	case has("їєґ"), has("і") && !has("ыэё"):
+		return "uk"
+	case has("ъ") && !has("ыэё"):
+		return "bg"
+	}
+	return "ru"
+}
+
+// detectLatin picks the language whose stopwords occur most often in text
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	scores := make(map[string]int)
+	for lang, list := range stopwords {
+		for _, word := range words {
+			for _, stopword := range list {
+				if word == stopword {
+					scores[lang]++
+					break
+				}
+			}
+		}
+	}
+	best, score := Undetermined, 0
+	for _, lang := range latinLanguages {
+		if scores[lang] > score {
+			best, score = lang, scores[lang]
+		}
+	}
+	return best
+}
+
+// cyrillicToLatin follows the Russian passport (ICAO) romanization
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+	'і': "i", 'ї': "i", 'є': "ie", 'ґ': "g", 'ў': "u",
+	'ђ': "dj", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz",
+}
+
+// languageRules override cyrillicToLatin for a language
+var languageRules = map[string]map[rune]string{
+	// Ukrainian national transliteration
+	"uk": {'г': "h", 'и': "y"},
+	// Bulgarian streamlined system
+	"bg": {'ъ': "a", 'щ': "sht", 'ю': "yu", 'я': "ya", 'й': "y", 'х': "h"},
+}
+
+// greekToLatin follows ELOT 743 for single letters
+var greekToLatin = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// greekDigraphs are Greek letter pairs written as one sound
+var greekDigraphs = map[string]string{"ου": "ou", "αυ": "av", "ευ": "ev", "ηυ": "iv"}
+
+// Searchable returns text in lower case, followed on a new line by its
+// Latin transliteration when it has Cyrillic or Greek letters, so that a
+// substring search matches either script
+func Searchable(text string) string {
+	lower := strings.ToLower(text)
+	if latin := strings.ToLower(Transliterate(text, "")); latin != lower {
+		return lower + "\n" + latin
+	}
+	return lower
+}
+
+// Transliterate writes the Cyrillic and Greek letters of text in Latin
+// script and leaves everything else as it is. lang, such as "uk", selects
+// the rules of that language; other languages use the Russian ones.
+func Transliterate(text, lang string) string {
+	rules := languageRules[lang]
+	src := []rune(text)
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(src); i++ {
+		start, r := i, src[i]
+		lower := unicode.ToLower(r)
+		var latin string
+		var ok bool
+		switch {
+		case unicode.Is(unicode.Greek, r):
+			lower = stripAccents(lower)
+			if i+1 < len(src) {
+				pair := string([]rune{lower, stripAccents(unicode.ToLower(src[i+1]))})
+				if latin, ok = greekDigraphs[pair]; ok {
+					i++
+					break
+				}
+			}
+			latin, ok = greekToLatin[lower]
+		default:
+			if latin, ok = rules[lower]; !ok {
+				latin, ok = cyrillicToLatin[lower]
+			}
+		}
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.IsUpper(r) && latin != "" {
+			latin = matchCase(latin, src, start, i)
+		}
+		b.WriteString(latin)
+	}
+	return b.String()
+}
+
+// stripAccents removes the tonos and dialytika from a Greek letter
+func stripAccents(r rune) rune {
+	decomposed := []rune(norm.NFD.String(string(r)))
+	return decomposed[0]
+}
+
+// matchCase capitalizes latin, the transliteration of src[first:last+1]
+// which starts with an upper case letter: fully in an upper case word, only
+// the first letter otherwise
+func matchCase(latin string, src []rune, first, last int) string {
+	if (last+1 < len(src) && unicode.IsUpper(src[last+1])) || (first > 0 && unicode.IsUpper(src[first-1])) || (last > first && unicode.IsUpper(src[last])) {
+		return strings.ToUpper(latin)
+	}
+	return strings.ToUpper(latin[:1]) + latin[1:]
+}
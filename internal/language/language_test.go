@@ -0,0 +1,39 @@
+package language
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	for text, want := range map[string]string{
+		"Звезда по имени Солнце, и мы все живём":    "ru",
+		"Ще не вмерла України і слава, і воля":      "uk",
+		"Σαν πας στον πηγαιμό για την Ιθάκη":        "el",
+		"Far away, this ship is taking me far away": "en",
+		"Ich will nicht, dass du mich so siehst":    "de",
+		"Je ne regrette rien, non, rien de rien":    "fr",
+		"La vida es un carnaval y las penas se van": "es",
+		"上を向いて歩こう 涙がこぼれないように":                       "ja",
+		"Starlight": Undetermined,
+		"":          "",
+		"1, 2, 3!":  "",
+	} {
+		assert.Equal(t, want, Detect(text), text)
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	assert.Equal(t, "Zvezda po imeni Solntse", Transliterate("Звезда по имени Солнце", "ru"))
+	assert.Equal(t, "KINO — Gruppa krovi", Transliterate("КИНО — Группа крови", "ru"), "Upper case words should stay upper case")
+	assert.Equal(t, "Shche ne vmerla Ukrainy", Transliterate("Ще не вмерла України", "uk"))
+	assert.Equal(t, "Ithaki, Athina", Transliterate("Ιθάκη, Αθήνα", "el"), "Accents should be dropped")
+	assert.Equal(t, "Ouranos", Transliterate("Ουρανός", "el"))
+	assert.Equal(t, "Far away", Transliterate("Far away", "en"), "Latin text should be kept")
+}
+
+func TestSearchable(t *testing.T) {
+	assert.Equal(t, "кино\nkino", Searchable("КИНО"))
+	assert.Equal(t, "far away", Searchable("Far Away"), "Latin text should not be repeated")
+}
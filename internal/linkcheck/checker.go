@@ -0,0 +1,169 @@
+package linkcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"song-library/internal/model"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Checker defaults
+const (
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxRedirects = 5
+	userAgent           = "song-library-linkcheck/1.0"
+)
+
+var (
+	errTooManyRedirects = errors.New("too many redirects")
+	errBlockedAddress   = errors.New("address is not public")
+)
+
+// Checker requests links to find out whether they still work. Links are
+// requested with HEAD, falling back to GET for servers that do not
+// support it, and redirects are followed up to MaxRedirects.
+//
+// Unless AllowPrivateNetworks is set, links to loopback, private and
+// link-local addresses are refused, so that songs cannot be used to probe
+// the internal network.
+type Checker struct {
+	client *http.Client
+	now    func() time.Time
+
+	Timeout              time.Duration
+	MaxRedirects         int
+	AllowPrivateNetworks bool
+}
+
+// NewChecker creates a Checker with the default settings
+func NewChecker() *Checker {
+	c := &Checker{
+		now:          time.Now,
+		Timeout:      DefaultTimeout,
+		MaxRedirects: DefaultMaxRedirects,
+	}
+	dialer := &net.Dialer{Timeout: DefaultTimeout, Control: c.control}
+	c.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: DefaultTimeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     time.Minute,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > c.MaxRedirects {
+				return errTooManyRedirects
+			}
+			return nil
+		},
+	}
+	return c
+}
+
+// control refuses connections to non-public addresses. It runs after DNS
+// resolution, so host names resolving to such addresses are refused too.
+func (c *Checker) control(network, address string, _ syscall.RawConn) error {
+	if c.AllowPrivateNetworks {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errors.Wrap(errBlockedAddress, host)
+	}
+	return nil
+}
+
+// Check requests link and returns the result without the song ID. Links
+// answering with a status below 400 after redirects are ok, all others
+// are broken.
+func (c *Checker) Check(ctx context.Context, link string) model.LinkCheck {
+	check := model.LinkCheck{URL: link, Status: model.LinkBroken, CheckedAt: c.now()}
+	if media, ok := Identify(link); ok {
+		check.Provider, check.MediaID = media.Provider, media.ID
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		check.Error = "not an http or https URL"
+		return check
+	}
+
+	resp, err := c.request(ctx, http.MethodHead, link)
+	if err == nil && headUnsupported(resp.StatusCode) {
+		resp, err = c.request(ctx, http.MethodGet, link)
+	}
+	if resp != nil {
+		check.StatusCode = resp.StatusCode
+		check.FinalURL = resp.Request.URL.String()
+		check.ContentType = resp.Header.Get("Content-Type")
+	}
+	switch {
+	case err != nil:
+		check.Error = err.Error()
+		check.StatusCode = 0
+	case resp.StatusCode >= http.StatusBadRequest:
+		check.Error = resp.Status
+	default:
+		check.Status = model.LinkOK
+	}
+	if check.FinalURL == link {
+		check.FinalURL = ""
+	}
+	// Short links redirect to the provider
+	if check.Provider == "" && check.FinalURL != "" {
+		if media, ok := Identify(check.FinalURL); ok {
+			check.Provider, check.MediaID = media.Provider, media.ID
+		}
+	}
+	return check
+}
+
+// request sends a request without reading the response body. On a
+// redirect error, the response that could not be followed is returned
+// with the error.
+func (c *Checker) request(ctx context.Context, method, link string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.client.Do(req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		var urlErr *url.Error
+		switch {
+		case errors.Is(err, errTooManyRedirects):
+			return resp, errors.Errorf("stopped after %d redirects", c.MaxRedirects)
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, errors.Errorf("no response within %s", c.Timeout)
+		case errors.As(err, &urlErr):
+			// The URL is already in the check
+			return nil, urlErr.Err
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// headUnsupported reports whether a HEAD response status may come from a
+// server that only handles GET
+func headUnsupported(status int) bool {
+	switch status {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented, http.StatusForbidden, http.StatusBadRequest:
+		return true
+	}
+	return false
+}
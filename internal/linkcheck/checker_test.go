@@ -0,0 +1,142 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestChecker creates a Checker that may reach the local test servers
+func newTestChecker() *Checker {
+	checker := NewChecker()
+	checker.AllowPrivateNetworks = true
+	return checker
+}
+
+func TestChecker_Check(t *testing.T) {
+	var methods []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/mp4")
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	checker := newTestChecker()
+	ctx := context.Background()
+
+	check := checker.Check(ctx, server.URL+"/ok")
+	assert.Equal(t, model.LinkOK, check.Status)
+	assert.Equal(t, http.StatusOK, check.StatusCode)
+	assert.Equal(t, "video/mp4", check.ContentType)
+	assert.Empty(t, check.FinalURL, "A link without redirects has no final URL")
+	assert.False(t, check.CheckedAt.IsZero())
+
+	check = checker.Check(ctx, server.URL+"/gone")
+	assert.Equal(t, model.LinkBroken, check.Status)
+	assert.Equal(t, http.StatusNotFound, check.StatusCode)
+	assert.Equal(t, "404 Not Found", check.Error)
+
+	check = checker.Check(ctx, server.URL+"/short")
+	assert.Equal(t, model.LinkOK, check.Status)
+	assert.Equal(t, server.URL+"/ok", check.FinalURL, "Redirects should be followed")
+
+	check = checker.Check(ctx, server.URL+"/loop")
+	assert.Equal(t, model.LinkBroken, check.Status)
+	assert.Equal(t, "stopped after 5 redirects", check.Error)
+	assert.Zero(t, check.StatusCode)
+
+	check = checker.Check(ctx, server.URL+"/get-only")
+	assert.Equal(t, model.LinkOK, check.Status, "GET should be tried when HEAD is not allowed")
+	assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods)
+
+	check = checker.Check(ctx, "mailto:muse@example.com")
+	assert.Equal(t, model.LinkBroken, check.Status)
+	assert.Equal(t, "not an http or https URL", check.Error)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	checker := newTestChecker()
+	checker.Timeout = 50 * time.Millisecond
+	check := checker.Check(context.Background(), server.URL)
+	assert.Equal(t, model.LinkBroken, check.Status)
+	assert.Equal(t, "no response within 50ms", check.Error)
+}
+
+func TestChecker_RefusesPrivateAddresses(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	check := NewChecker().Check(context.Background(), server.URL)
+	assert.Equal(t, model.LinkBroken, check.Status)
+	assert.Contains(t, check.Error, "address is not public")
+	assert.False(t, requested, "Loopback addresses should not be requested")
+}
+
+func TestJob_CheckDue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	repo := repository.NewMemorySongRepository()
+	working := &model.Song{GroupName: "Muse", SongName: "Starlight", Link: server.URL + "/ok"}
+	broken := &model.Song{GroupName: "Muse", SongName: "Uprising", Link: server.URL + "/gone"}
+	unlinked := &model.Song{GroupName: "Radiohead", SongName: "Creep"}
+	for _, song := range []*model.Song{working, broken, unlinked} {
+		require.NoError(t, repo.AddSong(song))
+	}
+
+	job := NewJob(repo, newTestChecker())
+	job.Concurrency = 2
+	assert.Equal(t, 2, job.CheckDue(context.Background()), "Both links should be checked")
+	assert.Zero(t, job.CheckDue(context.Background()), "Fresh checks should not be repeated")
+
+	check, err := repo.GetLinkCheck(strconv.FormatUint(uint64(working.ID), 10))
+	require.NoError(t, err)
+	assert.Equal(t, model.LinkOK, check.Status)
+	songs, err := repo.ListSongs(repository.SongQuery{BrokenLinks: true})
+	require.NoError(t, err)
+	require.Len(t, songs, 1)
+	assert.Equal(t, "Uprising", songs[0].SongName)
+
+	job.now = func() time.Time { return time.Now().Add(job.MaxAge + time.Minute) }
+	assert.Equal(t, 2, job.CheckDue(context.Background()), "Old checks should be repeated")
+}
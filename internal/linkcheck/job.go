@@ -0,0 +1,104 @@
+package linkcheck
+
+import (
+	"context"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/pkg/logger"
+	"sync"
+	"time"
+)
+
+// Job defaults
+const (
+	DefaultInterval    = time.Hour
+	DefaultMaxAge      = 24 * time.Hour
+	DefaultConcurrency = 4
+	DefaultBatchSize   = 100
+)
+
+// Job periodically checks the links of songs and records the results.
+// Links that were never checked or changed since their last check come
+// first, then those whose last check is older than MaxAge.
+type Job struct {
+	repo    repository.SongRepository
+	checker *Checker
+	now     func() time.Time
+
+	Interval    time.Duration
+	MaxAge      time.Duration
+	Concurrency int
+	BatchSize   int
+}
+
+// NewJob creates a Job that checks the links of repo's songs with checker
+func NewJob(repo repository.SongRepository, checker *Checker) *Job {
+	return &Job{
+		repo:        repo,
+		checker:     checker,
+		now:         time.Now,
+		Interval:    DefaultInterval,
+		MaxAge:      DefaultMaxAge,
+		Concurrency: DefaultConcurrency,
+		BatchSize:   DefaultBatchSize,
+	}
+}
+
+// Run checks due links every Interval until ctx is cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches are being checked
+		for j.CheckDue(ctx) == j.BatchSize && ctx.Err() == nil {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckDue checks up to BatchSize due links, at most Concurrency at a
+// time, and returns how many results were recorded
+func (j *Job) CheckDue(ctx context.Context) int {
+	songs, err := j.repo.GetLinksToCheck(j.now().Add(-j.MaxAge), j.BatchSize)
+	if err != nil {
+		logger.Error("Failed to load links to check", logger.Fields{"error": err.Error()})
+		return 0
+	}
+
+	checks := make([]model.LinkCheck, len(songs))
+	sem := make(chan struct{}, max(j.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range songs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			checks[i] = j.checker.Check(ctx, songs[i].Link)
+			checks[i].SongID = songs[i].ID
+		}()
+	}
+	wg.Wait()
+	// Checks interrupted by shutdown say nothing about the links
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	saved := 0
+	for i := range checks {
+		check := &checks[i]
+		if check.Status == model.LinkBroken {
+			logger.Warn("Song link is broken", logger.Fields{"song_id": check.SongID, "url": check.URL, "error": check.Error})
+		}
+		if err := j.repo.SaveLinkCheck(check); err != nil {
+			logger.Error("Failed to save link check", logger.Fields{"song_id": check.SongID, "error": err.Error()})
+			continue
+		}
+		saved++
+	}
+	return saved
+}
@@ -0,0 +1,104 @@
+// Package linkcheck checks the links of songs in the background
+package linkcheck
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Known media providers
+const (
+	ProviderYouTube     = "youtube"
+	ProviderVimeo       = "vimeo"
+	ProviderDailymotion = "dailymotion"
+	ProviderSpotify     = "spotify"
+	ProviderSoundCloud  = "soundcloud"
+)
+
+var (
+	youTubeID     = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoID       = regexp.MustCompile(`^[0-9]+$`)
+	dailymotionID = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	spotifyID     = regexp.MustCompile(`^[A-Za-z0-9]{22}$`)
+)
+
+// Media identifies a video or track on a known provider
+type Media struct {
+	Provider string
+	ID       string
+}
+
+// Identify recognizes links to the known providers and extracts the ID of
+// the video or track, e.g. "youtube" and "dQw4w9WgXcQ" for
+// https://youtu.be/dQw4w9WgXcQ. ok is false for other links.
+func Identify(link string) (media Media, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return Media{}, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	segments := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+	segment := func(i int) string {
+		if i < len(segments) {
+			return segments[i]
+		}
+		return ""
+	}
+
+	var id string
+	switch host {
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		media.Provider = ProviderYouTube
+		switch segment(0) {
+		case "watch":
+			id = u.Query().Get("v")
+		case "embed", "shorts", "live", "v":
+			id = segment(1)
+		}
+		ok = youTubeID.MatchString(id)
+	case "youtu.be":
+		media.Provider = ProviderYouTube
+		id = segment(0)
+		ok = youTubeID.MatchString(id)
+	case "vimeo.com":
+		media.Provider = ProviderVimeo
+		id = segment(0)
+		ok = vimeoID.MatchString(id)
+	case "player.vimeo.com":
+		media.Provider = ProviderVimeo
+		id = segment(1)
+		ok = segment(0) == "video" && vimeoID.MatchString(id)
+	case "dailymotion.com":
+		media.Provider = ProviderDailymotion
+		if segment(0) == "embed" {
+			segments = segments[1:]
+		}
+		// The ID may be followed by a slug: /video/x7tgad0_title
+		id, _, _ = strings.Cut(segment(1), "_")
+		ok = segment(0) == "video" && dailymotionID.MatchString(id)
+	case "dai.ly":
+		media.Provider = ProviderDailymotion
+		id = segment(0)
+		ok = dailymotionID.MatchString(id)
+	case "open.spotify.com":
+		media.Provider = ProviderSpotify
+		// Localized links start with the locale: /intl-de/track/<id>
+		if strings.HasPrefix(segment(0), "intl-") {
+			segments = segments[1:]
+		}
+		id = segment(1)
+		ok = segment(0) == "track" && spotifyID.MatchString(id)
+	case "soundcloud.com":
+		media.Provider = ProviderSoundCloud
+		// Tracks have no public ID in their URL, the path names them
+		id = segment(0) + "/" + segment(1)
+		ok = len(segments) == 2 && segment(1) != "sets"
+	}
+	if !ok {
+		return Media{}, false
+	}
+	media.ID = id
+	return media, true
+}
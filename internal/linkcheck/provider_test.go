@@ -0,0 +1,41 @@
+package linkcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		link string
+		want Media
+		ok   bool
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42", Media{ProviderYouTube, "dQw4w9WgXcQ"}, true},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", Media{ProviderYouTube, "dQw4w9WgXcQ"}, true},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ", Media{ProviderYouTube, "dQw4w9WgXcQ"}, true},
+		{"https://youtu.be/dQw4w9WgXcQ?si=abc", Media{ProviderYouTube, "dQw4w9WgXcQ"}, true},
+		{"https://www.youtube.com/embed/dQw4w9WgXcQ", Media{ProviderYouTube, "dQw4w9WgXcQ"}, true},
+		{"https://www.youtube.com/shorts/dQw4w9WgXcQ", Media{ProviderYouTube, "dQw4w9WgXcQ"}, true},
+		{"https://www.youtube.com/watch?v=short", Media{}, false},
+		{"https://www.youtube.com/@muse", Media{}, false},
+		{"https://vimeo.com/76979871", Media{ProviderVimeo, "76979871"}, true},
+		{"https://player.vimeo.com/video/76979871", Media{ProviderVimeo, "76979871"}, true},
+		{"https://vimeo.com/channels/staffpicks", Media{}, false},
+		{"https://www.dailymotion.com/video/x7tgad0_starlight", Media{ProviderDailymotion, "x7tgad0"}, true},
+		{"https://dai.ly/x7tgad0", Media{ProviderDailymotion, "x7tgad0"}, true},
+		{"https://open.spotify.com/track/3skn2lauGk7Dx6bVIt5DVj", Media{ProviderSpotify, "3skn2lauGk7Dx6bVIt5DVj"}, true},
+		{"https://open.spotify.com/intl-de/track/3skn2lauGk7Dx6bVIt5DVj", Media{ProviderSpotify, "3skn2lauGk7Dx6bVIt5DVj"}, true},
+		{"https://open.spotify.com/album/0eFHYz8NmK75zSplL5qlfM", Media{}, false},
+		{"https://soundcloud.com/muse/starlight", Media{ProviderSoundCloud, "muse/starlight"}, true},
+		{"https://soundcloud.com/muse/sets/absolution", Media{}, false},
+		{"https://example.com/watch?v=dQw4w9WgXcQ", Media{}, false},
+		{"ftp://youtu.be/dQw4w9WgXcQ", Media{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Identify(tt.link)
+		assert.Equal(t, tt.ok, ok, tt.link)
+		assert.Equal(t, tt.want, got, tt.link)
+	}
+}
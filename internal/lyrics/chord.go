@@ -0,0 +1,142 @@
+package lyrics
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidChord is returned for a chord name that cannot be parsed
+var ErrInvalidChord = errors.New("invalid chord")
+
+// Chord is a chord name split into its parts, e.g. "F#m7/C#" has root
+// "F#", quality "m7" and bass "C#"
+type Chord struct {
+	Root    string
+	Quality string
+	Bass    string
+}
+
+// noteNames spell the twelve pitch classes from C with sharps and flats
+var (
+	sharpNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatNames  = [12]string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+)
+
+// naturals are the pitch classes of the note letters
+var naturals = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// qualityPattern matches the chord qualities of common chord notations,
+// such as "maj7", "m7b5", "sus4", "add9", "7(#9)" or "°7"
+var qualityPattern = regexp.MustCompile(`^(?:maj|min|dim|aug|sus|add|alt|omit|no|m|M|o|[0-9]|[#b+\-°øΔ^()/,])*$`)
+
+// ParseChord parses a chord name such as "C", "Bbmaj7", "F#m7b5" or "D/F#".
+// "♯" and "♭" are accepted for "#" and "b".
+func ParseChord(name string) (Chord, error) {
+	s := strings.NewReplacer("♯", "#", "♭", "b").Replace(strings.TrimSpace(name))
+	root, rest := splitNote(s)
+	if root == "" {
+		return Chord{}, errors.Wrapf(ErrInvalidChord, "%q has no root note", name)
+	}
+	chord := Chord{Root: root, Quality: rest}
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		if bass, tail := splitNote(rest[i+1:]); bass != "" && tail == "" {
+			chord.Quality, chord.Bass = rest[:i], bass
+		}
+	}
+	if !qualityPattern.MatchString(chord.Quality) {
+		return Chord{}, errors.Wrapf(ErrInvalidChord, "%q has an unknown quality %q", name, chord.Quality)
+	}
+	return chord, nil
+}
+
+// splitNote splits a leading note name such as "C", "F#" or "Bb" off s
+func splitNote(s string) (note, rest string) {
+	if s == "" {
+		return "", s
+	}
+	if _, ok := naturals[s[0]]; !ok {
+		return "", s
+	}
+	n := 1
+	if len(s) > 1 && (s[1] == '#' || s[1] == 'b') {
+		n = 2
+	}
+	return s[:n], s[n:]
+}
+
+// String formats the chord name
+func (c Chord) String() string {
+	if c.Bass == "" {
+		return c.Root + c.Quality
+	}
+	return c.Root + c.Quality + "/" + c.Bass
+}
+
+// Minor reports whether the chord is a minor chord
+func (c Chord) Minor() bool {
+	return strings.HasPrefix(c.Quality, "m") && !strings.HasPrefix(c.Quality, "maj")
+}
+
+// Transpose moves the chord by semitones, spelling the notes that need an
+// accidental with flats or sharps
+func (c Chord) Transpose(semitones int, flats bool) Chord {
+	c.Root = transposeNote(c.Root, semitones, flats)
+	if c.Bass != "" {
+		c.Bass = transposeNote(c.Bass, semitones, flats)
+	}
+	return c
+}
+
+// pitchClass returns the pitch class of a note name, 0 for C
+func pitchClass(note string) int {
+	pc := naturals[note[0]]
+	if len(note) > 1 {
+		switch note[1] {
+		case '#':
+			pc++
+		case 'b':
+			pc--
+		}
+	}
+	return (pc + 12) % 12
+}
+
+func transposeNote(note string, semitones int, flats bool) string {
+	pc := ((pitchClass(note)+semitones)%12 + 12) % 12
+	if flats {
+		return flatNames[pc]
+	}
+	return sharpNames[pc]
+}
+
+// keyUsesFlats reports whether the key with tonic pitch class pc is written
+// with flats. neutral is true for C major and A minor, which need neither;
+// the keys with six accidentals follow preferFlats.
+func keyUsesFlats(pc int, minor, preferFlats bool) (flats, neutral bool) {
+	if minor {
+		// relative major
+		pc = (pc + 3) % 12
+	}
+	switch pc {
+	case 0:
+		return false, true
+	case 5, 10, 3, 8, 1:
+		return true, false
+	case 6:
+		return preferFlats, false
+	default:
+		return false, false
+	}
+}
+
+// isAnnotation reports whether a bracketed name is not a chord: ChordPro
+// annotations start with "*", and "N.C." marks a passage without chords
+func isAnnotation(name string) bool {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "N.C.", "N.C", "NC", "X", "-", "":
+		return true
+	}
+	return strings.HasPrefix(name, "*")
+}
@@ -0,0 +1,607 @@
+package lyrics
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidChordPro is returned when a ChordPro chord sheet cannot be parsed
+var ErrInvalidChordPro = errors.New("invalid ChordPro chord sheet")
+
+// Section types of a chord sheet
+const (
+	SectionVerse  = "verse"
+	SectionChorus = "chorus"
+	SectionBridge = "bridge"
+	SectionTab    = "tab"
+	SectionGrid   = "grid"
+)
+
+// ChordSheet holds a parsed ChordPro chord sheet
+type ChordSheet struct {
+	// Meta holds the metadata directives such as title and artist
+	Meta map[string]string `json:"meta,omitempty"`
+	// Key is the key the chords are written in
+	Key string `json:"key,omitempty"`
+	// Capo is the fret of the capo the chords are meant to be played with
+	Capo     int            `json:"capo"`
+	Sections []ChordSection `json:"sections"`
+}
+
+// ChordSection is a block of lines: a verse, chorus, bridge, tab or grid
+// marked by start and end directives, or a block of lines between blank
+// lines, which has no type
+type ChordSection struct {
+	Type  string      `json:"type,omitempty"`
+	Label string      `json:"label,omitempty"`
+	Lines []ChordLine `json:"lines"`
+}
+
+// ChordLine is a line of lyrics with the chords played on it. Lines of tab
+// and grid sections are kept verbatim.
+type ChordLine struct {
+	Text   string          `json:"text"`
+	Chords []ChordPosition `json:"chords,omitempty"`
+	// Comment marks a comment directive, e.g. {comment: Slowly}
+	Comment bool `json:"comment,omitempty"`
+}
+
+// ChordPosition is a chord and where in its line it is played
+type ChordPosition struct {
+	Chord string `json:"chord"`
+	// Position is the offset in characters into the line's text
+	Position int `json:"position"`
+}
+
+// directiveAliases maps the short forms of directives to their long ones
+var directiveAliases = map[string]string{
+	"t": "title", "st": "subtitle", "c": "comment", "ci": "comment_italic", "cb": "comment_box",
+	"soc": "start_of_chorus", "eoc": "end_of_chorus", "sov": "start_of_verse", "eov": "end_of_verse",
+	"sob": "start_of_bridge", "eob": "end_of_bridge", "sot": "start_of_tab", "eot": "end_of_tab",
+	"sog": "start_of_grid", "eog": "end_of_grid",
+}
+
+// metaDirectives are the metadata directives, in the order they are written
+var metaDirectives = []string{"title", "subtitle", "artist", "composer", "lyricist", "album", "year", "copyright", "key", "capo", "tempo", "time", "duration"}
+
+// ParseChordPro parses a chord sheet in ChordPro format: lyrics with chords
+// in brackets before the syllable they are played on, e.g. "[G]Far a[D]way",
+// and directives in braces such as {title: Starlight} or {start_of_chorus}.
+// Lines starting with "#" are comments. Formatting directives that do not
+// change the content are ignored.
+func ParseChordPro(src string) (*ChordSheet, error) {
+	sheet := &ChordSheet{Meta: map[string]string{}}
+	var current *ChordSection
+	var openedAt int
+	flush := func() {
+		if current != nil && len(current.Lines) > 0 {
+			sheet.Sections = append(sheet.Sections, *current)
+		}
+		current = nil
+	}
+	add := func(line ChordLine) {
+		if current == nil {
+			current = &ChordSection{}
+		}
+		current.Lines = append(current.Lines, line)
+	}
+
+	for i, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		lineNo := i + 1
+		raw = strings.TrimRight(raw, " \t")
+		trimmed := strings.TrimSpace(raw)
+		verbatim := current != nil && (current.Type == SectionTab || current.Type == SectionGrid)
+		explicit := current != nil && current.Type != ""
+
+		if strings.HasPrefix(trimmed, "#") && !verbatim {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "{") {
+			switch {
+			case verbatim:
+				add(ChordLine{Text: raw})
+			case trimmed == "":
+				if !explicit {
+					flush()
+				}
+			default:
+				line, err := parseChordLine(raw)
+				if err != nil {
+					return nil, errors.Wrapf(ErrInvalidChordPro, "line %d: %v", lineNo, err)
+				}
+				add(line)
+			}
+			continue
+		}
+
+		if !strings.HasSuffix(trimmed, "}") {
+			return nil, errors.Wrapf(ErrInvalidChordPro, "line %d: unterminated directive", lineNo)
+		}
+		name, value := splitDirective(trimmed[1 : len(trimmed)-1])
+		if verbatim && name != "end_of_"+current.Type {
+			add(ChordLine{Text: raw})
+			continue
+		}
+		switch {
+		case strings.HasPrefix(name, "start_of_"):
+			kind := strings.TrimPrefix(name, "start_of_")
+			if !isSectionType(kind) {
+				return nil, errors.Wrapf(ErrInvalidChordPro, "line %d: unknown section %q", lineNo, kind)
+			}
+			if explicit {
+				return nil, errors.Wrapf(ErrInvalidChordPro, "line %d: %s starts inside the %s of line %d", lineNo, kind, current.Type, openedAt)
+			}
+			flush()
+			current, openedAt = &ChordSection{Type: kind, Label: value, Lines: []ChordLine{}}, lineNo
+		case strings.HasPrefix(name, "end_of_"):
+			kind := strings.TrimPrefix(name, "end_of_")
+			if !explicit || current.Type != kind {
+				return nil, errors.Wrapf(ErrInvalidChordPro, "line %d: end of a %s that was not started", lineNo, kind)
+			}
+			flush()
+		case name == "comment", name == "comment_italic", name == "comment_box", name == "highlight":
+			add(ChordLine{Text: value, Comment: true})
+		case name == "chorus":
+			// A reference to the chorus, to be repeated here
+			if value == "" {
+				value = "Chorus"
+			}
+			add(ChordLine{Text: value, Comment: true})
+		case name == "key":
+			if value != "" {
+				if _, err := ParseChord(value); err != nil {
+					return nil, errors.Wrapf(ErrInvalidChordPro, "line %d: invalid key %q", lineNo, value)
+				}
+			}
+			sheet.Key = value
+		case name == "capo":
+			capo, err := strconv.Atoi(value)
+			if err != nil || capo < 0 || capo > 11 {
+				return nil, errors.Wrapf(ErrInvalidChordPro, "line %d: capo must be a fret from 0 to 11", lineNo)
+			}
+			sheet.Capo = capo
+		case name == "meta":
+			key, val := splitDirective(value)
+			sheet.Meta[key] = val
+		case isMetaDirective(name):
+			sheet.Meta[name] = value
+		}
+	}
+	if current != nil && current.Type != "" {
+		return nil, errors.Wrapf(ErrInvalidChordPro, "line %d: %s is not ended", openedAt, current.Type)
+	}
+	flush()
+
+	if len(sheet.Sections) == 0 {
+		return nil, errors.Wrap(ErrInvalidChordPro, "no lyrics or chords")
+	}
+	if len(sheet.Meta) == 0 {
+		sheet.Meta = nil
+	}
+	return sheet, nil
+}
+
+// splitDirective splits "name: value" or "name value" and resolves the
+// short form of name
+func splitDirective(s string) (name, value string) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexAny(s, ": \t")
+	if end < 0 {
+		end = len(s)
+	}
+	name = strings.ToLower(s[:end])
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s[end:]), ":"))
+	if long, ok := directiveAliases[name]; ok {
+		name = long
+	}
+	return name, value
+}
+
+func isSectionType(kind string) bool {
+	switch kind {
+	case SectionVerse, SectionChorus, SectionBridge, SectionTab, SectionGrid:
+		return true
+	}
+	return false
+}
+
+func isMetaDirective(name string) bool {
+	for _, meta := range metaDirectives {
+		if name == meta {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChordLine splits the bracketed chords out of a line of lyrics
+func parseChordLine(raw string) (ChordLine, error) {
+	line := ChordLine{}
+	var text []rune
+	src := []rune(raw)
+	for i := 0; i < len(src); i++ {
+		if src[i] != '[' {
+			text = append(text, src[i])
+			continue
+		}
+		end := i + 1
+		for end < len(src) && src[end] != ']' {
+			end++
+		}
+		if end == len(src) {
+			return line, errors.Errorf("unterminated chord at column %d", i+1)
+		}
+		name := strings.TrimSpace(string(src[i+1 : end]))
+		if !isAnnotation(name) {
+			if _, err := ParseChord(name); err != nil {
+				return line, err
+			}
+		}
+		line.Chords = append(line.Chords, ChordPosition{Chord: name, Position: len(text)})
+		i = end
+	}
+	line.Text = strings.TrimRight(string(text), " \t")
+	return line, nil
+}
+
+// Transpose returns a copy of the sheet with every chord and the key moved
+// by semitones. Notes are spelled with sharps or flats to suit the new key,
+// which is the key directive or else the first chord.
+func (s *ChordSheet) Transpose(semitones int) *ChordSheet {
+	t := s.clone()
+	semitones = (semitones%12 + 12) % 12
+	if semitones == 0 {
+		return t
+	}
+	flats := s.spelling(semitones)
+	move := func(name string) string {
+		if isAnnotation(name) {
+			return name
+		}
+		chord, err := ParseChord(name)
+		if err != nil {
+			return name
+		}
+		return chord.Transpose(semitones, flats(chord)).String()
+	}
+	for i := range t.Sections {
+		for j := range t.Sections[i].Lines {
+			for k := range t.Sections[i].Lines[j].Chords {
+				t.Sections[i].Lines[j].Chords[k].Chord = move(t.Sections[i].Lines[j].Chords[k].Chord)
+			}
+		}
+	}
+	if t.Key != "" {
+		t.Key = move(t.Key)
+	}
+	return t
+}
+
+// WithCapo returns a copy of the sheet rewritten for a capo on another
+// fret, with the chords changed so that the song sounds the same
+func (s *ChordSheet) WithCapo(capo int) *ChordSheet {
+	t := s.Transpose(s.Capo - capo)
+	t.Capo = capo
+	return t
+}
+
+// spelling decides whether a chord transposed by semitones is spelled with
+// flats, from the key it ends up in
+func (s *ChordSheet) spelling(semitones int) func(Chord) bool {
+	key, ok := s.key()
+	if !ok {
+		return func(Chord) bool { return false }
+	}
+	pc := (pitchClass(key.Root) + semitones) % 12
+	flats, neutral := keyUsesFlats(pc, key.Minor(), strings.HasSuffix(key.Root, "b"))
+	if !neutral {
+		return func(Chord) bool { return flats }
+	}
+	// Keys without accidentals borrow flat chords such as Bb more often
+	// than sharp ones, unless the chord was written with a sharp
+	return func(c Chord) bool { return !strings.HasSuffix(c.Root, "#") }
+}
+
+// key returns the key directive, or the first chord when there is none
+func (s *ChordSheet) key() (Chord, bool) {
+	if s.Key != "" {
+		key, err := ParseChord(s.Key)
+		return key, err == nil
+	}
+	for _, section := range s.Sections {
+		for _, line := range section.Lines {
+			for _, pos := range line.Chords {
+				if chord, err := ParseChord(pos.Chord); err == nil && !isAnnotation(pos.Chord) {
+					return chord, true
+				}
+			}
+		}
+	}
+	return Chord{}, false
+}
+
+func (s *ChordSheet) clone() *ChordSheet {
+	t := *s
+	if s.Meta != nil {
+		t.Meta = make(map[string]string, len(s.Meta))
+		for k, v := range s.Meta {
+			t.Meta[k] = v
+		}
+	}
+	t.Sections = make([]ChordSection, len(s.Sections))
+	for i, section := range s.Sections {
+		t.Sections[i] = section
+		t.Sections[i].Lines = make([]ChordLine, len(section.Lines))
+		for j, line := range section.Lines {
+			t.Sections[i].Lines[j] = line
+			t.Sections[i].Lines[j].Chords = append([]ChordPosition(nil), line.Chords...)
+		}
+	}
+	return &t
+}
+
+// PlainText returns the lyrics without chords, comments or tabs, with
+// sections separated by blank lines
+func (s *ChordSheet) PlainText() string {
+	var verses []string
+	for _, section := range s.Sections {
+		if section.Type == SectionTab || section.Type == SectionGrid {
+			continue
+		}
+		var lines []string
+		for _, line := range section.Lines {
+			if !line.Comment && line.Text != "" {
+				lines = append(lines, line.Text)
+			}
+		}
+		if len(lines) > 0 {
+			verses = append(verses, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(verses, "\n\n")
+}
+
+// Chart returns the sheet as plain text with the chords written above the
+// lyrics. Lyrics are padded where chords would run into each other.
+func (s *ChordSheet) Chart() string {
+	var b strings.Builder
+	if title := s.Meta["title"]; title != "" {
+		b.WriteString(title + "\n")
+	}
+	if artist := s.Meta["artist"]; artist != "" {
+		b.WriteString(artist + "\n")
+	}
+	if s.Key != "" {
+		b.WriteString("Key: " + s.Key + "\n")
+	}
+	if s.Capo > 0 {
+		fmt.Fprintf(&b, "Capo: %d\n", s.Capo)
+	}
+	for i, section := range s.Sections {
+		if b.Len() > 0 || i > 0 {
+			b.WriteString("\n")
+		}
+		if label := sectionLabel(section); label != "" {
+			b.WriteString(label + ":\n")
+		}
+		for _, line := range section.Lines {
+			switch {
+			case line.Comment:
+				b.WriteString("(" + line.Text + ")\n")
+			case len(line.Chords) == 0:
+				b.WriteString(line.Text + "\n")
+			default:
+				chords, lyrics := chartLine(line)
+				b.WriteString(chords + "\n")
+				if strings.TrimSpace(lyrics) != "" {
+					b.WriteString(lyrics + "\n")
+				}
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// chartLine lays out a line as a row of chords above a row of lyrics
+func chartLine(line ChordLine) (chords, lyrics string) {
+	text := []rune(line.Text)
+	var top, bottom []rune
+	next := 0
+	for _, pos := range line.Chords {
+		at := min(pos.Position, len(text))
+		bottom = append(bottom, text[next:at]...)
+		next = at
+		// Keep a space after the previous chord
+		if len(top) > 0 {
+			for len(bottom) <= len(top) {
+				bottom = append(bottom, ' ')
+			}
+		}
+		for len(top) < len(bottom) {
+			top = append(top, ' ')
+		}
+		top = append(top, []rune(pos.Chord)...)
+	}
+	bottom = append(bottom, text[next:]...)
+	return string(top), strings.TrimRight(string(bottom), " ")
+}
+
+// sectionLabel returns the label shown above a section
+func sectionLabel(section ChordSection) string {
+	if section.Label != "" || section.Type == "" || section.Type == SectionVerse {
+		return section.Label
+	}
+	return strings.ToUpper(section.Type[:1]) + section.Type[1:]
+}
+
+// HTML renders the sheet as an HTML page with the chords above the lyrics
+func (s *ChordSheet) HTML() string {
+	var b strings.Builder
+	title := s.Meta["title"]
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	b.WriteString("<style>\n" +
+		".line { margin: 0; white-space: pre-wrap; }\n" +
+		".segment { display: inline-block; vertical-align: bottom; }\n" +
+		".chord { display: block; font-weight: bold; min-height: 1.2em; padding-right: 0.3em; }\n" +
+		".chorus { border-left: 2px solid #999; padding-left: 0.8em; }\n" +
+		".comment { font-style: italic; color: #666; }\n" +
+		"</style>\n</head>\n<body>\n")
+	if title != "" {
+		b.WriteString("<h1>" + html.EscapeString(title) + "</h1>\n")
+	}
+	for _, name := range []string{"subtitle", "artist"} {
+		if value := s.Meta[name]; value != "" {
+			fmt.Fprintf(&b, "<h2 class=\"%s\">%s</h2>\n", name, html.EscapeString(value))
+		}
+	}
+	if s.Key != "" || s.Capo > 0 {
+		var parts []string
+		if s.Key != "" {
+			parts = append(parts, "Key: "+html.EscapeString(s.Key))
+		}
+		if s.Capo > 0 {
+			parts = append(parts, fmt.Sprintf("Capo: %d", s.Capo))
+		}
+		b.WriteString("<p class=\"info\">" + strings.Join(parts, " · ") + "</p>\n")
+	}
+	for _, section := range s.Sections {
+		class := "section"
+		if section.Type != "" {
+			class += " " + section.Type
+		}
+		b.WriteString("<div class=\"" + class + "\">\n")
+		if label := sectionLabel(section); label != "" {
+			b.WriteString("<p class=\"label\">" + html.EscapeString(label) + "</p>\n")
+		}
+		if section.Type == SectionTab || section.Type == SectionGrid {
+			var lines []string
+			for _, line := range section.Lines {
+				lines = append(lines, html.EscapeString(line.Text))
+			}
+			b.WriteString("<pre>" + strings.Join(lines, "\n") + "</pre>\n</div>\n")
+			continue
+		}
+		for _, line := range section.Lines {
+			if line.Comment {
+				b.WriteString("<p class=\"comment\">" + html.EscapeString(line.Text) + "</p>\n")
+				continue
+			}
+			b.WriteString("<p class=\"line\">")
+			for _, seg := range segments(line) {
+				b.WriteString("<span class=\"segment\"><span class=\"chord\">" + html.EscapeString(seg.chord) +
+					"</span><span class=\"lyrics\">" + html.EscapeString(seg.text) + "</span></span>")
+			}
+			b.WriteString("</p>\n")
+		}
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// segment is a chord and the lyrics up to the next chord
+type segment struct {
+	chord string
+	text  string
+}
+
+func segments(line ChordLine) []segment {
+	text := []rune(line.Text)
+	var segs []segment
+	if len(line.Chords) == 0 || line.Chords[0].Position > 0 {
+		end := len(text)
+		if len(line.Chords) > 0 {
+			end = min(line.Chords[0].Position, len(text))
+		}
+		segs = append(segs, segment{text: string(text[:end])})
+	}
+	for i, pos := range line.Chords {
+		start, end := min(pos.Position, len(text)), len(text)
+		if i+1 < len(line.Chords) {
+			end = min(line.Chords[i+1].Position, len(text))
+		}
+		segs = append(segs, segment{chord: pos.Chord, text: string(text[start:end])})
+	}
+	return segs
+}
+
+// String formats the sheet in ChordPro format
+func (s *ChordSheet) String() string {
+	var b strings.Builder
+	written := map[string]bool{}
+	for _, name := range metaDirectives {
+		switch name {
+		case "key":
+			if s.Key != "" {
+				b.WriteString("{key: " + s.Key + "}\n")
+			}
+		case "capo":
+			if s.Capo > 0 {
+				fmt.Fprintf(&b, "{capo: %d}\n", s.Capo)
+			}
+		default:
+			if value, ok := s.Meta[name]; ok {
+				b.WriteString("{" + name + ": " + value + "}\n")
+			}
+		}
+		written[name] = true
+	}
+	var others []string
+	for name := range s.Meta {
+		if !written[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		b.WriteString("{meta: " + name + " " + s.Meta[name] + "}\n")
+	}
+
+	for i, section := range s.Sections {
+		if b.Len() > 0 || i > 0 {
+			b.WriteString("\n")
+		}
+		if section.Type != "" {
+			b.WriteString("{start_of_" + section.Type)
+			if section.Label != "" {
+				b.WriteString(": " + section.Label)
+			}
+			b.WriteString("}\n")
+		}
+		for _, line := range section.Lines {
+			switch {
+			case line.Comment:
+				b.WriteString("{comment: " + line.Text + "}\n")
+			case section.Type == SectionTab || section.Type == SectionGrid:
+				b.WriteString(line.Text + "\n")
+			default:
+				b.WriteString(chordProLine(line) + "\n")
+			}
+		}
+		if section.Type != "" {
+			b.WriteString("{end_of_" + section.Type + "}\n")
+		}
+	}
+	return b.String()
+}
+
+// chordProLine puts the chords of line back into its text in brackets
+func chordProLine(line ChordLine) string {
+	text := []rune(line.Text)
+	var b strings.Builder
+	next := 0
+	for _, pos := range line.Chords {
+		at := min(pos.Position, len(text))
+		b.WriteString(string(text[next:at]))
+		next = at
+		b.WriteString("[" + pos.Chord + "]")
+	}
+	b.WriteString(string(text[next:]))
+	return b.String()
+}
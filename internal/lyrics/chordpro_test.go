@@ -0,0 +1,113 @@
+package lyrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const starlight = `{title: Starlight}
+{artist: Muse}
+{key: Bm}
+# Intro is played twice
+[Bm]Far a[G]way, this [D]ship is [A]taking me
+
+{start_of_chorus}
+[Bm]My life, you [F#]electrify my [G]life
+{end_of_chorus}
+{c: Repeat}`
+
+func TestParseChordPro(t *testing.T) {
+	sheet, err := ParseChordPro(starlight)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"title": "Starlight", "artist": "Muse"}, sheet.Meta)
+	assert.Equal(t, "Bm", sheet.Key)
+	require.Len(t, sheet.Sections, 3, "Blank lines and section directives should separate sections")
+	assert.Equal(t, ChordLine{Text: "Far away, this ship is taking me", Chords: []ChordPosition{
+		{Chord: "Bm", Position: 0}, {Chord: "G", Position: 5}, {Chord: "D", Position: 15}, {Chord: "A", Position: 23},
+	}}, sheet.Sections[0].Lines[0])
+	assert.Equal(t, SectionChorus, sheet.Sections[1].Type)
+	assert.True(t, sheet.Sections[2].Lines[0].Comment)
+	assert.Equal(t, "Far away, this ship is taking me\n\nMy life, you electrify my life", sheet.PlainText())
+
+	again, err := ParseChordPro(sheet.String())
+	require.Nil(t, err)
+	assert.Equal(t, sheet, again, "Formatting should round-trip")
+}
+
+func TestParseChordPro_Invalid(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"{title: Only metadata}",
+		"[G]Far a[Dway",
+		"[H]Far away",
+		"[Gxyz]Far away",
+		"{start_of_chorus}\nMy life",
+		"{end_of_verse}",
+		"{start_of_chorus}\n{start_of_verse}\n{end_of_verse}\n{end_of_chorus}",
+		"{capo: 13}\n[G]Far",
+		"{title: Starlight",
+	} {
+		_, err := ParseChordPro(src)
+		assert.ErrorIs(t, err, ErrInvalidChordPro, "Parsing %q should fail", src)
+	}
+}
+
+func TestParseChord(t *testing.T) {
+	for name, want := range map[string]Chord{
+		"C":        {Root: "C"},
+		"Bbmaj7":   {Root: "Bb", Quality: "maj7"},
+		"F#m7b5":   {Root: "F#", Quality: "m7b5"},
+		"D/F#":     {Root: "D", Bass: "F#"},
+		"C6/9":     {Root: "C", Quality: "6/9"},
+		"E♭sus4":   {Root: "Eb", Quality: "sus4"},
+		"G7(#9)/B": {Root: "G", Quality: "7(#9)", Bass: "B"},
+	} {
+		chord, err := ParseChord(name)
+		assert.Nil(t, err, name)
+		assert.Equal(t, want, chord, name)
+	}
+}
+
+func TestChordSheet_Transpose(t *testing.T) {
+	chords := func(sheet *ChordSheet) []string {
+		var names []string
+		for _, section := range sheet.Sections {
+			for _, line := range section.Lines {
+				for _, pos := range line.Chords {
+					names = append(names, pos.Chord)
+				}
+			}
+		}
+		return names
+	}
+	sheet, err := ParseChordPro("{key: G}\n[G]One [D/F#]two [Em7]three [C]four [*Riff] [N.C.]")
+	require.Nil(t, err)
+
+	up := sheet.Transpose(2)
+	assert.Equal(t, "A", up.Key)
+	assert.Equal(t, []string{"A", "E/G#", "F#m7", "D", "*Riff", "N.C."}, chords(up), "Sharp keys should be spelled with sharps")
+	down := sheet.Transpose(-2)
+	assert.Equal(t, "F", down.Key)
+	assert.Equal(t, []string{"F", "C/E", "Dm7", "Bb", "*Riff", "N.C."}, chords(down), "Flat keys should be spelled with flats")
+	assert.Equal(t, []string{"G", "D/F#", "Em7", "C", "*Riff", "N.C."}, chords(sheet), "Transposing should not change the original")
+	assert.Equal(t, chords(sheet), chords(sheet.Transpose(12)))
+
+	capo, err := ParseChordPro("{capo: 3}\n[Em]Far [C]away [G]now")
+	require.Nil(t, err)
+	open := capo.WithCapo(0)
+	assert.Equal(t, 0, open.Capo)
+	assert.Equal(t, []string{"Gm", "Eb", "Bb"}, chords(open), "Removing the capo should keep the sounding chords")
+	assert.Equal(t, []string{"Dm", "Bb", "F"}, chords(capo.WithCapo(5)))
+}
+
+func TestChordSheet_Render(t *testing.T) {
+	sheet, err := ParseChordPro("{title: Song}\n[G]Far a[D]way\n[Am]I[C]t's <me>")
+	require.Nil(t, err)
+	assert.Equal(t, "Song\n\nG    D\nFar away\nAm C\nI  t's <me>", sheet.Chart(), "Lyrics should be padded where chords collide")
+
+	page := sheet.HTML()
+	assert.Contains(t, page, `<span class="segment"><span class="chord">D</span><span class="lyrics">way</span></span>`)
+	assert.Contains(t, page, "&lt;me&gt;", "Lyrics should be escaped")
+}
@@ -0,0 +1,177 @@
+package lyrics
+
+import "strings"
+
+// Diff operations
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+)
+
+// DiffLine is a single line of a line-level diff. OldLine and NewLine are
+// 1-based line numbers in the old and new text, zero when not applicable.
+type DiffLine struct {
+	Op      string `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+// DiffLines computes a line-level diff between two texts. It finds a
+// shortest edit script with Myers' algorithm, which bisects the texts
+// recursively and so takes memory linear in their length. Within a changed
+// block, deleted lines come before inserted ones.
+func DiffLines(oldText, newText string) []DiffLine {
+	d := &differ{a: splitLines(oldText), b: splitLines(newText)}
+	d.diff(0, len(d.a), 0, len(d.b))
+	d.flush()
+	return d.out
+}
+
+// differ builds the diff of a and b in order. Deletions and insertions are
+// held back until the end of their changed block.
+type differ struct {
+	a, b     []string
+	out      []DiffLine
+	deleted  []DiffLine
+	inserted []DiffLine
+}
+
+// diff appends the diff of a[aLo:aHi] and b[bLo:bHi]
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.equal(aLo, bLo)
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	switch x, y, ok := d.bisect(aLo, aHi, bLo, bHi); {
+	case ok:
+		d.diff(aLo, x, bLo, y)
+		d.diff(x, aHi, y, bHi)
+	default:
+		for i := aLo; i < aHi; i++ {
+			d.deleted = append(d.deleted, DiffLine{Op: OpDelete, OldLine: i + 1, Text: d.a[i]})
+		}
+		for j := bLo; j < bHi; j++ {
+			d.inserted = append(d.inserted, DiffLine{Op: OpInsert, NewLine: j + 1, Text: d.b[j]})
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.equal(aHi+i, bHi+i)
+	}
+}
+
+// bisect returns a point (x, y) in the middle of a shortest edit script of
+// a[aLo:aHi] into b[bLo:bHi], which must differ in their first and last
+// lines. It runs the script forward from the start and backward from the
+// end at once until the two meet. ok is false when the ranges have no line
+// in common, and so are replaced as a whole.
+func (d *differ) bisect(aLo, aHi, bLo, bHi int) (x, y int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[offset+k] is how far along a the furthest forward path on
+	// diagonal k = x - y has come, backward[offset+k] the same for the
+	// backward path from the end; -1 when not reached yet
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	// With an odd delta the forward paths meet the backward ones that are
+	// one step shorter, otherwise the backward paths meet the forward ones
+	front := delta%2 != 0
+	// Paths that left the edit graph are not extended further
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for steps := 0; steps < maxD; steps++ {
+		for k := -steps + fStart; k <= steps-fEnd; k += 2 {
+			i := offset + k
+			var fx int
+			if k == -steps || (k != steps && forward[i-1] < forward[i+1]) {
+				fx = forward[i+1]
+			} else {
+				fx = forward[i-1] + 1
+			}
+			fy := fx - k
+			for fx < n && fy < m && d.a[aLo+fx] == d.b[bLo+fy] {
+				fx++
+				fy++
+			}
+			forward[i] = fx
+			switch {
+			case fx > n:
+				fEnd += 2
+			case fy > m:
+				fStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < len(backward) && backward[j] != -1 && fx >= n-backward[j] {
+					return aLo + fx, bLo + fy, true
+				}
+			}
+		}
+		for k := -steps + bStart; k <= steps-bEnd; k += 2 {
+			i := offset + k
+			var bx int
+			if k == -steps || (k != steps && backward[i-1] < backward[i+1]) {
+				bx = backward[i+1]
+			} else {
+				bx = backward[i-1] + 1
+			}
+			by := bx - k
+			for bx < n && by < m && d.a[aHi-bx-1] == d.b[bHi-by-1] {
+				bx++
+				by++
+			}
+			backward[i] = bx
+			switch {
+			case bx > n:
+				bEnd += 2
+			case by > m:
+				bStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < len(forward) && forward[j] != -1 {
+					fx := forward[j]
+					if fx >= n-bx {
+						return aLo + fx, bLo + fx - (j - offset), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// equal appends a line both texts have, after the changed block before it
+func (d *differ) equal(i, j int) {
+	d.flush()
+	d.out = append(d.out, DiffLine{Op: OpEqual, OldLine: i + 1, NewLine: j + 1, Text: d.a[i]})
+}
+
+// flush appends the changed block held back so far
+func (d *differ) flush() {
+	d.out = append(d.out, d.deleted...)
+	d.out = append(d.out, d.inserted...)
+	d.deleted, d.inserted = d.deleted[:0], d.inserted[:0]
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
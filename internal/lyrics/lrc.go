@@ -0,0 +1,176 @@
+package lyrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidLRC is returned when LRC content cannot be parsed
+var ErrInvalidLRC = errors.New("invalid LRC lyrics")
+
+// Line is a single time-coded lyric line
+type Line struct {
+	Time      time.Duration `json:"-"`
+	TimeMS    int64         `json:"time_ms"`
+	Timestamp string        `json:"timestamp"`
+	Text      string        `json:"text"`
+}
+
+// LRC holds parsed synchronized lyrics
+type LRC struct {
+	Tags  map[string]string `json:"tags,omitempty"`
+	Lines []Line            `json:"lines"`
+}
+
+// ParseLRC parses LRC formatted lyrics, e.g. "[01:02.50]Some words".
+// ID tags such as [ar:...] and [ti:...] are collected into Tags and an
+// [offset:...] tag (in milliseconds) is applied to every timestamp.
+// Lines are returned sorted by time.
+func ParseLRC(src string) (*LRC, error) {
+	lrc := &LRC{Tags: map[string]string{}}
+	var offset time.Duration
+
+	for i, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		lineNo := i + 1
+		rest := strings.TrimSpace(raw)
+		if rest == "" {
+			continue
+		}
+		if !strings.HasPrefix(rest, "[") {
+			return nil, errors.Wrapf(ErrInvalidLRC, "line %d: missing timestamp", lineNo)
+		}
+
+		var stamps []time.Duration
+		for strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, errors.Wrapf(ErrInvalidLRC, "line %d: unterminated tag", lineNo)
+			}
+			tag := rest[1:end]
+			rest = rest[end+1:]
+
+			if d, ok := parseTimestamp(tag); ok {
+				stamps = append(stamps, d)
+				continue
+			}
+			key, value, found := strings.Cut(tag, ":")
+			if !found || len(stamps) > 0 || !isTagKey(key) {
+				return nil, errors.Wrapf(ErrInvalidLRC, "line %d: invalid tag %q", lineNo, tag)
+			}
+			key = strings.ToLower(strings.TrimSpace(key))
+			value = strings.TrimSpace(value)
+			if key == "offset" {
+				ms, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, errors.Wrapf(ErrInvalidLRC, "line %d: invalid offset %q", lineNo, value)
+				}
+				offset = time.Duration(ms) * time.Millisecond
+			}
+			lrc.Tags[key] = value
+		}
+
+		text := strings.TrimSpace(rest)
+		if len(stamps) == 0 {
+			if text != "" {
+				return nil, errors.Wrapf(ErrInvalidLRC, "line %d: text without timestamp", lineNo)
+			}
+			continue
+		}
+		for _, d := range stamps {
+			lrc.Lines = append(lrc.Lines, Line{Time: d, Text: text})
+		}
+	}
+
+	if len(lrc.Lines) == 0 {
+		return nil, errors.Wrap(ErrInvalidLRC, "no timed lines")
+	}
+
+	// LRC offsets are subtracted: a positive offset makes lyrics appear sooner
+	for i := range lrc.Lines {
+		d := lrc.Lines[i].Time - offset
+		if d < 0 {
+			d = 0
+		}
+		lrc.Lines[i].Time = d
+		lrc.Lines[i].TimeMS = d.Milliseconds()
+		lrc.Lines[i].Timestamp = FormatTimestamp(d)
+	}
+	sort.SliceStable(lrc.Lines, func(i, j int) bool {
+		return lrc.Lines[i].Time < lrc.Lines[j].Time
+	})
+	if len(lrc.Tags) == 0 {
+		lrc.Tags = nil
+	}
+	return lrc, nil
+}
+
+// PlainText returns the lyric lines without timestamps, one per line.
+// Empty timed lines are kept so verse breaks survive the conversion.
+func (l *LRC) PlainText() string {
+	texts := make([]string, 0, len(l.Lines))
+	for _, line := range l.Lines {
+		texts = append(texts, line.Text)
+	}
+	return strings.Trim(strings.Join(texts, "\n"), "\n")
+}
+
+// FormatTimestamp formats d as an LRC timestamp (mm:ss.xx)
+func FormatTimestamp(d time.Duration) string {
+	centis := d.Milliseconds() / 10
+	return fmt.Sprintf("%02d:%02d.%02d", centis/6000, centis/100%60, centis%100)
+}
+
+// parseTimestamp parses mm:ss, mm:ss.xx or mm:ss.xxx
+func parseTimestamp(tag string) (time.Duration, bool) {
+	minPart, secPart, found := strings.Cut(tag, ":")
+	if !found || minPart == "" || !isDigits(minPart) {
+		return 0, false
+	}
+	secPart, fracPart, hasFrac := strings.Cut(secPart, ".")
+	if len(secPart) != 2 || !isDigits(secPart) {
+		return 0, false
+	}
+	if hasFrac && (len(fracPart) == 0 || len(fracPart) > 3 || !isDigits(fracPart)) {
+		return 0, false
+	}
+
+	minutes, _ := strconv.Atoi(minPart)
+	seconds, _ := strconv.Atoi(secPart)
+	if seconds >= 60 {
+		return 0, false
+	}
+	var millis int
+	if hasFrac {
+		millis, _ = strconv.Atoi(fracPart + strings.Repeat("0", 3-len(fracPart)))
+	}
+	return time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second +
+		time.Duration(millis)*time.Millisecond, true
+}
+
+func isTagKey(key string) bool {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,129 @@
+package lyrics
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLRC(t *testing.T) {
+	src := "[ar:Muse]\n[ti:Starlight]\n[00:12.50]Far away\n[00:05.00][00:20.125]This ship is taking me"
+
+	lrc, err := ParseLRC(src)
+	assert.Nil(t, err, "Parsing valid LRC should not return an error")
+	assert.Equal(t, "Muse", lrc.Tags["ar"], "Artist tag should be parsed")
+	assert.Len(t, lrc.Lines, 3, "Lines with several timestamps should be repeated")
+	assert.Equal(t, 5*time.Second, lrc.Lines[0].Time, "Lines should be sorted by time")
+	assert.Equal(t, "00:20.12", lrc.Lines[2].Timestamp, "Timestamp should be normalized")
+	assert.Equal(t, int64(20125), lrc.Lines[2].TimeMS, "Milliseconds should be preserved")
+	assert.Equal(t, "This ship is taking me\nFar away\nThis ship is taking me", lrc.PlainText())
+}
+
+func TestParseLRC_Offset(t *testing.T) {
+	lrc, err := ParseLRC("[offset:500]\n[00:01.00]First\n[00:00.20]Intro")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), lrc.Lines[0].Time, "Offset should not produce negative times")
+	assert.Equal(t, 500*time.Millisecond, lrc.Lines[1].Time, "Offset should be subtracted")
+}
+
+func TestParseLRC_Invalid(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"no timestamps here",
+		"[00:1x.00]Bad seconds",
+		"[00:10.00 missing bracket",
+		"[ar:Muse]",
+	} {
+		_, err := ParseLRC(src)
+		assert.ErrorIs(t, err, ErrInvalidLRC, "Parsing %q should fail", src)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	diff := DiffLines("Far away\nThis ship is taking me", "Far away\nThis ship is taking me far\nAway from you")
+
+	assert.Equal(t, []DiffLine{
+		{Op: OpEqual, OldLine: 1, NewLine: 1, Text: "Far away"},
+		{Op: OpDelete, OldLine: 2, Text: "This ship is taking me"},
+		{Op: OpInsert, NewLine: 2, Text: "This ship is taking me far"},
+		{Op: OpInsert, NewLine: 3, Text: "Away from you"},
+	}, diff)
+}
+
+func TestDiffLines_Shortest(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomText := func() []string {
+		lines := make([]string, rnd.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rnd.Intn(4)))
+		}
+		return lines
+	}
+	for n := 0; n < 2000; n++ {
+		a, b := randomText(), randomText()
+		diff := DiffLines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+
+		var oldLines, newLines []string
+		equal := 0
+		for _, line := range diff {
+			switch line.Op {
+			case OpEqual:
+				equal++
+				oldLines = append(oldLines, line.Text)
+				newLines = append(newLines, line.Text)
+				require.Equal(t, len(oldLines), line.OldLine)
+				require.Equal(t, len(newLines), line.NewLine)
+			case OpDelete:
+				oldLines = append(oldLines, line.Text)
+				require.Equal(t, len(oldLines), line.OldLine)
+			case OpInsert:
+				newLines = append(newLines, line.Text)
+				require.Equal(t, len(newLines), line.NewLine)
+			}
+		}
+		require.Equal(t, strings.Join(a, "\n"), strings.Join(oldLines, "\n"), "The diff should reproduce the old text")
+		require.Equal(t, strings.Join(b, "\n"), strings.Join(newLines, "\n"), "The diff should reproduce the new text")
+		require.Equal(t, lcsLength(a, b), equal, "The diff of %q and %q should keep a longest common subsequence", a, b)
+	}
+}
+
+func TestDiffLines_LongTexts(t *testing.T) {
+	lines := make([]string, 50000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	changed := append([]string{}, lines...)
+	for i := 0; i < len(changed); i += 1000 {
+		changed[i] = "changed"
+	}
+	diff := DiffLines(strings.Join(lines, "\n"), strings.Join(changed, "\n"))
+	assert.Len(t, diff, len(lines)+50, "Each changed line should be deleted and inserted")
+}
+
+// lcsLength returns the length of a longest common subsequence of a and b
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestVerses(t *testing.T) {
+	verses := Verses("First line\nSecond line\n\n\nChorus\r\n\r\nOutro\n")
+	assert.Equal(t, []string{"First line\nSecond line", "Chorus", "Outro"}, verses, "Blank lines should separate verses")
+	assert.Empty(t, Verses("  \n"), "Blank text should have no verses")
+}
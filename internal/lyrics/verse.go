@@ -0,0 +1,24 @@
+package lyrics
+
+import "strings"
+
+// Verses splits plain-text lyrics into verses separated by blank lines
+func Verses(text string) []string {
+	var verses []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			verses = append(verses, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return verses
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheControl sets a Cache-Control header so HTTP caches keep successful
+// responses no longer than maxAge. Error responses are never cached and a
+// zero maxAge disables caching altogether.
+func CacheControl(maxAge time.Duration) gin.HandlerFunc {
+	value := "no-cache"
+	if seconds := int(maxAge.Seconds()); seconds > 0 {
+		value = fmt.Sprintf("public, max-age=%d", seconds)
+	}
+	return func(c *gin.Context) {
+		c.Writer = &cacheControlWriter{ResponseWriter: c.Writer, value: value}
+		c.Next()
+	}
+}
+
+// cacheControlWriter picks the Cache-Control header once the status is known
+type cacheControlWriter struct {
+	gin.ResponseWriter
+	value string
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest {
+		w.Header().Set("Cache-Control", "no-store")
+	} else {
+		w.Header().Set("Cache-Control", w.value)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
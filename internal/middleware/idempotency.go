@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// Idempotency headers
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks responses replayed from a stored key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// DefaultIdempotencyPurgeInterval is how often expired keys are removed
+const DefaultIdempotencyPurgeInterval = 10 * time.Minute
+
+// idempotencyLockTimeout is how long a key stays reserved by a request that
+// never stored its response, for example because the server stopped
+const idempotencyLockTimeout = time.Minute
+
+// Idempotency makes retries of non-idempotent requests safe. The first
+// request with an Idempotency-Key header runs and its response is stored
+// with the key; retries with the same key get the stored response instead
+// of running again. Keys are scoped to the X-User header and expire after
+// a TTL.
+type Idempotency struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewIdempotency creates an Idempotency that keeps keys for ttl
+func NewIdempotency(repo repository.IdempotencyRepository, ttl time.Duration) *Idempotency {
+	return &Idempotency{repo: repo, ttl: ttl, now: time.Now}
+}
+
+// Handler returns the middleware. Requests without the header pass through.
+// A key reused for a different request, or while its first request is
+// still running, is rejected with 409 Conflict. Server errors are not
+// stored, so the request can be retried with the same key.
+func (i *Idempotency) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(IdempotencyKeyHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+		if !validRequestID(header) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid idempotency key",
+				"details": "Idempotency-Key must be 1 to 128 printable ASCII characters",
+			})
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := i.now()
+		key := &model.IdempotencyKey{
+			Key:         c.GetHeader(UserHeader) + ":" + header,
+			Fingerprint: fingerprint(c.Request.Method, c.Request.URL.Path, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyLockTimeout),
+		}
+		err = i.repo.AddIdempotencyKey(key)
+		if errors.Is(err, repository.ErrIdempotencyKeyExists) {
+			i.replay(c, key)
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to store idempotency key", "details": err.Error()})
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		ctx := c.Request.Context()
+		if recorder.Status() >= http.StatusInternalServerError {
+			if err := i.repo.DeleteIdempotencyKey(key.Key); err != nil {
+				logger.ErrorContext(ctx, "Failed to release idempotency key", logger.Fields{"error": err.Error()})
+			}
+			return
+		}
+		key.Status = recorder.Status()
+		key.ContentType = recorder.Header().Get("Content-Type")
+		key.Body = recorder.body.Bytes()
+		key.ExpiresAt = i.now().Add(i.ttl)
+		if err := i.repo.SaveIdempotencyKey(key); err != nil {
+			logger.ErrorContext(ctx, "Failed to store idempotent response", logger.Fields{"error": err.Error()})
+		}
+	}
+}
+
+// replay answers a retry of key with the stored response
+func (i *Idempotency) replay(c *gin.Context, key *model.IdempotencyKey) {
+	stored, err := i.repo.GetIdempotencyKey(key.Key, i.now())
+	switch {
+	case errors.Is(err, repository.ErrIdempotencyKeyNotFound):
+		// The first request failed and released the key in the meantime
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error":   "Request in progress",
+			"details": "a request with this idempotency key has just finished, retry it",
+		})
+	case err != nil:
+		c.Error(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to read idempotency key", "details": err.Error()})
+	case stored.Fingerprint != key.Fingerprint:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error":   "Idempotency key reused",
+			"details": "the idempotency key was used for a request with a different method, path or body",
+		})
+	case !stored.Completed():
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error":   "Request in progress",
+			"details": "a request with this idempotency key is still being processed",
+		})
+	default:
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(stored.Status, stored.ContentType, stored.Body)
+		c.Abort()
+	}
+}
+
+// Run removes expired keys every interval until ctx is done
+func (i *Idempotency) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := i.repo.DeleteExpiredIdempotencyKeys(i.now())
+			if err != nil {
+				logger.Error("Failed to purge idempotency keys", logger.Fields{"error": err.Error()})
+			} else if n > 0 {
+				logger.Debug("Purged expired idempotency keys", logger.Fields{"count": n})
+			}
+		}
+	}
+}
+
+// fingerprint identifies a request by its method, path and body
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, method+" "+path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
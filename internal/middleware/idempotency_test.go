@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"song-library/internal/repository"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	idempotency := NewIdempotency(repository.NewMemoryIdempotencyRepository(), time.Hour)
+	now := time.Now()
+	idempotency.now = func() time.Time { return now }
+
+	created, status := 0, http.StatusCreated
+	r := gin.New()
+	r.POST("/songs", idempotency.Handler(), func(c *gin.Context) {
+		created++
+		c.JSON(status, gin.H{"id": created})
+	})
+	post := func(key, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/songs", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		req.Header.Set(UserHeader, user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("k1", "bob", `{"song":"Uprising"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = post("k1", "bob", `{"song":"Uprising"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, "A retry should get the stored status")
+	assert.JSONEq(t, `{"id":1}`, w.Body.String(), "A retry should get the stored body")
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, created, "A retry should not run the request again")
+
+	w = post("k1", "bob", `{"song":"Starlight"}`)
+	assert.Equal(t, http.StatusConflict, w.Code, "A key reused for a different body should be rejected")
+	w = post("k1", "alice", `{"song":"Starlight"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, "Keys should be scoped to the user")
+	post("", "bob", `{"song":"Uprising"}`)
+	post("", "bob", `{"song":"Uprising"}`)
+	assert.Equal(t, 4, created, "Requests without a key should always run")
+
+	status = http.StatusInternalServerError
+	post("k2", "bob", `{}`)
+	status = http.StatusCreated
+	w = post("k2", "bob", `{}`)
+	assert.Equal(t, http.StatusCreated, w.Code, "Server errors should not be stored")
+
+	now = now.Add(2 * time.Hour)
+	w = post("k1", "bob", `{"song":"Starlight"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, "Expired keys should be reusable")
+	assert.Equal(t, `{"id":`+strconv.Itoa(created)+`}`, w.Body.String())
+
+	w = post(strings.Repeat("k", 200), "bob", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "Overlong keys should be rejected")
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	idempotency := NewIdempotency(repository.NewMemoryIdempotencyRepository(), time.Hour)
+	r := gin.New()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	r.POST("/songs", idempotency.Handler(), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/songs", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post() }()
+	<-started
+	w := post()
+	assert.Equal(t, http.StatusConflict, w.Code, "A retry during the first request should be rejected")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
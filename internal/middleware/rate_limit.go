@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTrackedClients bounds the number of client buckets kept; full buckets are
+// dropped once it is exceeded
+const maxTrackedClients = 10000
+
+// RateLimiter allows each client a number of requests per second with a
+// token bucket. The limits can be changed while it is in use.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	clients map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allows rate requests per second with bursts of up to burst
+// requests. A burst below 1 defaults to rate and a zero rate disables the
+// limiter.
+func NewRateLimiter(rate, burst int) *RateLimiter {
+	l := &RateLimiter{clients: make(map[string]*bucket), now: time.Now}
+	l.SetLimit(rate, burst)
+	return l
+}
+
+// SetLimit changes the limits for all clients
+func (l *RateLimiter) SetLimit(rate, burst int) {
+	if burst < 1 {
+		burst = rate
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate, l.burst = float64(rate), float64(burst)
+}
+
+// Allow takes a token from the bucket of client. When the bucket is empty it
+// returns false and how long until the next token.
+func (l *RateLimiter) Allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	b, ok := l.clients[client]
+	if !ok {
+		if len(l.clients) >= maxTrackedClients {
+			l.dropFull(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// dropFull forgets clients whose buckets have refilled; they start with a
+// full bucket anyway
+func (l *RateLimiter) dropFull(now time.Time) {
+	for client, b := range l.clients {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// RateLimit rejects requests of clients over the limit with 429 Too Many
+// Requests. Clients are identified by IP address.
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, wait := limiter.Allow(c.ClientIP())
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Too many requests",
+				"details": "rate limit exceeded, retry in " + wait.Round(time.Millisecond).String(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(2, 3)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.Allow("a")
+		assert.True(t, ok, "A full bucket should allow a burst")
+	}
+	ok, wait := limiter.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+	ok, _ = limiter.Allow("b")
+	assert.True(t, ok, "Clients should be limited separately")
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.Allow("a")
+	assert.True(t, ok, "The bucket should refill at the rate")
+
+	limiter.SetLimit(0, 0)
+	ok, _ = limiter.Allow("a")
+	assert.True(t, ok, "A zero rate should disable the limiter")
+}
+
+func TestRateLimit(t *testing.T) {
+	r := gin.New()
+	r.Use(RateLimit(NewRateLimiter(1, 1)))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"song-library/pkg/logger"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request headers identifying the caller, used for log correlation and
+// recorded with every write
+const (
+	RequestIDHeader = "X-Request-ID"
+	UserHeader      = "X-User"
+)
+
+// maxRequestIDLength bounds request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// RequestID propagates the X-Request-ID header of the request, or assigns
+// a new ID, and returns it in the response. The request context carries the
+// ID, route, method and user for every line logged with it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			c.Request.Header.Set(RequestIDHeader, id)
+		}
+		c.Header(RequestIDHeader, id)
+
+		fields := logger.Fields{
+			"request_id": id,
+			"method":     c.Request.Method,
+			"route":      c.FullPath(),
+		}
+		if user := c.GetHeader(UserHeader); user != "" {
+			fields["user"] = user
+		}
+		c.Request = c.Request.WithContext(logger.NewRequestContext(c.Request.Context(), fields))
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestLogger logs one line per request with its status, latency and the
+// fields of the request context. Server errors are logged as errors and
+// client errors as warnings; only one in every sampling successful
+// requests is logged.
+type RequestLogger struct {
+	sampling atomic.Int64
+	count    atomic.Int64
+}
+
+// NewRequestLogger creates a RequestLogger that logs one in sampling
+// successful requests; values below 1 log all of them
+func NewRequestLogger(sampling int) *RequestLogger {
+	l := &RequestLogger{}
+	l.SetSampling(sampling)
+	return l
+}
+
+// SetSampling changes the sampling of successful requests
+func (l *RequestLogger) SetSampling(sampling int) {
+	if sampling < 1 {
+		sampling = 1
+	}
+	l.sampling.Store(int64(sampling))
+}
+
+// Handler returns the middleware. It must run after RequestID.
+func (l *RequestLogger) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusBadRequest && (l.count.Add(1)-1)%l.sampling.Load() != 0 {
+			return
+		}
+		fields := logger.Fields{
+			"path":       c.Request.URL.Path,
+			"status":     status,
+			"latency_ms": logger.Milliseconds(time.Since(start)),
+			"client_ip":  c.ClientIP(),
+			"size":       c.Writer.Size(),
+		}
+		if len(c.Errors) > 0 {
+			fields["error"] = c.Errors.String()
+		}
+		ctx := c.Request.Context()
+		switch {
+		case status >= http.StatusInternalServerError:
+			logger.ErrorContext(ctx, "Request failed", fields)
+		case status >= http.StatusBadRequest:
+			logger.WarnContext(ctx, "Request rejected", fields)
+		default:
+			logger.InfoContext(ctx, "Request completed", fields)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"song-library/pkg/logger"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	var fields logger.Fields
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/songs/:id", func(c *gin.Context) {
+		fields = logger.FieldsFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/songs/1", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	req.Header.Set(UserHeader, "alice")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "req-42", w.Header().Get(RequestIDHeader), "The request ID should be propagated")
+	assert.Equal(t, "req-42", fields["request_id"])
+	assert.Equal(t, "/songs/:id", fields["route"])
+	assert.Equal(t, "alice", fields["user"])
+
+	req = httptest.NewRequest("GET", "/songs/1", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Len(t, w.Header().Get(RequestIDHeader), 32, "Invalid request IDs should be replaced")
+	assert.Equal(t, w.Header().Get(RequestIDHeader), fields["request_id"])
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionMerge   = "merge"
+)
+
+// AuditEntry records a single write operation on a song
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Action    string    `gorm:"not null;index" json:"action"`
+	SongID    uint      `gorm:"not null;index" json:"song_id"`
+	Actor     string    `gorm:"index" json:"actor"`
+	RequestID string    `gorm:"index" json:"request_id,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Before    JSON      `gorm:"type:text" json:"before" swaggertype:"object"`
+	After     JSON      `gorm:"type:text" json:"after" swaggertype:"object"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName keeps the audit table name singular, as in the SQL migration
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// JSON is a JSON document stored as text and emitted verbatim
+type JSON string
+
+// NewJSON marshals v, returning an empty JSON for nil values
+func NewJSON(v interface{}) (JSON, error) {
+	if v == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return JSON(data), nil
+}
+
+// MarshalJSON emits the stored document, or null when empty
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// IdempotencyKey is a client-supplied key for a non-idempotent request and
+// the response it produced. A key without a status belongs to a request
+// that is still running.
+type IdempotencyKey struct {
+	// Key is the Idempotency-Key header prefixed with the user who sent it
+	Key string `gorm:"primaryKey;size:255" json:"key"`
+	// Fingerprint is a hash of the method, path and body of the request
+	Fingerprint string    `gorm:"size:64;not null" json:"fingerprint"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// Completed reports whether the response of the request has been stored
+func (k *IdempotencyKey) Completed() bool {
+	return k.Status != 0
+}
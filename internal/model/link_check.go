@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Link check statuses
+const (
+	LinkOK     = "ok"
+	LinkBroken = "broken"
+)
+
+// LinkCheck is the result of the latest check of a song's link
+type LinkCheck struct {
+	SongID uint `gorm:"primaryKey;autoIncrement:false" json:"song_id"`
+	// URL is the link that was checked; when the song's link changes, the
+	// check is out of date until the link is checked again
+	URL    string `gorm:"not null" json:"url"`
+	Status string `gorm:"size:16;not null;index" json:"status"`
+	// StatusCode is the HTTP status of the final response, 0 when there
+	// was none
+	StatusCode int `json:"status_code"`
+	// FinalURL is where redirects led
+	FinalURL    string `json:"final_url,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	// Provider and MediaID identify the media on known sites, e.g.
+	// "youtube" and the video ID
+	Provider  string    `gorm:"size:32" json:"provider,omitempty"`
+	MediaID   string    `json:"media_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `gorm:"not null;index" json:"checked_at"`
+}
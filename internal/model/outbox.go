@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change that caused it. The relay publishes unpublished events in ID order.
+type OutboxEvent struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	EventID   string `gorm:"not null;uniqueIndex" json:"event_id"`
+	EventType string `gorm:"not null" json:"event_type"`
+	SongID    uint   `gorm:"not null;index" json:"song_id"`
+	Payload   string `gorm:"type:text;not null" json:"payload"`
+	Attempts  int    `gorm:"not null;default:0" json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	// NextAttemptAt delays the retry of a failed event, and with it the
+	// later events of its song
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PublishedAt   *time.Time `gorm:"index" json:"published_at,omitempty"`
+	// DeadAt is set when the event ran out of attempts. Dead events are not
+	// published and no longer hold back the later events of their song.
+	DeadAt *time.Time `gorm:"index" json:"dead_at,omitempty"`
+}
+
+// TableName keeps the outbox table name singular, as in the SQL migration
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
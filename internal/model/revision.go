@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// SongRevision is a snapshot of a song's fields taken after each change
+type SongRevision struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SongID      uint      `gorm:"not null;uniqueIndex:idx_song_revisions_song_revision" json:"song_id"`
+	Revision    int       `gorm:"not null;uniqueIndex:idx_song_revisions_song_revision" json:"revision"`
+	GroupName   string    `json:"group_name"`
+	SongName    string    `json:"song_name"`
+	ReleaseDate time.Time `json:"release_date"`
+	Text        string    `json:"text"`
+	LRC         string    `gorm:"column:lrc" json:"lrc,omitempty"`
+	ChordPro    string    `gorm:"column:chordpro" json:"chordpro,omitempty"`
+	Link        string    `json:"link"`
+	Author      string    `json:"author"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewSongRevision snapshots the current state of song
+func NewSongRevision(song *Song, author string) *SongRevision {
+	return &SongRevision{
+		SongID:      song.ID,
+		GroupName:   song.GroupName,
+		SongName:    song.SongName,
+		ReleaseDate: song.ReleaseDate,
+		Text:        song.Text,
+		LRC:         song.LRC,
+		ChordPro:    song.ChordPro,
+		Link:        song.Link,
+		Author:      author,
+	}
+}
+
+// SameContent reports whether the revision holds the same song fields as other
+func (r *SongRevision) SameContent(other *SongRevision) bool {
+	return r.GroupName == other.GroupName &&
+		r.SongName == other.SongName &&
+		r.ReleaseDate.Equal(other.ReleaseDate) &&
+		r.Text == other.Text &&
+		r.LRC == other.LRC &&
+		r.ChordPro == other.ChordPro &&
+		r.Link == other.Link
+}
+
+// ApplyTo copies the revision's fields onto song
+func (r *SongRevision) ApplyTo(song *Song) {
+	song.GroupName = r.GroupName
+	song.SongName = r.SongName
+	song.ReleaseDate = r.ReleaseDate
+	song.Text = r.Text
+	song.LRC = r.LRC
+	song.ChordPro = r.ChordPro
+	song.Link = r.Link
+}
@@ -8,7 +8,21 @@ type Song struct {
 	SongName    string    `json:"song_name"`
 	ReleaseDate time.Time `json:"release_date"`
 	Text        string    `json:"text"`
-	Link        string    `json:"link"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	LRC         string    `gorm:"column:lrc" json:"lrc,omitempty"`
+	// ChordPro is the chord sheet of the song in ChordPro format
+	ChordPro string `gorm:"column:chordpro" json:"chordpro,omitempty"`
+	Link     string `json:"link"`
+	// Language is the ISO 639-1 code of the lyrics language, detected from
+	// Text when the song is written
+	Language string `gorm:"size:8;index" json:"language"`
+	// NormalizedKey is the normalized artist and title; it is unique
+	NormalizedKey string `gorm:"column:normalized_key" json:"-"`
+	// SearchGroupName, SearchSongName and SearchText are the artist, title
+	// and lyrics in lower case, followed by their Latin transliteration
+	// when it differs, for substring filters
+	SearchGroupName string    `gorm:"column:search_group_name" json:"-"`
+	SearchSongName  string    `gorm:"column:search_song_name" json:"-"`
+	SearchText      string    `gorm:"column:search_text;type:text" json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
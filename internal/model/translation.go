@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// SongTranslation is the lyrics of a song in another language, verse by
+// verse. Verses[i] translates the i-th verse of the song's Text, where
+// verses are separated by blank lines.
+type SongTranslation struct {
+	SongID uint `gorm:"primaryKey;autoIncrement:false" json:"song_id"`
+	// Language is a BCP 47 language tag such as "en" or "pt-BR"
+	Language   string    `gorm:"primaryKey;size:35" json:"language"`
+	Verses     []string  `gorm:"serializer:json;type:text" json:"verses"`
+	Translator string    `json:"translator"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
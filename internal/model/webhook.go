@@ -0,0 +1,82 @@
+package model
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// Webhook is a partner subscription to song lifecycle events
+type Webhook struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	URL       string     `gorm:"not null" json:"url"`
+	Secret    string     `gorm:"not null" json:"secret,omitempty"`
+	Events    StringList `gorm:"type:text;not null" json:"events" swaggertype:"array,string"`
+	Active    bool       `gorm:"not null" json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook wants events of eventType
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook. Deliveries
+// that exhaust their attempts are marked dead and form the dead-letter list.
+type WebhookDelivery struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	WebhookID     uint      `gorm:"not null;index" json:"webhook_id"`
+	EventID       string    `gorm:"not null" json:"event_id"`
+	EventType     string    `gorm:"not null" json:"event_type"`
+	Payload       string    `gorm:"type:text;not null" json:"-"`
+	Status        string    `gorm:"not null;index" json:"status"`
+	Attempts      int       `gorm:"not null;default:0" json:"attempts"`
+	ResponseCode  int       `json:"response_code,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `gorm:"index" json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// StringList is a list of strings stored as comma-separated text
+type StringList []string
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	return strings.Join(l, ","), nil
+}
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return errors.Errorf("cannot scan %T into StringList", value)
+	}
+	*l = nil
+	if s == "" {
+		return nil
+	}
+	*l = strings.Split(s, ",")
+	return nil
+}
@@ -0,0 +1,161 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"song-library/internal/events"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/pkg/logger"
+	"time"
+)
+
+// Relay defaults
+const (
+	DefaultPollInterval = time.Second
+	DefaultBatchSize    = 100
+	DefaultSongLimit    = 10
+	DefaultMaxAttempts  = 10
+	DefaultBaseBackoff  = time.Second
+	DefaultMaxBackoff   = 5 * time.Minute
+)
+
+// Relay publishes events from the outbox table. An event is marked as
+// published only after the publisher accepted it, so a crash in between
+// leads to a second publish rather than a lost event (at-least-once).
+// Events of one song are published in the order they were written: when an
+// event fails, later events of the same song wait until it is retried,
+// with exponential backoff. After MaxAttempts the event is marked dead and
+// the song's later events go ahead.
+//
+// A batch holds at most SongLimit events of each song, so a song with a
+// long backlog does not hold up the others.
+//
+// Run a single relay per database; concurrent relays would not keep the
+// per-song ordering.
+type Relay struct {
+	repo      repository.SongRepository
+	publisher events.Publisher
+	now       func() time.Time
+	wake      chan struct{}
+
+	PollInterval time.Duration
+	BatchSize    int
+	SongLimit    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// NewRelay creates a Relay that publishes outbox events to publisher
+func NewRelay(repo repository.SongRepository, publisher events.Publisher) *Relay {
+	return &Relay{
+		repo:         repo,
+		publisher:    publisher,
+		now:          time.Now,
+		wake:         make(chan struct{}, 1),
+		PollInterval: DefaultPollInterval,
+		BatchSize:    DefaultBatchSize,
+		SongLimit:    DefaultSongLimit,
+		MaxAttempts:  DefaultMaxAttempts,
+		BaseBackoff:  DefaultBaseBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+	}
+}
+
+// Wake makes Run publish pending events without waiting for the next poll
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes pending events until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while events are being published
+		for r.ProcessBatch(ctx) > 0 && ctx.Err() == nil {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// ProcessBatch publishes up to BatchSize due events and returns how many
+// were published
+func (r *Relay) ProcessBatch(ctx context.Context) int {
+	pending, err := r.repo.GetDueOutboxEvents(r.now(), r.SongLimit, r.BatchSize)
+	if err != nil {
+		logger.Error("Failed to load outbox events", logger.Fields{"error": err.Error()})
+		return 0
+	}
+
+	published := 0
+	blocked := map[uint]bool{}
+	for _, entry := range pending {
+		if blocked[entry.SongID] {
+			continue
+		}
+
+		var e events.Event
+		if err := json.Unmarshal([]byte(entry.Payload), &e); err != nil {
+			// Retrying would not help
+			r.fail(&entry, err, true)
+			continue
+		}
+		if err := r.publisher.Publish(ctx, e); err != nil {
+			blocked[entry.SongID] = true
+			r.fail(&entry, err, false)
+			continue
+		}
+
+		if err := r.repo.MarkOutboxEventPublished(entry.ID, r.now()); err != nil {
+			// The event will be published again on the next run
+			blocked[entry.SongID] = true
+			logger.Error("Failed to mark outbox event as published", logger.Fields{"event_id": entry.EventID, "error": err.Error()})
+			continue
+		}
+		published++
+	}
+	return published
+}
+
+// fail records a failed attempt at entry, marking it dead after
+// MaxAttempts or at once when the failure is permanent
+func (r *Relay) fail(entry *model.OutboxEvent, cause error, permanent bool) {
+	attempts := entry.Attempts + 1
+	fields := logger.Fields{
+		"event_id": entry.EventID,
+		"song_id":  entry.SongID,
+		"attempts": attempts,
+		"error":    cause.Error(),
+	}
+	var err error
+	if permanent || attempts >= r.MaxAttempts {
+		logger.Error("Outbox event moved to dead letters", fields)
+		err = r.repo.MarkOutboxEventDead(entry.ID, cause.Error(), r.now())
+	} else {
+		logger.Error("Failed to publish outbox event", fields)
+		err = r.repo.MarkOutboxEventFailed(entry.ID, cause.Error(), r.now().Add(r.backoff(attempts)))
+	}
+	if err != nil {
+		logger.Error("Failed to record outbox failure", logger.Fields{"event_id": entry.EventID, "error": err.Error()})
+	}
+}
+
+// backoff returns the delay before the next attempt after attempts failures
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.BaseBackoff
+	for i := 1; i < attempts && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.MaxBackoff)
+}
@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"song-library/internal/db"
+	"song-library/internal/events"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// flakyPublisher records published events and fails for the songs in fail
+type flakyPublisher struct {
+	published []events.Event
+	fail      map[uint]bool
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, e events.Event) error {
+	if p.fail[e.SongID] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, e)
+	return nil
+}
+
+func TestRelay_PublishesInOrderPerSong(t *testing.T) {
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
+	repo := repository.NewSongRepository(conn)
+	songService := service.NewSongService(repo)
+
+	ctx := context.Background()
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Starlight"})
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Uprising"})
+	songService.UpdateSong(ctx, "1", &model.Song{Text: "Far away"})
+
+	publisher := &flakyPublisher{fail: map[uint]bool{1: true}}
+	relay := NewRelay(repo, publisher)
+
+	assert.Equal(t, 1, relay.ProcessBatch(ctx), "Only the healthy song should be published")
+	assert.Equal(t, uint(2), publisher.published[0].SongID)
+
+	pending, _ := repo.GetPendingOutboxEvents(10)
+	assert.Len(t, pending, 2, "Failed events should stay in the outbox")
+	assert.Equal(t, 1, pending[0].Attempts, "Failed attempt should be recorded")
+	assert.Equal(t, 0, pending[1].Attempts, "Later events of a failing song should wait")
+
+	publisher.fail = nil
+	assert.Equal(t, 0, relay.ProcessBatch(ctx), "A failed song should wait for its retry")
+	relay.now = func() time.Time { return time.Now().Add(DefaultBaseBackoff) }
+	assert.Equal(t, 2, relay.ProcessBatch(ctx), "Pending events should be published on retry")
+	assert.Equal(t, events.SongCreated, publisher.published[1].Type, "Events of one song should keep their order")
+	assert.Equal(t, events.SongUpdated, publisher.published[2].Type)
+	assert.Equal(t, 0, relay.ProcessBatch(ctx), "Published events should not be published again")
+}
+
+func TestRelay_DeadLetters(t *testing.T) {
+	repo := repository.NewMemorySongRepository()
+	songService := service.NewSongService(repo)
+	ctx := context.Background()
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Starlight"})
+	songService.UpdateSong(ctx, "1", &model.Song{Text: "Far away"})
+	repo.AddOutboxEvent(&model.OutboxEvent{EventID: "garbled", EventType: events.SongUpdated, SongID: 2, Payload: "{"})
+
+	publisher := &flakyPublisher{fail: map[uint]bool{1: true}}
+	relay := NewRelay(repo, publisher)
+	relay.MaxAttempts = 3
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+
+	assert.Equal(t, 0, relay.ProcessBatch(ctx))
+	pending, _ := repo.GetPendingOutboxEvents(10)
+	assert.Len(t, pending, 2, "An event that cannot be decoded should be dead at once")
+
+	relay.ProcessBatch(ctx)
+	assert.Len(t, publisher.published, 0, "The retry should wait for the backoff")
+	for i := 0; i < 2; i++ {
+		now = now.Add(DefaultMaxBackoff)
+		relay.ProcessBatch(ctx)
+	}
+	pending, _ = repo.GetPendingOutboxEvents(10)
+	require.Len(t, pending, 1, "The event should be dead after MaxAttempts")
+	assert.Equal(t, events.SongUpdated, pending[0].EventType)
+	assert.Equal(t, 0, pending[0].Attempts, "Later events should wait while the first is retried")
+
+	publisher.fail = nil
+	assert.Equal(t, 1, relay.ProcessBatch(ctx), "The next event of the song should go ahead once the first is dead")
+}
+
+func TestRelay_RetriesFailedSubscribers(t *testing.T) {
+	repo := repository.NewMemorySongRepository()
+	songService := service.NewSongService(repo)
+	ctx := context.Background()
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Starlight"})
+
+	bus := events.NewBus()
+	var handled []string
+	failing := true
+	bus.Subscribe(func(e events.Event) error {
+		handled = append(handled, e.ID)
+		return nil
+	})
+	bus.Subscribe(func(e events.Event) error {
+		if failing {
+			return errors.New("database is down")
+		}
+		return nil
+	})
+	relay := NewRelay(repo, bus)
+
+	assert.Equal(t, 0, relay.ProcessBatch(ctx), "An event a subscriber failed to handle should not be published")
+	pending, _ := repo.GetPendingOutboxEvents(10)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+
+	failing = false
+	relay.now = func() time.Time { return time.Now().Add(DefaultBaseBackoff) }
+	assert.Equal(t, 1, relay.ProcessBatch(ctx), "The event should be published on retry")
+	assert.Len(t, handled, 2, "Every subscriber should see the retried event")
+}
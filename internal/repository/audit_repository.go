@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"song-library/internal/model"
+	"time"
+)
+
+// AuditFilter narrows an audit log query. Zero values are ignored.
+type AuditFilter struct {
+	SongID    string
+	Actor     string
+	Action    string
+	RequestID string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Offset    int
+}
+
+// AddAuditEntry appends an entry to the audit log. Entries are never
+// updated or deleted.
+func (r *songRepository) AddAuditEntry(entry *model.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *songRepository) AddAuditEntries(entries []*model.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.Create(&entries).Error
+}
+
+// GetAuditEntries returns matching audit entries, newest first
+func (r *songRepository) GetAuditEntries(filter AuditFilter) ([]model.AuditEntry, error) {
+	query := r.db.Model(&model.AuditEntry{})
+	if filter.SongID != "" {
+		query = query.Where("song_id = ?", filter.SongID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var entries []model.AuditEntry
+	if err := query.Order("created_at DESC, id DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"song-library/internal/cache"
+	"song-library/internal/model"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache keys for song reads
+const (
+	songListCacheKey     = "songs:all"
+	songCacheKeyPrefix   = "songs:id:"
+	songQueryCachePrefix = "songs:query:"
+)
+
+// cachedSongRepository is a cache-aside decorator for SongRepository.
+// Reads are served from the cache and loaded through singleflight so that
+// concurrent misses on a popular key hit the database once. Writes go to
+// the wrapped repository and then invalidate the affected keys.
+//
+// Every invalidation advances a generation. A load that overlapped a write
+// does not keep its result, since it may have read the data from before
+// the write. Listings are cached under keys that include the generation,
+// so a write orphans all of them at once and they expire unread.
+type cachedSongRepository struct {
+	SongRepository
+	cache      cache.Cache
+	ttl        time.Duration
+	group      *singleflight.Group
+	generation *atomic.Uint64
+	// lastWrite is the time of the last invalidation in Unix nanoseconds
+	lastWrite *atomic.Int64
+	now       func() time.Time
+	// lag and flightPrefix are set for replica reads, see CacheReplicaReads
+	lag          time.Duration
+	flightPrefix string
+}
+
+// NewCachedSongRepository wraps repo with a read cache whose entries live
+// for ttl
+func NewCachedSongRepository(repo SongRepository, c cache.Cache, ttl time.Duration) SongRepository {
+	return &cachedSongRepository{
+		SongRepository: repo,
+		cache:          c,
+		ttl:            ttl,
+		group:          &singleflight.Group{},
+		generation:     &atomic.Uint64{},
+		lastWrite:      &atomic.Int64{},
+		now:            time.Now,
+	}
+}
+
+// CacheReplicaReads serves reads from the cache of cached, a repository
+// created by NewCachedSongRepository, and loads misses from replica. A
+// replica may not have caught up with the primary for up to lag after a
+// write, so its results are only cached once the last write is older than
+// that. replica is returned as is when cached has no cache.
+func CacheReplicaReads(cached, replica SongRepository, lag time.Duration) SongRepository {
+	c, ok := cached.(*cachedSongRepository)
+	if !ok {
+		return replica
+	}
+	view := *c
+	view.SongRepository = replica
+	view.lag = lag
+	// Reads that must see the primary do not join replica loads
+	view.flightPrefix = "replica:"
+	return &view
+}
+
+// WithContext binds the wrapped repository to ctx and keeps the cache
+func (r *cachedSongRepository) WithContext(ctx context.Context) SongRepository {
+	bound := *r
+	bound.SongRepository = WithContext(r.SongRepository, ctx)
+	return &bound
+}
+
+func (r *cachedSongRepository) GetSongs() ([]model.Song, error) {
+	var songs []model.Song
+	err := r.load(songListCacheKey, &songs, func() (interface{}, error) {
+		return r.SongRepository.GetSongs()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return songs, nil
+}
+
+// ListSongs caches every distinct query, pages included
+func (r *cachedSongRepository) ListSongs(query SongQuery) ([]model.Song, error) {
+	params, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	key := songQueryCachePrefix + strconv.FormatUint(r.generation.Load(), 10) + ":" + string(params)
+	var songs []model.Song
+	err = r.load(key, &songs, func() (interface{}, error) {
+		return r.SongRepository.ListSongs(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return songs, nil
+}
+
+func (r *cachedSongRepository) GetSongByID(id string) (*model.Song, error) {
+	var song model.Song
+	err := r.load(songCacheKey(id), &song, func() (interface{}, error) {
+		return r.SongRepository.GetSongByID(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
+
+func (r *cachedSongRepository) AddSong(song *model.Song) error {
+	defer r.invalidate()
+	return r.SongRepository.AddSong(song)
+}
+
+func (r *cachedSongRepository) UpdateSong(id string, song *model.Song) error {
+	defer r.invalidate(id)
+	return r.SongRepository.UpdateSong(id, song)
+}
+
+func (r *cachedSongRepository) SaveSong(song *model.Song) error {
+	defer r.invalidate(strconv.FormatUint(uint64(song.ID), 10))
+	return r.SongRepository.SaveSong(song)
+}
+
+func (r *cachedSongRepository) DeleteSong(id string) error {
+	defer r.invalidate(id)
+	return r.SongRepository.DeleteSong(id)
+}
+
+func (r *cachedSongRepository) AddSongs(songs []*model.Song) error {
+	defer r.invalidate()
+	return r.SongRepository.AddSongs(songs)
+}
+
+func (r *cachedSongRepository) SaveSongs(songs []*model.Song) error {
+	defer r.invalidate(songIDs(songs)...)
+	return r.SongRepository.SaveSongs(songs)
+}
+
+func (r *cachedSongRepository) DeleteSongs(ids []string) error {
+	defer r.invalidate(ids...)
+	return r.SongRepository.DeleteSongs(ids)
+}
+
+// SaveLinkCheck invalidates the listings, which can filter by broken links
+func (r *cachedSongRepository) SaveLinkCheck(check *model.LinkCheck) error {
+	defer r.invalidate()
+	return r.SongRepository.SaveLinkCheck(check)
+}
+
+// Transaction bypasses the cache inside the transaction and invalidates
+// every song written by fn once the transaction has finished
+func (r *cachedSongRepository) Transaction(fn func(repo SongRepository) error) error {
+	written := &writeTracker{}
+	defer func() { r.invalidate(written.ids()...) }()
+
+	return r.SongRepository.Transaction(func(tx SongRepository) error {
+		written.SongRepository = tx
+		return fn(written)
+	})
+}
+
+// load decodes the cached value of key into dst, calling fetch on a miss
+func (r *cachedSongRepository) load(key string, dst interface{}, fetch func() (interface{}, error)) error {
+	if data, ok := r.cache.Get(key); ok {
+		if err := json.Unmarshal(data, dst); err == nil {
+			return nil
+		}
+	}
+
+	// Loads started after a write do not join one started before it
+	generation := r.generation.Load()
+	flight := r.flightPrefix + key + "@" + strconv.FormatUint(generation, 10)
+	data, err, _ := r.group.Do(flight, func() (interface{}, error) {
+		value, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if r.generation.Load() != generation || r.lagging() {
+			return data, nil
+		}
+		r.cache.Set(key, data, r.ttl)
+		// A write may have invalidated the key between the check and Set
+		if r.generation.Load() != generation {
+			r.cache.Delete(key)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data.([]byte), dst)
+}
+
+// invalidate drops the song list and the given songs from the cache and
+// orphans the cached listings
+func (r *cachedSongRepository) invalidate(ids ...string) {
+	r.lastWrite.Store(r.now().UnixNano())
+	r.generation.Add(1)
+	keys := []string{songListCacheKey}
+	for _, id := range ids {
+		keys = append(keys, songCacheKey(id))
+	}
+	r.cache.Delete(keys...)
+}
+
+// lagging reports whether loaded data may predate the last write because
+// it comes from a replica
+func (r *cachedSongRepository) lagging() bool {
+	return r.lag > 0 && r.now().Sub(time.Unix(0, r.lastWrite.Load())) < r.lag
+}
+
+func songIDs(songs []*model.Song) []string {
+	ids := make([]string, len(songs))
+	for i, song := range songs {
+		ids[i] = strconv.FormatUint(uint64(song.ID), 10)
+	}
+	return ids
+}
+
+func songCacheKey(id string) string {
+	return songCacheKeyPrefix + id
+}
+
+// writeTracker records the songs written through a transactional repository
+type writeTracker struct {
+	SongRepository
+	mu      sync.Mutex
+	written []string
+}
+
+func (w *writeTracker) track(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = append(w.written, id)
+}
+
+func (w *writeTracker) ids() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+func (w *writeTracker) AddSong(song *model.Song) error {
+	err := w.SongRepository.AddSong(song)
+	w.track(strconv.FormatUint(uint64(song.ID), 10))
+	return err
+}
+
+func (w *writeTracker) UpdateSong(id string, song *model.Song) error {
+	w.track(id)
+	return w.SongRepository.UpdateSong(id, song)
+}
+
+func (w *writeTracker) SaveSong(song *model.Song) error {
+	w.track(strconv.FormatUint(uint64(song.ID), 10))
+	return w.SongRepository.SaveSong(song)
+}
+
+func (w *writeTracker) DeleteSong(id string) error {
+	w.track(id)
+	return w.SongRepository.DeleteSong(id)
+}
+
+func (w *writeTracker) AddSongs(songs []*model.Song) error {
+	err := w.SongRepository.AddSongs(songs)
+	for _, id := range songIDs(songs) {
+		w.track(id)
+	}
+	return err
+}
+
+func (w *writeTracker) SaveSongs(songs []*model.Song) error {
+	for _, id := range songIDs(songs) {
+		w.track(id)
+	}
+	return w.SongRepository.SaveSongs(songs)
+}
+
+func (w *writeTracker) DeleteSongs(ids []string) error {
+	for _, id := range ids {
+		w.track(id)
+	}
+	return w.SongRepository.DeleteSongs(ids)
+}
+
+// Transaction joins nested transactions to the outer one
+func (w *writeTracker) Transaction(fn func(repo SongRepository) error) error {
+	return fn(w)
+}
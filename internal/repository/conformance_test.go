@@ -0,0 +1,83 @@
+package repository_test
+
+import (
+	"os"
+	"path/filepath"
+	"song-library/config"
+	"song-library/internal/db"
+	"song-library/internal/repository"
+	"song-library/internal/repository/repositorytest"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// PostgresDSNEnv names the variable holding a Postgres database for the
+// conformance tests; they are skipped for Postgres when it is empty. The
+// tables of that database are dropped.
+const PostgresDSNEnv = "TEST_POSTGRES_DSN"
+
+func TestMemoryRepository(t *testing.T) {
+	repositorytest.RunSongRepositoryTests(t, func(t *testing.T) repository.SongRepository {
+		return repository.NewMemorySongRepository()
+	})
+	repositorytest.RunWebhookRepositoryTests(t, func(t *testing.T) repository.WebhookRepository {
+		return repository.NewMemoryWebhookRepository()
+	})
+	repositorytest.RunIdempotencyRepositoryTests(t, func(t *testing.T) repository.IdempotencyRepository {
+		return repository.NewMemoryIdempotencyRepository()
+	})
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	open := func(t *testing.T) *gorm.DB {
+		conn, err := db.Connect(&config.Config{DBDriver: db.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "songs.db")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			sqlDB, _ := conn.DB()
+			sqlDB.Close()
+		})
+		return conn
+	}
+	repositorytest.RunSongRepositoryTests(t, func(t *testing.T) repository.SongRepository {
+		return repository.NewSongRepository(open(t))
+	})
+	repositorytest.RunWebhookRepositoryTests(t, func(t *testing.T) repository.WebhookRepository {
+		return repository.NewWebhookRepository(open(t))
+	})
+	repositorytest.RunIdempotencyRepositoryTests(t, func(t *testing.T) repository.IdempotencyRepository {
+		return repository.NewIdempotencyRepository(open(t))
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresDSNEnv)
+	}
+	open := func(t *testing.T) *gorm.DB {
+		conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.Migrator().DropTable("songs", "song_revisions", "song_translations", "link_checks", "audit_log", "outbox", "webhook_deliveries", "webhooks", "idempotency_keys"); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Migrate(conn); err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	repositorytest.RunSongRepositoryTests(t, func(t *testing.T) repository.SongRepository {
+		return repository.NewSongRepository(open(t))
+	})
+	repositorytest.RunWebhookRepositoryTests(t, func(t *testing.T) repository.WebhookRepository {
+		return repository.NewWebhookRepository(open(t))
+	})
+	repositorytest.RunIdempotencyRepositoryTests(t, func(t *testing.T) repository.IdempotencyRepository {
+		return repository.NewIdempotencyRepository(open(t))
+	})
+}
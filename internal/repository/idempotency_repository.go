@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"song-library/internal/model"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrIdempotencyKeyNotFound is returned when no unexpired key matches
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	// ErrIdempotencyKeyExists is returned when adding a key that is in use
+	ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+)
+
+// IdempotencyRepository stores idempotency keys and the responses of their
+// requests
+type IdempotencyRepository interface {
+	// AddIdempotencyKey stores a new key, replacing an expired one. It
+	// returns ErrIdempotencyKeyExists when the key is in use.
+	AddIdempotencyKey(key *model.IdempotencyKey) error
+	// GetIdempotencyKey returns the key unless it has expired at now
+	GetIdempotencyKey(key string, now time.Time) (*model.IdempotencyKey, error)
+	SaveIdempotencyKey(key *model.IdempotencyKey) error
+	DeleteIdempotencyKey(key string) error
+	// DeleteExpiredIdempotencyKeys removes the keys expired at now and
+	// returns how many there were
+	DeleteExpiredIdempotencyKeys(now time.Time) (int64, error)
+}
+
+// idempotencyRepository implements IdempotencyRepository
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) AddIdempotencyKey(key *model.IdempotencyKey) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(&model.IdempotencyKey{}, "key = ? AND expires_at <= ?", key.Key, key.CreatedAt).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(key).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrIdempotencyKeyExists
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *idempotencyRepository) GetIdempotencyKey(key string, now time.Time) (*model.IdempotencyKey, error) {
+	var stored model.IdempotencyKey
+	if err := r.db.First(&stored, "key = ? AND expires_at > ?", key, now).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *idempotencyRepository) SaveIdempotencyKey(key *model.IdempotencyKey) error {
+	return r.db.Save(key).Error
+}
+
+func (r *idempotencyRepository) DeleteIdempotencyKey(key string) error {
+	return r.db.Delete(&model.IdempotencyKey{}, "key = ?", key).Error
+}
+
+func (r *idempotencyRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	res := r.db.Delete(&model.IdempotencyKey{}, "expires_at <= ?", now)
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"song-library/internal/model"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *songRepository) GetLinkCheck(songID string) (*model.LinkCheck, error) {
+	var check model.LinkCheck
+	if err := r.db.First(&check, "song_id = ?", songID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLinkCheckNotFound
+		}
+		return nil, err
+	}
+	return &check, nil
+}
+
+func (r *songRepository) SaveLinkCheck(check *model.LinkCheck) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "song_id"}},
+		UpdateAll: true,
+	}).Create(check).Error
+}
+
+// GetLinksToCheck returns songs whose link was never checked, changed since
+// its check, or was last checked before the given time. Links without a
+// check of their current URL come first, then those checked longest ago.
+func (r *songRepository) GetLinksToCheck(checkedBefore time.Time, limit int) ([]model.Song, error) {
+	songs := []model.Song{}
+	err := r.db.Model(&model.Song{}).
+		Select("songs.*").
+		Joins("LEFT JOIN link_checks ON link_checks.song_id = songs.id").
+		Where("songs.link <> ''").
+		Where("link_checks.song_id IS NULL OR link_checks.url <> songs.link OR link_checks.checked_at < ?", checkedBefore).
+		Order("CASE WHEN link_checks.url = songs.link THEN 1 ELSE 0 END, link_checks.checked_at, songs.id").
+		Limit(limit).
+		Find(&songs).Error
+	return songs, err
+}
@@ -0,0 +1,1078 @@
+package repository
+
+import (
+	"song-library/internal/model"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// memoryData is the state of an in-memory song repository
+type memoryData struct {
+	songs     map[uint]model.Song
+	revisions []model.SongRevision
+	// translations are keyed by song ID and language
+	translations map[translationKey]model.SongTranslation
+	linkChecks   map[uint]model.LinkCheck
+	audit        []model.AuditEntry
+	outbox       []model.OutboxEvent
+	// sharedOutbox is set while outbox may be shared with another copy
+	sharedOutbox bool
+	nextSongID   uint
+	nextRevID    uint
+	nextAudID    uint
+	nextOutID    uint
+}
+
+// clone returns a copy of d to modify in a transaction. Revisions and
+// audit entries are only ever appended, and writes are serialized, so the
+// copy shares them with d: its appends land past the end of d's slices.
+// The outbox is copied on the first update.
+func (d *memoryData) clone() *memoryData {
+	c := *d
+	c.sharedOutbox = true
+	c.songs = make(map[uint]model.Song, len(d.songs))
+	for id, song := range d.songs {
+		c.songs[id] = song
+	}
+	c.translations = make(map[translationKey]model.SongTranslation, len(d.translations))
+	for key, translation := range d.translations {
+		c.translations[key] = translation
+	}
+	c.linkChecks = make(map[uint]model.LinkCheck, len(d.linkChecks))
+	for id, check := range d.linkChecks {
+		c.linkChecks[id] = check
+	}
+	return &c
+}
+
+// memoryStore is the data shared by an in-memory repository and the
+// repositories of its transactions
+type memoryStore struct {
+	// writeMu serializes writes and transactions
+	writeMu sync.Mutex
+	// mu guards data
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+// memorySongRepository implements SongRepository without a database. Data
+// lives only as long as the process.
+type memorySongRepository struct {
+	store *memoryStore
+	// tx is the private copy of the data inside a transaction
+	tx *memoryData
+}
+
+// NewMemorySongRepository creates an empty in-memory SongRepository
+func NewMemorySongRepository() SongRepository {
+	return &memorySongRepository{store: &memoryStore{data: &memoryData{
+		songs:        map[uint]model.Song{},
+		translations: map[translationKey]model.SongTranslation{},
+		linkChecks:   map[uint]model.LinkCheck{},
+	}}}
+}
+
+// read calls fn with the current data
+func (r *memorySongRepository) read(fn func(d *memoryData)) {
+	if r.tx != nil {
+		fn(r.tx)
+		return
+	}
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	fn(r.store.data)
+}
+
+// write calls fn with the data to modify
+func (r *memorySongRepository) write(fn func(d *memoryData) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	r.store.writeMu.Lock()
+	defer r.store.writeMu.Unlock()
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return fn(r.store.data)
+}
+
+func (r *memorySongRepository) GetSongs() ([]model.Song, error) {
+	var songs []model.Song
+	r.read(func(d *memoryData) {
+		songs = make([]model.Song, 0, len(d.songs))
+		for _, song := range d.songs {
+			songs = append(songs, song)
+		}
+	})
+	sortSongs(songs)
+	return songs, nil
+}
+
+func (r *memorySongRepository) ListSongs(query SongQuery) ([]model.Song, error) {
+	var songs []model.Song
+	r.read(func(d *memoryData) {
+		for _, song := range d.songs {
+			if query.Language != "" && song.Language != query.Language {
+				continue
+			}
+			if query.BrokenLinks && !d.linkBroken(song) {
+				continue
+			}
+			if !query.matches(&song) {
+				continue
+			}
+			songs = append(songs, song)
+		}
+	})
+	if query.Sort == nil {
+		query.Sort = SongSort{{Column: "id"}}
+	}
+	sort.SliceStable(songs, func(i, j int) bool { return query.Sort.compare(&songs[i], &songs[j]) < 0 })
+	if query.After != nil {
+		start := sort.Search(len(songs), func(i int) bool { return query.Sort.compare(&songs[i], query.After) > 0 })
+		songs = songs[start:]
+	}
+	if query.Limit > 0 && len(songs) > query.Limit {
+		songs = songs[:query.Limit]
+	}
+	return songs, nil
+}
+
+func (r *memorySongRepository) CountSongs(query SongQuery) (int, error) {
+	query.After, query.Limit = nil, 0
+	songs, err := r.ListSongs(query)
+	return len(songs), err
+}
+
+func (r *memorySongRepository) ListArtists(after string, limit int) ([]string, error) {
+	names := []string{}
+	r.read(func(d *memoryData) {
+		seen := map[string]bool{}
+		for _, song := range d.songs {
+			if (after == "" || song.GroupName > after) && !seen[song.GroupName] {
+				seen[song.GroupName] = true
+				names = append(names, song.GroupName)
+			}
+		}
+	})
+	sort.Strings(names)
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+	return names, nil
+}
+
+func (r *memorySongRepository) CountArtists() (int, error) {
+	names, err := r.ListArtists("", 0)
+	return len(names), err
+}
+
+func (r *memorySongRepository) GetSongByID(id string) (*model.Song, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, ErrSongNotFound
+	}
+	var song model.Song
+	var ok bool
+	r.read(func(d *memoryData) { song, ok = d.songs[key] })
+	if !ok {
+		return nil, ErrSongNotFound
+	}
+	return &song, nil
+}
+
+func (r *memorySongRepository) GetSongsByIDs(ids []string) ([]model.Song, error) {
+	songs := []model.Song{}
+	r.read(func(d *memoryData) {
+		seen := map[uint]bool{}
+		for _, id := range ids {
+			key, err := parseID(id)
+			if song, ok := d.songs[key]; err == nil && ok && !seen[key] {
+				seen[key] = true
+				songs = append(songs, song)
+			}
+		}
+	})
+	sortSongs(songs)
+	return songs, nil
+}
+
+func (r *memorySongRepository) GetSongsByGroups(groups []string) ([]model.Song, error) {
+	wanted := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		wanted[group] = true
+	}
+	songs := []model.Song{}
+	r.read(func(d *memoryData) {
+		for _, song := range d.songs {
+			if wanted[song.GroupName] {
+				songs = append(songs, song)
+			}
+		}
+	})
+	sortSongs(songs)
+	return songs, nil
+}
+
+func (r *memorySongRepository) GetSongByKey(key string) (*model.Song, error) {
+	var song model.Song
+	var ok bool
+	r.read(func(d *memoryData) { song, ok = d.songByKey(key) })
+	if !ok {
+		return nil, ErrSongNotFound
+	}
+	return &song, nil
+}
+
+// songByKey finds a song by normalized key
+func (d *memoryData) songByKey(key string) (model.Song, bool) {
+	if key == "" {
+		return model.Song{}, false
+	}
+	for _, song := range d.songs {
+		if song.NormalizedKey == key {
+			return song, true
+		}
+	}
+	return model.Song{}, false
+}
+
+// keyTaken reports whether a song other than id has the normalized key,
+// which the database forbids with a unique index
+func (d *memoryData) keyTaken(key string, id uint) bool {
+	song, ok := d.songByKey(key)
+	return ok && song.ID != id
+}
+
+func (r *memorySongRepository) GetSongsByKeys(keys []string) ([]model.Song, error) {
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+	songs := []model.Song{}
+	r.read(func(d *memoryData) {
+		for _, song := range d.songs {
+			if song.NormalizedKey != "" && wanted[song.NormalizedKey] {
+				songs = append(songs, song)
+			}
+		}
+	})
+	sortSongs(songs)
+	return songs, nil
+}
+
+func (r *memorySongRepository) AddSong(song *model.Song) error {
+	setDerived(song)
+	return r.write(func(d *memoryData) error {
+		if d.keyTaken(song.NormalizedKey, 0) {
+			return ErrDuplicateSong
+		}
+		if song.ID == 0 {
+			d.nextSongID++
+			song.ID = d.nextSongID
+		} else if _, ok := d.songs[song.ID]; ok {
+			return errors.Errorf("song %d already exists", song.ID)
+		} else if song.ID > d.nextSongID {
+			d.nextSongID = song.ID
+		}
+		now := time.Now()
+		if song.CreatedAt.IsZero() {
+			song.CreatedAt = now
+		}
+		song.UpdatedAt = now
+		d.songs[song.ID] = *song
+		return nil
+	})
+}
+
+// UpdateSong applies the non-empty fields of song; like the database
+// implementation it does nothing when the song does not exist
+func (r *memorySongRepository) UpdateSong(id string, song *model.Song) error {
+	key, err := parseID(id)
+	if err != nil {
+		return nil
+	}
+	return r.write(func(d *memoryData) error {
+		stored, ok := d.songs[key]
+		if !ok {
+			return nil
+		}
+		if song.NormalizedKey != "" {
+			if d.keyTaken(song.NormalizedKey, key) {
+				return ErrDuplicateSong
+			}
+			stored.NormalizedKey = song.NormalizedKey
+		}
+		if song.GroupName != "" {
+			stored.GroupName = song.GroupName
+		}
+		if song.SongName != "" {
+			stored.SongName = song.SongName
+		}
+		if !song.ReleaseDate.IsZero() {
+			stored.ReleaseDate = song.ReleaseDate
+		}
+		if song.Text != "" {
+			stored.Text = song.Text
+		}
+		if song.Language != "" {
+			stored.Language = song.Language
+		}
+		if song.LRC != "" {
+			stored.LRC = song.LRC
+		}
+		if song.ChordPro != "" {
+			stored.ChordPro = song.ChordPro
+		}
+		if song.Link != "" {
+			stored.Link = song.Link
+		}
+		setSearch(&stored)
+		stored.UpdatedAt = time.Now()
+		song.UpdatedAt = stored.UpdatedAt
+		d.songs[key] = stored
+		return nil
+	})
+}
+
+// SaveSong writes all fields of song, including zero values
+func (r *memorySongRepository) SaveSong(song *model.Song) error {
+	if song.ID == 0 {
+		return r.AddSong(song)
+	}
+	setDerived(song)
+	return r.write(func(d *memoryData) error {
+		if d.keyTaken(song.NormalizedKey, song.ID) {
+			return ErrDuplicateSong
+		}
+		if stored, ok := d.songs[song.ID]; ok && song.CreatedAt.IsZero() {
+			song.CreatedAt = stored.CreatedAt
+		}
+		if song.ID > d.nextSongID {
+			d.nextSongID = song.ID
+		}
+		song.UpdatedAt = time.Now()
+		d.songs[song.ID] = *song
+		return nil
+	})
+}
+
+func (r *memorySongRepository) DeleteSong(id string) error {
+	key, err := parseID(id)
+	if err != nil {
+		return nil
+	}
+	return r.write(func(d *memoryData) error {
+		d.deleteSong(key)
+		return nil
+	})
+}
+
+// AddSongs adds songs all or none, like the single statement of the
+// database repository
+func (r *memorySongRepository) AddSongs(songs []*model.Song) error {
+	return r.Transaction(func(repo SongRepository) error {
+		for _, song := range songs {
+			if err := repo.AddSong(song); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memorySongRepository) SaveSongs(songs []*model.Song) error {
+	return r.Transaction(func(repo SongRepository) error {
+		for _, song := range songs {
+			if err := repo.SaveSong(song); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memorySongRepository) DeleteSongs(ids []string) error {
+	return r.write(func(d *memoryData) error {
+		for _, id := range ids {
+			if key, err := parseID(id); err == nil {
+				d.deleteSong(key)
+			}
+		}
+		return nil
+	})
+}
+
+// Transaction runs fn against a private copy of the data and keeps the copy
+// only when fn succeeds. Transactions and writes are serialized; reads
+// outside the transaction see the data as of before it.
+func (r *memorySongRepository) Transaction(fn func(repo SongRepository) error) error {
+	if r.tx != nil {
+		// Nested transactions roll back on their own, like savepoints
+		nested := r.tx.clone()
+		if err := fn(&memorySongRepository{store: r.store, tx: nested}); err != nil {
+			return err
+		}
+		*r.tx = *nested
+		return nil
+	}
+
+	r.store.writeMu.Lock()
+	defer r.store.writeMu.Unlock()
+	r.store.mu.RLock()
+	tx := r.store.data.clone()
+	r.store.mu.RUnlock()
+
+	if err := fn(&memorySongRepository{store: r.store, tx: tx}); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	r.store.data = tx
+	r.store.mu.Unlock()
+	return nil
+}
+
+// AddRevision stores rev under the next revision number of its song
+func (r *memorySongRepository) AddRevision(rev *model.SongRevision) error {
+	return r.write(func(d *memoryData) error {
+		last := 0
+		for _, existing := range d.revisions {
+			if existing.SongID == rev.SongID && existing.Revision > last {
+				last = existing.Revision
+			}
+		}
+		d.nextRevID++
+		rev.ID = d.nextRevID
+		rev.Revision = last + 1
+		if rev.CreatedAt.IsZero() {
+			rev.CreatedAt = time.Now()
+		}
+		d.revisions = append(d.revisions, *rev)
+		return nil
+	})
+}
+
+func (r *memorySongRepository) AddRevisions(revs []*model.SongRevision) error {
+	return r.Transaction(func(repo SongRepository) error {
+		for _, rev := range revs {
+			if err := repo.AddRevision(rev); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetRevisions returns the revisions of a song, oldest first
+func (r *memorySongRepository) GetRevisions(songID string) ([]model.SongRevision, error) {
+	revs := []model.SongRevision{}
+	key, err := parseID(songID)
+	if err != nil {
+		return revs, nil
+	}
+	r.read(func(d *memoryData) {
+		for _, rev := range d.revisions {
+			if rev.SongID == key {
+				revs = append(revs, rev)
+			}
+		}
+	})
+	sort.Slice(revs, func(i, j int) bool { return revs[i].Revision < revs[j].Revision })
+	return revs, nil
+}
+
+func (r *memorySongRepository) GetRevision(songID string, revision int) (*model.SongRevision, error) {
+	revs, err := r.GetRevisions(songID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rev := range revs {
+		if rev.Revision == revision {
+			return &rev, nil
+		}
+	}
+	return nil, ErrRevisionNotFound
+}
+
+func (r *memorySongRepository) GetLatestRevisions(songIDs []string) ([]model.SongRevision, error) {
+	wanted := make(map[uint]bool, len(songIDs))
+	for _, id := range songIDs {
+		if key, err := parseID(id); err == nil {
+			wanted[key] = true
+		}
+	}
+	latest := map[uint]model.SongRevision{}
+	r.read(func(d *memoryData) {
+		for _, rev := range d.revisions {
+			if wanted[rev.SongID] && rev.Revision > latest[rev.SongID].Revision {
+				latest[rev.SongID] = rev
+			}
+		}
+	})
+	revs := make([]model.SongRevision, 0, len(latest))
+	for _, rev := range latest {
+		revs = append(revs, rev)
+	}
+	sort.Slice(revs, func(i, j int) bool { return revs[i].SongID < revs[j].SongID })
+	return revs, nil
+}
+
+// translationKey identifies the translation of a song into a language
+type translationKey struct {
+	songID   uint
+	language string
+}
+
+// deleteSong deletes a song, its translations and its link check
+func (d *memoryData) deleteSong(id uint) {
+	delete(d.songs, id)
+	delete(d.linkChecks, id)
+	for key := range d.translations {
+		if key.songID == id {
+			delete(d.translations, key)
+		}
+	}
+}
+
+func (r *memorySongRepository) GetTranslations(songID string) ([]model.SongTranslation, error) {
+	translations := []model.SongTranslation{}
+	key, err := parseID(songID)
+	if err != nil {
+		return translations, nil
+	}
+	r.read(func(d *memoryData) {
+		for k, translation := range d.translations {
+			if k.songID == key {
+				translations = append(translations, copyTranslation(translation))
+			}
+		}
+	})
+	sort.Slice(translations, func(i, j int) bool { return translations[i].Language < translations[j].Language })
+	return translations, nil
+}
+
+func (r *memorySongRepository) GetTranslation(songID, lang string) (*model.SongTranslation, error) {
+	key, err := parseID(songID)
+	if err != nil {
+		return nil, ErrTranslationNotFound
+	}
+	var translation model.SongTranslation
+	var ok bool
+	r.read(func(d *memoryData) {
+		translation, ok = d.translations[translationKey{key, lang}]
+	})
+	if !ok {
+		return nil, ErrTranslationNotFound
+	}
+	translation = copyTranslation(translation)
+	return &translation, nil
+}
+
+func (r *memorySongRepository) SaveTranslation(translation *model.SongTranslation) error {
+	return r.write(func(d *memoryData) error {
+		key := translationKey{translation.SongID, translation.Language}
+		now := time.Now()
+		if existing, ok := d.translations[key]; ok {
+			translation.CreatedAt = existing.CreatedAt
+		} else if translation.CreatedAt.IsZero() {
+			translation.CreatedAt = now
+		}
+		translation.UpdatedAt = now
+		d.translations[key] = copyTranslation(*translation)
+		return nil
+	})
+}
+
+func (r *memorySongRepository) DeleteTranslation(songID, lang string) error {
+	key, err := parseID(songID)
+	if err != nil {
+		return ErrTranslationNotFound
+	}
+	return r.write(func(d *memoryData) error {
+		if _, ok := d.translations[translationKey{key, lang}]; !ok {
+			return ErrTranslationNotFound
+		}
+		delete(d.translations, translationKey{key, lang})
+		return nil
+	})
+}
+
+func (r *memorySongRepository) GetLinkCheck(songID string) (*model.LinkCheck, error) {
+	key, err := parseID(songID)
+	if err != nil {
+		return nil, ErrLinkCheckNotFound
+	}
+	var check model.LinkCheck
+	var ok bool
+	r.read(func(d *memoryData) {
+		check, ok = d.linkChecks[key]
+	})
+	if !ok {
+		return nil, ErrLinkCheckNotFound
+	}
+	return &check, nil
+}
+
+func (r *memorySongRepository) SaveLinkCheck(check *model.LinkCheck) error {
+	return r.write(func(d *memoryData) error {
+		d.linkChecks[check.SongID] = *check
+		return nil
+	})
+}
+
+func (r *memorySongRepository) GetLinksToCheck(checkedBefore time.Time, limit int) ([]model.Song, error) {
+	type due struct {
+		song      model.Song
+		checkedAt time.Time
+	}
+	var songs []due
+	r.read(func(d *memoryData) {
+		for _, song := range d.songs {
+			if song.Link == "" {
+				continue
+			}
+			check, ok := d.linkChecks[song.ID]
+			switch {
+			case !ok || check.URL != song.Link:
+				songs = append(songs, due{song: song})
+			case check.CheckedAt.Before(checkedBefore):
+				songs = append(songs, due{song: song, checkedAt: check.CheckedAt})
+			}
+		}
+	})
+	// Current links never checked first (zero time), then oldest checks
+	sort.Slice(songs, func(i, j int) bool {
+		if c := songs[i].checkedAt.Compare(songs[j].checkedAt); c != 0 {
+			return c < 0
+		}
+		return songs[i].song.ID < songs[j].song.ID
+	})
+	result := make([]model.Song, 0, min(len(songs), limit))
+	for _, s := range page(songs, 0, limit) {
+		result = append(result, s.song)
+	}
+	return result, nil
+}
+
+// linkBroken reports whether the latest check of the song's current link
+// failed
+func (d *memoryData) linkBroken(song model.Song) bool {
+	check, ok := d.linkChecks[song.ID]
+	return ok && check.URL == song.Link && check.Status == model.LinkBroken
+}
+
+// AddAuditEntry appends an entry to the audit log
+func (r *memorySongRepository) AddAuditEntry(entry *model.AuditEntry) error {
+	return r.write(func(d *memoryData) error {
+		d.nextAudID++
+		entry.ID = d.nextAudID
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = time.Now()
+		}
+		d.audit = append(d.audit, *entry)
+		return nil
+	})
+}
+
+func (r *memorySongRepository) AddAuditEntries(entries []*model.AuditEntry) error {
+	return r.Transaction(func(repo SongRepository) error {
+		for _, entry := range entries {
+			if err := repo.AddAuditEntry(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetAuditEntries returns matching audit entries, newest first
+func (r *memorySongRepository) GetAuditEntries(filter AuditFilter) ([]model.AuditEntry, error) {
+	entries := []model.AuditEntry{}
+	r.read(func(d *memoryData) {
+		for _, entry := range d.audit {
+			switch {
+			case filter.SongID != "" && strconv.FormatUint(uint64(entry.SongID), 10) != filter.SongID,
+				filter.Actor != "" && entry.Actor != filter.Actor,
+				filter.Action != "" && entry.Action != filter.Action,
+				filter.RequestID != "" && entry.RequestID != filter.RequestID,
+				!filter.Since.IsZero() && entry.CreatedAt.Before(filter.Since),
+				!filter.Until.IsZero() && !entry.CreatedAt.Before(filter.Until):
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(entries[j].CreatedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	return page(entries, filter.Offset, filter.Limit), nil
+}
+
+func (r *memorySongRepository) AddOutboxEvent(event *model.OutboxEvent) error {
+	return r.write(func(d *memoryData) error {
+		for _, existing := range d.outbox {
+			if existing.EventID == event.EventID {
+				return errors.Errorf("outbox event %s already exists", event.EventID)
+			}
+		}
+		d.nextOutID++
+		event.ID = d.nextOutID
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now()
+		}
+		d.outbox = append(d.outbox, *event)
+		return nil
+	})
+}
+
+func (r *memorySongRepository) AddOutboxEvents(events []*model.OutboxEvent) error {
+	return r.Transaction(func(repo SongRepository) error {
+		for _, event := range events {
+			if err := repo.AddOutboxEvent(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetPendingOutboxEvents returns up to limit events that are neither
+// published nor dead, in the order they were written
+func (r *memorySongRepository) GetPendingOutboxEvents(limit int) ([]model.OutboxEvent, error) {
+	events := []model.OutboxEvent{}
+	r.read(func(d *memoryData) {
+		for _, event := range d.outbox {
+			if event.PublishedAt == nil && event.DeadAt == nil {
+				events = append(events, event)
+			}
+		}
+	})
+	return page(events, 0, limit), nil
+}
+
+func (r *memorySongRepository) GetDueOutboxEvents(now time.Time, perSong, limit int) ([]model.OutboxEvent, error) {
+	pending, _ := r.GetPendingOutboxEvents(0)
+	blocked := map[uint]bool{}
+	for _, event := range pending {
+		if event.NextAttemptAt != nil && event.NextAttemptAt.After(now) {
+			blocked[event.SongID] = true
+		}
+	}
+	events := []model.OutboxEvent{}
+	perSongCount := map[uint]int{}
+	for _, event := range pending {
+		if blocked[event.SongID] || perSongCount[event.SongID] >= perSong {
+			continue
+		}
+		perSongCount[event.SongID]++
+		events = append(events, event)
+	}
+	return page(events, 0, limit), nil
+}
+
+func (r *memorySongRepository) MarkOutboxEventPublished(id uint, at time.Time) error {
+	return r.updateOutboxEvent(id, func(event *model.OutboxEvent) {
+		event.PublishedAt = &at
+		event.Attempts++
+		event.LastError = ""
+	})
+}
+
+func (r *memorySongRepository) MarkOutboxEventFailed(id uint, reason string, retryAt time.Time) error {
+	return r.updateOutboxEvent(id, func(event *model.OutboxEvent) {
+		event.Attempts++
+		event.LastError = reason
+		event.NextAttemptAt = &retryAt
+	})
+}
+
+func (r *memorySongRepository) MarkOutboxEventDead(id uint, reason string, at time.Time) error {
+	return r.updateOutboxEvent(id, func(event *model.OutboxEvent) {
+		event.Attempts++
+		event.LastError = reason
+		event.DeadAt = &at
+	})
+}
+
+func (r *memorySongRepository) updateOutboxEvent(id uint, fn func(event *model.OutboxEvent)) error {
+	return r.write(func(d *memoryData) error {
+		if d.sharedOutbox {
+			d.outbox = append([]model.OutboxEvent(nil), d.outbox...)
+			d.sharedOutbox = false
+		}
+		for i := range d.outbox {
+			if d.outbox[i].ID == id {
+				fn(&d.outbox[i])
+			}
+		}
+		return nil
+	})
+}
+
+// memoryWebhookRepository implements WebhookRepository without a database
+type memoryWebhookRepository struct {
+	mu             sync.RWMutex
+	webhooks       map[uint]model.Webhook
+	deliveries     map[uint]model.WebhookDelivery
+	nextWebhookID  uint
+	nextDeliveryID uint
+}
+
+// NewMemoryWebhookRepository creates an empty in-memory WebhookRepository
+func NewMemoryWebhookRepository() WebhookRepository {
+	return &memoryWebhookRepository{
+		webhooks:   map[uint]model.Webhook{},
+		deliveries: map[uint]model.WebhookDelivery{},
+	}
+}
+
+func (r *memoryWebhookRepository) GetWebhooks() ([]model.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	webhooks := make([]model.Webhook, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		webhooks = append(webhooks, copyWebhook(webhook))
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (r *memoryWebhookRepository) GetWebhookByID(id string) (*model.Webhook, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	webhook, ok := r.webhooks[key]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	webhook = copyWebhook(webhook)
+	return &webhook, nil
+}
+
+func (r *memoryWebhookRepository) AddWebhook(webhook *model.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextWebhookID++
+	webhook.ID = r.nextWebhookID
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = webhook.CreatedAt
+	r.webhooks[webhook.ID] = copyWebhook(*webhook)
+	return nil
+}
+
+func (r *memoryWebhookRepository) SaveWebhook(webhook *model.Webhook) error {
+	if webhook.ID == 0 {
+		return r.AddWebhook(webhook)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook.UpdatedAt = time.Now()
+	r.webhooks[webhook.ID] = copyWebhook(*webhook)
+	return nil
+}
+
+// DeleteWebhook removes a webhook together with its delivery log
+func (r *memoryWebhookRepository) DeleteWebhook(id string) error {
+	key, err := parseID(id)
+	if err != nil {
+		return ErrWebhookNotFound
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.webhooks[key]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(r.webhooks, key)
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.WebhookID == key {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepository) AddDelivery(delivery *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.deliveries {
+		if existing.WebhookID == delivery.WebhookID && existing.EventID == delivery.EventID {
+			return nil
+		}
+	}
+	r.nextDeliveryID++
+	delivery.ID = r.nextDeliveryID
+	delivery.CreatedAt = time.Now()
+	delivery.UpdatedAt = delivery.CreatedAt
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryWebhookRepository) SaveDelivery(delivery *model.WebhookDelivery) error {
+	if delivery.ID == 0 {
+		return r.AddDelivery(delivery)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.UpdatedAt = time.Now()
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryWebhookRepository) GetDeliveryByID(id string) (*model.WebhookDelivery, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, ErrDeliveryNotFound
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	delivery, ok := r.deliveries[key]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+func (r *memoryWebhookRepository) GetDeliveries(webhookID, status string) ([]model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	deliveries := []model.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if webhookID != "" && strconv.FormatUint(uint64(delivery.WebhookID), 10) != webhookID {
+			continue
+		}
+		if status != "" && delivery.Status != status {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	return deliveries, nil
+}
+
+func (r *memoryWebhookRepository) GetDueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	deliveries := []model.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.Status == model.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].NextAttemptAt.Equal(deliveries[j].NextAttemptAt) {
+			return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return page(deliveries, 0, limit), nil
+}
+
+// copyTranslation copies the verses so callers cannot modify stored data
+func copyTranslation(translation model.SongTranslation) model.SongTranslation {
+	translation.Verses = append([]string(nil), translation.Verses...)
+	return translation
+}
+
+// copyWebhook copies the event list so callers cannot modify stored data
+func copyWebhook(webhook model.Webhook) model.Webhook {
+	webhook.Events = append(model.StringList(nil), webhook.Events...)
+	return webhook
+}
+
+func parseID(id string) (uint, error) {
+	n, err := strconv.ParseUint(id, 10, 0)
+	return uint(n), err
+}
+
+func sortSongs(songs []model.Song) {
+	sort.Slice(songs, func(i, j int) bool { return songs[i].ID < songs[j].ID })
+}
+
+// page returns up to limit items starting at offset; a zero limit returns
+// all remaining items
+func page[T any](items []T, offset, limit int) []T {
+	offset = max(offset, 0)
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// memoryIdempotencyRepository implements IdempotencyRepository without a
+// database
+type memoryIdempotencyRepository struct {
+	mu   sync.Mutex
+	keys map[string]model.IdempotencyKey
+}
+
+// NewMemoryIdempotencyRepository creates an empty in-memory
+// IdempotencyRepository
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{keys: map[string]model.IdempotencyKey{}}
+}
+
+func (r *memoryIdempotencyRepository) AddIdempotencyKey(key *model.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.keys[key.Key]; ok && stored.ExpiresAt.After(key.CreatedAt) {
+		return ErrIdempotencyKeyExists
+	}
+	r.keys[key.Key] = copyIdempotencyKey(*key)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) GetIdempotencyKey(key string, now time.Time) (*model.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.keys[key]
+	if !ok || !stored.ExpiresAt.After(now) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	stored = copyIdempotencyKey(stored)
+	return &stored, nil
+}
+
+func (r *memoryIdempotencyRepository) SaveIdempotencyKey(key *model.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.Key] = copyIdempotencyKey(*key)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteIdempotencyKey(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, key)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for k, stored := range r.keys {
+		if !stored.ExpiresAt.After(now) {
+			delete(r.keys, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func copyIdempotencyKey(key model.IdempotencyKey) model.IdempotencyKey {
+	key.Body = append([]byte(nil), key.Body...)
+	return key
+}
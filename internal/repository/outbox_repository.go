@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"song-library/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (r *songRepository) AddOutboxEvent(event *model.OutboxEvent) error {
+	return r.db.Create(event).Error
+}
+
+// AddOutboxEvents stores events in order, so they are published in order
+func (r *songRepository) AddOutboxEvents(events []*model.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return r.db.Create(&events).Error
+}
+
+// GetPendingOutboxEvents returns up to limit events that are neither
+// published nor dead, in the order they were written
+func (r *songRepository) GetPendingOutboxEvents(limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := r.db.Where("published_at IS NULL AND dead_at IS NULL").Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *songRepository) GetDueOutboxEvents(now time.Time, perSong, limit int) ([]model.OutboxEvent, error) {
+	blocked := r.db.Model(&model.OutboxEvent{}).
+		Select("song_id").
+		Where("published_at IS NULL AND dead_at IS NULL AND next_attempt_at > ?", now)
+	ranked := r.db.Model(&model.OutboxEvent{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY song_id ORDER BY id) AS song_position").
+		Where("published_at IS NULL AND dead_at IS NULL").
+		Where("song_id NOT IN (?)", blocked)
+	var events []model.OutboxEvent
+	err := r.db.Table("(?) AS pending", ranked).
+		Where("song_position <= ?", perSong).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *songRepository) MarkOutboxEventPublished(id uint, at time.Time) error {
+	return r.db.Model(&model.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"published_at": at,
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   "",
+	}).Error
+}
+
+func (r *songRepository) MarkOutboxEventFailed(id uint, reason string, retryAt time.Time) error {
+	return r.db.Model(&model.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      reason,
+		"next_attempt_at": retryAt,
+	}).Error
+}
+
+func (r *songRepository) MarkOutboxEventDead(id uint, reason string, at time.Time) error {
+	return r.db.Model(&model.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": reason,
+		"dead_at":    at,
+	}).Error
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"song-library/pkg/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicaPingTimeout bounds a single replica health check
+const replicaPingTimeout = 2 * time.Second
+
+// Replica is a read-only copy of the primary database
+type Replica struct {
+	Name string
+	Repo SongRepository
+	// Ping reports whether the replica can serve queries
+	Ping func(ctx context.Context) error
+}
+
+type replicaState struct {
+	Replica
+	healthy atomic.Bool
+}
+
+// ReplicaRouter picks the replica that serves a read. Replicas are used in
+// turn while their health checks pass. Clients that wrote within the
+// read-your-writes window are kept on the primary so that they see their
+// own changes despite replication lag.
+type ReplicaRouter struct {
+	replicas []*replicaState
+	window   time.Duration
+	next     atomic.Uint64
+
+	mu        sync.Mutex
+	lastWrite map[string]time.Time
+	now       func() time.Time
+}
+
+// NewReplicaRouter creates a router over replicas. All replicas start out
+// healthy.
+func NewReplicaRouter(replicas []Replica, window time.Duration) *ReplicaRouter {
+	r := &ReplicaRouter{
+		window:    window,
+		lastWrite: make(map[string]time.Time),
+		now:       time.Now,
+	}
+	for _, replica := range replicas {
+		state := &replicaState{Replica: replica}
+		state.healthy.Store(true)
+		r.replicas = append(r.replicas, state)
+	}
+	return r
+}
+
+// Reader returns the replica repository to read from on behalf of client.
+// It returns false when the read should go to the primary.
+func (r *ReplicaRouter) Reader(client string) (SongRepository, bool) {
+	if r.recentlyWrote(client) {
+		return nil, false
+	}
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		replica := r.replicas[(start+i)%n]
+		if replica.healthy.Load() {
+			return replica.Repo, true
+		}
+	}
+	return nil, false
+}
+
+// Window returns the read-your-writes window, the replication lag the
+// router allows for
+func (r *ReplicaRouter) Window() time.Duration {
+	return r.window
+}
+
+// Wrote records a write by client, sending its reads to the primary for
+// the read-your-writes window
+func (r *ReplicaRouter) Wrote(client string) {
+	if client == "" || r.window <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	for c, at := range r.lastWrite {
+		if now.Sub(at) >= r.window {
+			delete(r.lastWrite, c)
+		}
+	}
+	r.lastWrite[client] = now
+}
+
+func (r *ReplicaRouter) recentlyWrote(client string) bool {
+	if client == "" {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	at, ok := r.lastWrite[client]
+	return ok && r.now().Sub(at) < r.window
+}
+
+// CheckHealth pings every replica, taking failing ones out of rotation and
+// returning recovered ones to it
+func (r *ReplicaRouter) CheckHealth(ctx context.Context) {
+	for _, replica := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		err := replica.Ping(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if replica.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		if healthy {
+			logger.Info("Replica is back in rotation", logger.Fields{"replica": replica.Name})
+		} else {
+			logger.Error("Replica taken out of rotation", logger.Fields{"replica": replica.Name, "error": err.Error()})
+		}
+	}
+}
+
+// Run checks the health of the replicas every interval until ctx is done
+func (r *ReplicaRouter) Run(ctx context.Context, interval time.Duration) {
+	r.CheckHealth(ctx)
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.CheckHealth(ctx)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaRouter(t *testing.T) {
+	replicaA, replicaB := NewMemorySongRepository(), NewMemorySongRepository()
+	var bDown bool
+	router := NewReplicaRouter([]Replica{
+		{Name: "a", Repo: replicaA, Ping: func(context.Context) error { return nil }},
+		{Name: "b", Repo: replicaB, Ping: func(context.Context) error {
+			if bDown {
+				return errors.New("connection refused")
+			}
+			return nil
+		}},
+	}, 5*time.Second)
+	now := time.Now()
+	router.now = func() time.Time { return now }
+
+	used := map[SongRepository]bool{}
+	for i := 0; i < 4; i++ {
+		repo, ok := router.Reader("user:alice")
+		assert.True(t, ok)
+		used[repo] = true
+	}
+	assert.Len(t, used, 2, "Reads should be spread over the replicas")
+
+	router.Wrote("user:alice")
+	_, ok := router.Reader("user:alice")
+	assert.False(t, ok, "A client should read from the primary right after writing")
+	_, ok = router.Reader("user:bob")
+	assert.True(t, ok, "Other clients should keep using the replicas")
+	now = now.Add(5 * time.Second)
+	_, ok = router.Reader("user:alice")
+	assert.True(t, ok, "The replicas should be used again after the window")
+
+	bDown = true
+	router.CheckHealth(context.Background())
+	for i := 0; i < 4; i++ {
+		repo, _ := router.Reader("user:alice")
+		assert.Equal(t, replicaA, repo, "A failing replica should be out of rotation")
+	}
+
+	router.replicas[0].Ping = func(context.Context) error { return errors.New("timeout") }
+	router.CheckHealth(context.Background())
+	_, ok = router.Reader("user:alice")
+	assert.False(t, ok, "Reads should fall back to the primary without healthy replicas")
+
+	bDown = false
+	router.CheckHealth(context.Background())
+	repo, ok := router.Reader("user:alice")
+	assert.True(t, ok)
+	assert.Equal(t, replicaB, repo, "A recovered replica should return to rotation")
+}
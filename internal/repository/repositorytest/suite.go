@@ -0,0 +1,740 @@
+// Package repositorytest holds the conformance tests every repository
+// implementation must pass
+package repositorytest
+
+import (
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunSongRepositoryTests runs the SongRepository conformance tests. newRepo
+// must return an empty repository on every call.
+func RunSongRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.SongRepository) {
+	t.Run("Songs", func(t *testing.T) { testSongs(t, newRepo(t)) })
+	t.Run("Sort", func(t *testing.T) { testSort(t, newRepo(t)) })
+	t.Run("Language", func(t *testing.T) { testLanguage(t, newRepo(t)) })
+	t.Run("Filters", func(t *testing.T) { testFilters(t, newRepo(t)) })
+	t.Run("Artists", func(t *testing.T) { testArtists(t, newRepo(t)) })
+	t.Run("Duplicates", func(t *testing.T) { testDuplicates(t, newRepo(t)) })
+	t.Run("Batch", func(t *testing.T) { testBatch(t, newRepo(t)) })
+	t.Run("Revisions", func(t *testing.T) { testRevisions(t, newRepo(t)) })
+	t.Run("Translations", func(t *testing.T) { testTranslations(t, newRepo(t)) })
+	t.Run("LinkChecks", func(t *testing.T) { testLinkChecks(t, newRepo(t)) })
+	t.Run("AuditLog", func(t *testing.T) { testAuditLog(t, newRepo(t)) })
+	t.Run("Outbox", func(t *testing.T) { testOutbox(t, newRepo(t)) })
+	t.Run("Transaction", func(t *testing.T) { testTransaction(t, newRepo(t)) })
+}
+
+// RunWebhookRepositoryTests runs the WebhookRepository conformance tests
+func RunWebhookRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.WebhookRepository) {
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newRepo(t)) })
+	t.Run("Deliveries", func(t *testing.T) { testDeliveries(t, newRepo(t)) })
+}
+
+// RunIdempotencyRepositoryTests runs the IdempotencyRepository conformance
+// tests
+func RunIdempotencyRepositoryTests(t *testing.T, newRepo func(t *testing.T) repository.IdempotencyRepository) {
+	t.Run("IdempotencyKeys", func(t *testing.T) { testIdempotencyKeys(t, newRepo(t)) })
+}
+
+func id(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
+
+func testSongs(t *testing.T, repo repository.SongRepository) {
+	songs, err := repo.GetSongs()
+	require.NoError(t, err)
+	assert.NotNil(t, songs, "An empty list should not be nil")
+	assert.Empty(t, songs)
+
+	release := time.Date(2006, 9, 4, 0, 0, 0, 0, time.UTC)
+	starlight := &model.Song{GroupName: "Muse", SongName: "Starlight", ReleaseDate: release, Link: "https://example.com/starlight"}
+	require.NoError(t, repo.AddSong(starlight))
+	assert.NotZero(t, starlight.ID, "AddSong should assign an ID")
+	assert.False(t, starlight.CreatedAt.IsZero(), "AddSong should set CreatedAt")
+	creep := &model.Song{GroupName: "Radiohead", SongName: "Creep"}
+	require.NoError(t, repo.AddSong(creep))
+	uprising := &model.Song{GroupName: "Muse", SongName: "Uprising"}
+	require.NoError(t, repo.AddSong(uprising))
+
+	got, err := repo.GetSongByID(id(starlight.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "Starlight", got.SongName)
+	assert.True(t, release.Equal(got.ReleaseDate), "Release date should round-trip")
+
+	_, err = repo.GetSongByID("9999")
+	assert.ErrorIs(t, err, repository.ErrSongNotFound)
+
+	songs, err = repo.GetSongs()
+	require.NoError(t, err)
+	assert.Len(t, songs, 3)
+
+	byIDs, err := repo.GetSongsByIDs([]string{id(uprising.ID), "9999", id(starlight.ID)})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{starlight.ID, uprising.ID}, songIDs(byIDs), "Missing IDs should be skipped")
+
+	byGroups, err := repo.GetSongsByGroups([]string{"Muse", "Queen"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{starlight.ID, uprising.ID}, songIDs(byGroups))
+
+	require.NoError(t, repo.UpdateSong(id(starlight.ID), &model.Song{SongName: "Starlight (Live)"}))
+	got, _ = repo.GetSongByID(id(starlight.ID))
+	assert.Equal(t, "Starlight (Live)", got.SongName)
+	assert.Equal(t, "Muse", got.GroupName, "UpdateSong should keep empty fields")
+	assert.Equal(t, "https://example.com/starlight", got.Link)
+
+	got.Link = ""
+	require.NoError(t, repo.SaveSong(got))
+	got, _ = repo.GetSongByID(id(starlight.ID))
+	assert.Empty(t, got.Link, "SaveSong should write empty fields")
+	assert.Equal(t, "Starlight (Live)", got.SongName)
+
+	require.NoError(t, repo.DeleteSong(id(creep.ID)))
+	_, err = repo.GetSongByID(id(creep.ID))
+	assert.ErrorIs(t, err, repository.ErrSongNotFound, "Deleted songs should be gone")
+	songs, _ = repo.GetSongs()
+	assert.Len(t, songs, 2)
+}
+
+func testSort(t *testing.T, repo repository.SongRepository) {
+	date := func(year int) time.Time { return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC) }
+	for _, song := range []*model.Song{
+		{GroupName: "Muse", SongName: "Uprising", ReleaseDate: date(2009)},
+		{GroupName: "Muse", SongName: "Starlight", ReleaseDate: date(2006)},
+		{GroupName: "Radiohead", SongName: "Creep", ReleaseDate: date(1992)},
+		{GroupName: "Muse", SongName: "Madness", ReleaseDate: date(2009)},
+		{GroupName: "Placebo", SongName: "Pure Morning", ReleaseDate: date(1998)},
+	} {
+		require.NoError(t, repo.AddSong(song))
+	}
+
+	for _, tc := range []struct {
+		sort string
+		want []uint
+	}{
+		{"", []uint{1, 2, 3, 4, 5}},
+		{"release_date", []uint{3, 5, 2, 1, 4}},
+		{"-release_date,song_name", []uint{4, 1, 2, 5, 3}},
+		{"group_name", []uint{1, 2, 4, 5, 3}},
+		{"group_name,-id", []uint{4, 2, 1, 5, 3}},
+	} {
+		order, err := repository.ParseSongSort(tc.sort)
+		require.NoError(t, err)
+		songs, err := repo.ListSongs(repository.SongQuery{Sort: order})
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, songIDs(songs), "sort %q", tc.sort)
+	}
+	songs, err := repo.ListSongs(repository.SongQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 3, 4, 5}, songIDs(songs), "Songs should be sorted by ID by default")
+
+	// Keyset pages continue after the last song of the previous page, in
+	// every direction mix, including past songs deleted in the meantime
+	for _, s := range []string{"release_date", "-release_date,song_name", "-group_name,-id", "created_at"} {
+		order, _ := repository.ParseSongSort(s)
+		all, err := repo.ListSongs(repository.SongQuery{Sort: order})
+		require.NoError(t, err)
+		var paged []model.Song
+		var after *model.Song
+		for {
+			page, err := repo.ListSongs(repository.SongQuery{Sort: order, After: after, Limit: 2})
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			paged = append(paged, page...)
+			last := page[len(page)-1]
+			after = &last
+		}
+		assert.Equal(t, songIDs(all), songIDs(paged), "sort %q", s)
+	}
+
+	order, _ := repository.ParseSongSort("release_date")
+	first, err := repo.ListSongs(repository.SongQuery{Sort: order, Limit: 2})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteSong(id(first[1].ID)))
+	require.NoError(t, repo.AddSong(&model.Song{GroupName: "Placebo", SongName: "Nancy Boy", ReleaseDate: date(1996)}))
+	rest, err := repo.ListSongs(repository.SongQuery{Sort: order, After: &first[1]})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{2, 1, 4}, songIDs(rest), "Inserts before the cursor should not shift the next page")
+}
+
+func testLanguage(t *testing.T, repo repository.SongRepository) {
+	kino := &model.Song{GroupName: "Кино", SongName: "Звезда по имени Солнце", Text: "Белый снег, серый лёд, на растрескавшейся земле"}
+	require.NoError(t, repo.AddSong(kino))
+	assert.Equal(t, "ru", kino.Language, "AddSong should detect the language")
+	batch := []*model.Song{
+		{GroupName: "Muse", SongName: "Starlight", Text: "Far away, this ship is taking me far away"},
+		{GroupName: "Muse", SongName: "Uprising"},
+	}
+	require.NoError(t, repo.AddSongs(batch))
+	assert.Equal(t, "en", batch[0].Language, "AddSongs should detect the language")
+	assert.Empty(t, batch[1].Language, "Songs without lyrics should have no language")
+
+	songs, err := repo.ListSongs(repository.SongQuery{Language: "ru"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{kino.ID}, songIDs(songs))
+
+	batch[1].Text = "Они не заставят нас"
+	require.NoError(t, repo.SaveSong(batch[1]))
+	songs, _ = repo.ListSongs(repository.SongQuery{Language: "ru", Sort: repository.SongSort{{Column: "id", Desc: true}}})
+	assert.Equal(t, []uint{batch[1].ID, kino.ID}, songIDs(songs), "SaveSong should detect the language")
+}
+
+func testFilters(t *testing.T, repo repository.SongRepository) {
+	date := func(year int) time.Time { return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC) }
+	kino := &model.Song{GroupName: "Кино", SongName: "Звезда по имени Солнце", Text: "Белый снег, серый лёд", ReleaseDate: date(1989)}
+	uprising := &model.Song{GroupName: "Muse", SongName: "Uprising", Text: "They will not force us", ReleaseDate: date(2009)}
+	percent := &model.Song{GroupName: "Muse", SongName: "100% Pure", ReleaseDate: date(2006)}
+	require.NoError(t, repo.AddSongs([]*model.Song{kino, uprising, percent}))
+
+	for _, tc := range []struct {
+		name  string
+		query repository.SongQuery
+		want  []uint
+	}{
+		{"artist ignores case", repository.SongQuery{GroupName: "MUS"}, []uint{uprising.ID, percent.ID}},
+		{"Cyrillic title", repository.SongQuery{SongName: "звезда"}, []uint{kino.ID}},
+		{"transliterated title", repository.SongQuery{SongName: "Zvezda"}, []uint{kino.ID}},
+		{"lyrics", repository.SongQuery{Text: "force"}, []uint{uprising.ID}},
+		{"wildcards are literal", repository.SongQuery{SongName: "0%"}, []uint{percent.ID}},
+		{"underscore is literal", repository.SongQuery{SongName: "u_r"}, []uint{}},
+		{"released after", repository.SongQuery{ReleasedAfter: date(2006)}, []uint{uprising.ID}},
+		{"released before", repository.SongQuery{ReleasedBefore: date(2009)}, []uint{kino.ID, percent.ID}},
+		{"combined", repository.SongQuery{GroupName: "muse", ReleasedBefore: date(2009)}, []uint{percent.ID}},
+	} {
+		songs, err := repo.ListSongs(tc.query)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, songIDs(songs), tc.name)
+		count, err := repo.CountSongs(tc.query)
+		require.NoError(t, err)
+		assert.Equal(t, len(tc.want), count, tc.name)
+	}
+
+	count, err := repo.CountSongs(repository.SongQuery{GroupName: "muse", After: uprising, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "CountSongs should ignore After and Limit")
+
+	require.NoError(t, repo.UpdateSong(id(uprising.ID), &model.Song{SongName: "Восстание"}))
+	songs, _ := repo.ListSongs(repository.SongQuery{SongName: "vosstanie"})
+	assert.Equal(t, []uint{uprising.ID}, songIDs(songs), "UpdateSong should update the search columns")
+	songs, _ = repo.ListSongs(repository.SongQuery{GroupName: "muse", Text: "force"})
+	assert.Equal(t, []uint{uprising.ID}, songIDs(songs), "UpdateSong should keep the search columns of fields it leaves alone")
+}
+
+func testArtists(t *testing.T, repo repository.SongRepository) {
+	for _, song := range []*model.Song{
+		{GroupName: "Radiohead", SongName: "Creep"},
+		{GroupName: "Muse", SongName: "Uprising"},
+		{GroupName: "Placebo", SongName: "Pure Morning"},
+		{GroupName: "Muse", SongName: "Starlight"},
+	} {
+		require.NoError(t, repo.AddSong(song))
+	}
+
+	names, err := repo.ListArtists("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Muse", "Placebo", "Radiohead"}, names)
+	names, err = repo.ListArtists("Muse", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Placebo"}, names)
+	names, err = repo.ListArtists("Radiohead", 10)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	count, err := repo.CountArtists()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func testDuplicates(t *testing.T, repo repository.SongRepository) {
+	starlight := &model.Song{GroupName: "Muse", SongName: "Starlight"}
+	require.NoError(t, repo.AddSong(starlight))
+	assert.Equal(t, "muse|starlight", starlight.NormalizedKey, "AddSong should set the normalized key")
+
+	got, err := repo.GetSongByKey("muse|starlight")
+	require.NoError(t, err)
+	assert.Equal(t, starlight.ID, got.ID)
+	_, err = repo.GetSongByKey("muse|uprising")
+	assert.ErrorIs(t, err, repository.ErrSongNotFound)
+
+	err = repo.AddSong(&model.Song{GroupName: "MUSE", SongName: "Starlight!"})
+	assert.ErrorIs(t, err, repository.ErrDuplicateSong, "Songs differing only in case and punctuation are duplicates")
+
+	uprising := &model.Song{GroupName: "Muse", SongName: "Uprising"}
+	require.NoError(t, repo.AddSong(uprising))
+	uprising.SongName = "Starlight"
+	assert.ErrorIs(t, repo.SaveSong(uprising), repository.ErrDuplicateSong)
+	err = repo.UpdateSong(id(uprising.ID), &model.Song{SongName: "Starlight", NormalizedKey: "muse|starlight"})
+	assert.ErrorIs(t, err, repository.ErrDuplicateSong)
+
+	songs, err := repo.GetSongs()
+	require.NoError(t, err)
+	assert.Len(t, songs, 2)
+}
+
+func testBatch(t *testing.T, repo repository.SongRepository) {
+	songs := []*model.Song{
+		{GroupName: "Muse", SongName: "Starlight"},
+		{GroupName: "Muse", SongName: "Uprising"},
+		{GroupName: "Radiohead", SongName: "Creep"},
+	}
+	require.NoError(t, repo.AddSongs(songs))
+	for _, song := range songs {
+		assert.NotZero(t, song.ID, "AddSongs should assign IDs")
+	}
+	err := repo.AddSongs([]*model.Song{{GroupName: "Queen", SongName: "Bicycle Race"}, {GroupName: "muse", SongName: "STARLIGHT"}})
+	assert.ErrorIs(t, err, repository.ErrDuplicateSong)
+	all, _ := repo.GetSongs()
+	assert.Len(t, all, 3, "A failed AddSongs should add nothing")
+
+	byKeys, err := repo.GetSongsByKeys([]string{"muse|uprising", "radiohead|creep", "queen|bicycle race"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{songs[1].ID, songs[2].ID}, songIDs(byKeys))
+
+	songs[0].Text, songs[1].SongName = "Far away", "Uprising (Live)"
+	require.NoError(t, repo.SaveSongs(songs[:2]))
+	got, _ := repo.GetSongByID(id(songs[1].ID))
+	assert.Equal(t, "Uprising (Live)", got.SongName)
+	assert.Equal(t, "muse|uprising live", got.NormalizedKey, "SaveSongs should update the normalized key")
+	got, _ = repo.GetSongByID(id(songs[0].ID))
+	assert.Equal(t, "Far away", got.Text)
+
+	require.NoError(t, repo.DeleteSongs([]string{id(songs[0].ID), id(songs[2].ID), "9999"}))
+	all, _ = repo.GetSongs()
+	assert.Equal(t, []uint{songs[1].ID}, songIDs(all))
+
+	revs := []*model.SongRevision{
+		model.NewSongRevision(songs[1], "alice"),
+		model.NewSongRevision(songs[1], "bob"),
+	}
+	require.NoError(t, repo.AddRevision(model.NewSongRevision(songs[1], "")))
+	require.NoError(t, repo.AddRevisions(revs))
+	assert.Equal(t, 2, revs[0].Revision, "AddRevisions should continue the revision numbers")
+	assert.Equal(t, 3, revs[1].Revision)
+	latest, err := repo.GetLatestRevisions([]string{id(songs[1].ID), id(songs[0].ID)})
+	require.NoError(t, err)
+	require.Len(t, latest, 1, "Songs without revisions should be skipped")
+	assert.Equal(t, "bob", latest[0].Author)
+
+	require.NoError(t, repo.AddAuditEntries([]*model.AuditEntry{
+		{Action: model.AuditActionCreate, SongID: songs[1].ID, RequestID: "batch"},
+		{Action: model.AuditActionUpdate, SongID: songs[1].ID, RequestID: "batch"},
+	}))
+	entries, _ := repo.GetAuditEntries(repository.AuditFilter{RequestID: "batch"})
+	assert.Len(t, entries, 2)
+
+	require.NoError(t, repo.AddOutboxEvents([]*model.OutboxEvent{
+		{EventID: "e1", EventType: "song.created", SongID: songs[1].ID, Payload: "{}"},
+		{EventID: "e2", EventType: "song.updated", SongID: songs[1].ID, Payload: "{}"},
+	}))
+	pending, _ := repo.GetPendingOutboxEvents(10)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "e1", pending[0].EventID, "Events should keep their order")
+}
+
+func testRevisions(t *testing.T, repo repository.SongRepository) {
+	song := &model.Song{GroupName: "Muse", SongName: "Starlight"}
+	require.NoError(t, repo.AddSong(song))
+	other := &model.Song{GroupName: "Queen", SongName: "Bohemian Rhapsody"}
+	require.NoError(t, repo.AddSong(other))
+
+	revs, err := repo.GetRevisions(id(song.ID))
+	require.NoError(t, err)
+	assert.NotNil(t, revs, "An empty list should not be nil")
+
+	for _, name := range []string{"Starlight", "Starlight (Live)", "Starlight (Remix)"} {
+		song.SongName = name
+		require.NoError(t, repo.AddRevision(model.NewSongRevision(song, "alice")))
+	}
+	otherRev := model.NewSongRevision(other, "bob")
+	require.NoError(t, repo.AddRevision(otherRev))
+	assert.Equal(t, 1, otherRev.Revision, "Revisions should be numbered per song")
+
+	revs, err = repo.GetRevisions(id(song.ID))
+	require.NoError(t, err)
+	require.Len(t, revs, 3)
+	for i, rev := range revs {
+		assert.Equal(t, i+1, rev.Revision, "Revisions should be returned oldest first")
+	}
+
+	rev, err := repo.GetRevision(id(song.ID), 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Starlight (Live)", rev.SongName)
+	assert.Equal(t, "alice", rev.Author)
+
+	_, err = repo.GetRevision(id(song.ID), 4)
+	assert.ErrorIs(t, err, repository.ErrRevisionNotFound)
+}
+
+func testTranslations(t *testing.T, repo repository.SongRepository) {
+	song := &model.Song{GroupName: "Muse", SongName: "Starlight", Text: "Far away\n\nMy life"}
+	require.NoError(t, repo.AddSong(song))
+
+	translations, err := repo.GetTranslations(id(song.ID))
+	require.NoError(t, err)
+	assert.NotNil(t, translations, "An empty list should not be nil")
+
+	for _, lang := range []string{"pt-BR", "de"} {
+		require.NoError(t, repo.SaveTranslation(&model.SongTranslation{SongID: song.ID, Language: lang, Verses: []string{"1 " + lang, "2 " + lang}}))
+	}
+	require.NoError(t, repo.SaveTranslation(&model.SongTranslation{SongID: song.ID, Language: "de", Verses: []string{"Weit weg", "Mein Leben"}, Translator: "alice"}))
+
+	translations, err = repo.GetTranslations(id(song.ID))
+	require.NoError(t, err)
+	require.Len(t, translations, 2, "Saving a language again should replace its translation")
+	assert.Equal(t, "de", translations[0].Language, "Translations should be ordered by language")
+	assert.Equal(t, []string{"Weit weg", "Mein Leben"}, translations[0].Verses)
+	assert.Equal(t, "alice", translations[0].Translator)
+
+	translation, err := repo.GetTranslation(id(song.ID), "pt-BR")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1 pt-BR", "2 pt-BR"}, translation.Verses)
+	_, err = repo.GetTranslation(id(song.ID), "fr")
+	assert.ErrorIs(t, err, repository.ErrTranslationNotFound)
+
+	require.NoError(t, repo.DeleteTranslation(id(song.ID), "pt-BR"))
+	assert.ErrorIs(t, repo.DeleteTranslation(id(song.ID), "pt-BR"), repository.ErrTranslationNotFound)
+
+	require.NoError(t, repo.DeleteSong(id(song.ID)))
+	translations, err = repo.GetTranslations(id(song.ID))
+	require.NoError(t, err)
+	assert.Empty(t, translations, "Deleting a song should delete its translations")
+}
+
+func testLinkChecks(t *testing.T, repo repository.SongRepository) {
+	now := time.Now().UTC().Truncate(time.Second)
+	starlight := &model.Song{GroupName: "Muse", SongName: "Starlight", Link: "https://example.com/starlight"}
+	uprising := &model.Song{GroupName: "Muse", SongName: "Uprising", Link: "https://example.com/uprising"}
+	creep := &model.Song{GroupName: "Radiohead", SongName: "Creep", Link: "https://example.com/creep"}
+	unlinked := &model.Song{GroupName: "Radiohead", SongName: "Nude"}
+	for _, song := range []*model.Song{starlight, uprising, creep, unlinked} {
+		require.NoError(t, repo.AddSong(song))
+	}
+
+	_, err := repo.GetLinkCheck(id(starlight.ID))
+	assert.ErrorIs(t, err, repository.ErrLinkCheckNotFound)
+	due, err := repo.GetLinksToCheck(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{starlight.ID, uprising.ID, creep.ID}, songIDs(due), "Unchecked links should be due, songs without a link not")
+
+	require.NoError(t, repo.SaveLinkCheck(&model.LinkCheck{SongID: starlight.ID, URL: starlight.Link, Status: model.LinkOK, StatusCode: 200, CheckedAt: now.Add(-2 * time.Hour)}))
+	require.NoError(t, repo.SaveLinkCheck(&model.LinkCheck{SongID: uprising.ID, URL: uprising.Link, Status: model.LinkBroken, StatusCode: 404, CheckedAt: now}))
+	require.NoError(t, repo.SaveLinkCheck(&model.LinkCheck{SongID: creep.ID, URL: creep.Link, Status: model.LinkOK, StatusCode: 200, CheckedAt: now.Add(-time.Hour)}))
+	require.NoError(t, repo.SaveLinkCheck(&model.LinkCheck{SongID: creep.ID, URL: creep.Link, Status: model.LinkBroken, Error: "timeout", CheckedAt: now.Add(-time.Hour)}))
+
+	check, err := repo.GetLinkCheck(id(creep.ID))
+	require.NoError(t, err)
+	assert.Equal(t, model.LinkBroken, check.Status, "Saving a check again should replace it")
+	assert.Equal(t, "timeout", check.Error)
+
+	due, _ = repo.GetLinksToCheck(now.Add(-30*time.Minute), 10)
+	assert.Equal(t, []uint{starlight.ID, creep.ID}, songIDs(due), "Links checked before the cutoff should be due, oldest first")
+	due, _ = repo.GetLinksToCheck(now.Add(-30*time.Minute), 1)
+	assert.Equal(t, []uint{starlight.ID}, songIDs(due))
+
+	broken, err := repo.ListSongs(repository.SongQuery{BrokenLinks: true})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{uprising.ID, creep.ID}, songIDs(broken))
+
+	uprising.Link = "https://example.com/uprising-live"
+	require.NoError(t, repo.UpdateSong(id(uprising.ID), uprising))
+	due, _ = repo.GetLinksToCheck(now.Add(-30*time.Minute), 10)
+	assert.Equal(t, []uint{uprising.ID, starlight.ID, creep.ID}, songIDs(due), "A changed link should be due like an unchecked one")
+	broken, _ = repo.ListSongs(repository.SongQuery{BrokenLinks: true})
+	assert.Equal(t, []uint{creep.ID}, songIDs(broken), "The check of a previous link should not count")
+
+	require.NoError(t, repo.DeleteSong(id(creep.ID)))
+	_, err = repo.GetLinkCheck(id(creep.ID))
+	assert.ErrorIs(t, err, repository.ErrLinkCheckNotFound, "Deleting a song should delete its link check")
+}
+
+func testAuditLog(t *testing.T, repo repository.SongRepository) {
+	start := time.Now().Add(-time.Hour)
+	entries := []*model.AuditEntry{
+		{Action: model.AuditActionCreate, SongID: 1, Actor: "alice", RequestID: "req-1", CreatedAt: start},
+		{Action: model.AuditActionUpdate, SongID: 1, Actor: "bob", RequestID: "req-2", CreatedAt: start.Add(time.Minute)},
+		{Action: model.AuditActionCreate, SongID: 2, Actor: "alice", RequestID: "req-3", CreatedAt: start.Add(2 * time.Minute)},
+		{Action: model.AuditActionDelete, SongID: 1, Actor: "alice", RequestID: "req-4", CreatedAt: start.Add(3 * time.Minute)},
+	}
+	for _, entry := range entries {
+		require.NoError(t, repo.AddAuditEntry(entry))
+	}
+
+	all, err := repo.GetAuditEntries(repository.AuditFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"req-4", "req-3", "req-2", "req-1"}, requestIDs(all), "Entries should be newest first")
+
+	bySong, _ := repo.GetAuditEntries(repository.AuditFilter{SongID: "1", Actor: "alice"})
+	assert.Equal(t, []string{"req-4", "req-1"}, requestIDs(bySong))
+
+	byAction, _ := repo.GetAuditEntries(repository.AuditFilter{Action: model.AuditActionCreate})
+	assert.Equal(t, []string{"req-3", "req-1"}, requestIDs(byAction))
+
+	window, _ := repo.GetAuditEntries(repository.AuditFilter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)})
+	assert.Equal(t, []string{"req-3", "req-2"}, requestIDs(window), "Since is inclusive and Until exclusive")
+
+	paged, _ := repo.GetAuditEntries(repository.AuditFilter{Limit: 2, Offset: 1})
+	assert.Equal(t, []string{"req-3", "req-2"}, requestIDs(paged))
+
+	negative, err := repo.GetAuditEntries(repository.AuditFilter{Limit: 2, Offset: -1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"req-4", "req-3"}, requestIDs(negative), "A negative offset should start at the first entry")
+
+	none, err := repo.GetAuditEntries(repository.AuditFilter{RequestID: "missing"})
+	require.NoError(t, err)
+	assert.NotNil(t, none, "An empty list should not be nil")
+}
+
+func testOutbox(t *testing.T, repo repository.SongRepository) {
+	for i, songID := range []uint{1, 2, 1} {
+		event := &model.OutboxEvent{
+			EventID:   "event-" + strconv.Itoa(i),
+			EventType: "song.updated",
+			SongID:    songID,
+			Payload:   "{}",
+		}
+		require.NoError(t, repo.AddOutboxEvent(event))
+	}
+	assert.Error(t, repo.AddOutboxEvent(&model.OutboxEvent{EventID: "event-0", EventType: "song.updated", SongID: 1, Payload: "{}"}),
+		"Event IDs should be unique")
+
+	pending, err := repo.GetPendingOutboxEvents(10)
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	assert.Equal(t, "event-0", pending[0].EventID, "Events should be returned in write order")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repo.MarkOutboxEventPublished(pending[0].ID, now))
+	require.NoError(t, repo.MarkOutboxEventFailed(pending[1].ID, "broker unavailable", now.Add(time.Minute)))
+
+	pending, err = repo.GetPendingOutboxEvents(1)
+	require.NoError(t, err)
+	require.Len(t, pending, 1, "The limit should be applied")
+	assert.Equal(t, "event-1", pending[0].EventID, "Published events should no longer be pending")
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "broker unavailable", pending[0].LastError)
+
+	due, err := repo.GetDueOutboxEvents(now, 10, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"event-2"}, outboxEventIDs(due), "Songs waiting for a retry should be left out")
+	due, _ = repo.GetDueOutboxEvents(now.Add(time.Minute), 10, 10)
+	assert.Equal(t, []string{"event-1", "event-2"}, outboxEventIDs(due), "Failed events should be retried once due")
+
+	for i := 3; i < 6; i++ {
+		require.NoError(t, repo.AddOutboxEvent(&model.OutboxEvent{EventID: "event-" + strconv.Itoa(i), EventType: "song.updated", SongID: 1, Payload: "{}"}))
+	}
+	due, _ = repo.GetDueOutboxEvents(now, 2, 10)
+	assert.Equal(t, []string{"event-2", "event-3"}, outboxEventIDs(due), "A batch should hold at most perSong events of a song")
+
+	require.NoError(t, repo.MarkOutboxEventDead(pending[0].ID, "rejected", now))
+	pending, _ = repo.GetPendingOutboxEvents(10)
+	assert.Equal(t, []string{"event-2", "event-3", "event-4", "event-5"}, outboxEventIDs(pending), "Dead events should no longer be pending")
+	due, _ = repo.GetDueOutboxEvents(now, 10, 10)
+	assert.Equal(t, []string{"event-2", "event-3", "event-4", "event-5"}, outboxEventIDs(due), "A dead event should not hold back its song")
+}
+
+func outboxEventIDs(events []model.OutboxEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.EventID
+	}
+	return ids
+}
+
+func testTransaction(t *testing.T, repo repository.SongRepository) {
+	errRollback := errors.New("rollback")
+	err := repo.Transaction(func(tx repository.SongRepository) error {
+		song := &model.Song{GroupName: "Muse", SongName: "Starlight"}
+		if err := tx.AddSong(song); err != nil {
+			return err
+		}
+		if err := tx.AddRevision(model.NewSongRevision(song, "alice")); err != nil {
+			return err
+		}
+		_, err := tx.GetSongByID(id(song.ID))
+		assert.NoError(t, err, "Writes should be visible inside the transaction")
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	songs, _ := repo.GetSongs()
+	assert.Empty(t, songs, "A failed transaction should be rolled back")
+
+	var committed model.Song
+	err = repo.Transaction(func(tx repository.SongRepository) error {
+		committed = model.Song{GroupName: "Muse", SongName: "Uprising"}
+		if err := tx.AddSong(&committed); err != nil {
+			return err
+		}
+		return tx.AddRevision(model.NewSongRevision(&committed, "alice"))
+	})
+	require.NoError(t, err)
+	_, err = repo.GetSongByID(id(committed.ID))
+	assert.NoError(t, err, "A successful transaction should be committed")
+	revs, _ := repo.GetRevisions(id(committed.ID))
+	assert.Len(t, revs, 1)
+
+	event := &model.OutboxEvent{EventID: "tx-event", EventType: "song.created", SongID: committed.ID, Payload: "{}"}
+	require.NoError(t, repo.AddOutboxEvent(event))
+	err = repo.Transaction(func(tx repository.SongRepository) error {
+		if err := tx.AddRevision(model.NewSongRevision(&committed, "bob")); err != nil {
+			return err
+		}
+		if err := tx.MarkOutboxEventFailed(event.ID, "boom", time.Now()); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	require.NoError(t, repo.AddRevision(model.NewSongRevision(&committed, "carol")))
+	revs, _ = repo.GetRevisions(id(committed.ID))
+	if assert.Len(t, revs, 2, "Rolled back revisions should not be kept") {
+		assert.ElementsMatch(t, []string{"alice", "carol"}, []string{revs[0].Author, revs[1].Author})
+	}
+	events, _ := repo.GetPendingOutboxEvents(10)
+	if assert.Len(t, events, 1) {
+		assert.Zero(t, events[0].Attempts, "Rolled back outbox updates should not be kept")
+	}
+}
+
+func testWebhooks(t *testing.T, repo repository.WebhookRepository) {
+	webhook := &model.Webhook{URL: "https://example.com/hook", Secret: "s3cret", Events: model.StringList{"song.created", "song.deleted"}, Active: true}
+	require.NoError(t, repo.AddWebhook(webhook))
+	assert.NotZero(t, webhook.ID)
+	require.NoError(t, repo.AddWebhook(&model.Webhook{URL: "https://example.com/other", Secret: "x", Events: model.StringList{"song.updated"}, Active: true}))
+
+	got, err := repo.GetWebhookByID(id(webhook.ID))
+	require.NoError(t, err)
+	assert.Equal(t, model.StringList{"song.created", "song.deleted"}, got.Events)
+
+	got.Active = false
+	require.NoError(t, repo.SaveWebhook(got))
+	webhooks, err := repo.GetWebhooks()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	assert.Equal(t, webhook.ID, webhooks[0].ID, "Webhooks should be ordered by ID")
+	assert.False(t, webhooks[0].Active, "SaveWebhook should write false values")
+
+	delivery := &model.WebhookDelivery{WebhookID: webhook.ID, EventID: "e1", EventType: "song.created", Payload: "{}", Status: model.DeliveryPending, NextAttemptAt: time.Now()}
+	require.NoError(t, repo.AddDelivery(delivery))
+	require.NoError(t, repo.DeleteWebhook(id(webhook.ID)))
+	_, err = repo.GetWebhookByID(id(webhook.ID))
+	assert.ErrorIs(t, err, repository.ErrWebhookNotFound)
+	_, err = repo.GetDeliveryByID(id(delivery.ID))
+	assert.ErrorIs(t, err, repository.ErrDeliveryNotFound, "Deliveries should be deleted with their webhook")
+	assert.ErrorIs(t, repo.DeleteWebhook(id(webhook.ID)), repository.ErrWebhookNotFound)
+
+	inactive := &model.Webhook{URL: "https://example.com/paused", Secret: "x", Events: model.StringList{"song.created"}, Active: false}
+	require.NoError(t, repo.AddWebhook(inactive))
+	got, err = repo.GetWebhookByID(id(inactive.ID))
+	require.NoError(t, err)
+	assert.False(t, got.Active, "AddWebhook should store inactive webhooks as inactive")
+}
+
+func testDeliveries(t *testing.T, repo repository.WebhookRepository) {
+	now := time.Now()
+	deliveries := []*model.WebhookDelivery{
+		{WebhookID: 1, EventID: "e1", Status: model.DeliveryPending, NextAttemptAt: now.Add(-time.Minute)},
+		{WebhookID: 1, EventID: "e2", Status: model.DeliveryPending, NextAttemptAt: now.Add(time.Minute)},
+		{WebhookID: 2, EventID: "e3", Status: model.DeliveryPending, NextAttemptAt: now.Add(-2 * time.Minute)},
+		{WebhookID: 1, EventID: "e4", Status: model.DeliveryDead, NextAttemptAt: now.Add(-time.Hour)},
+	}
+	for _, d := range deliveries {
+		d.EventType, d.Payload = "song.created", "{}"
+		require.NoError(t, repo.AddDelivery(d))
+	}
+
+	again := &model.WebhookDelivery{WebhookID: 1, EventID: "e1", EventType: "song.created", Payload: "{}", Status: model.DeliveryPending, NextAttemptAt: now}
+	require.NoError(t, repo.AddDelivery(again), "Queuing an event twice should not fail")
+	assert.Zero(t, again.ID, "A webhook should not get an event twice")
+
+	due, err := repo.GetDueDeliveries(now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e3", "e1"}, eventIDs(due), "Due deliveries should be ordered by next attempt")
+
+	due, _ = repo.GetDueDeliveries(now, 1)
+	assert.Len(t, due, 1, "The limit should be applied")
+
+	forWebhook, err := repo.GetDeliveries("1", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e4", "e2", "e1"}, eventIDs(forWebhook), "Deliveries should be newest first")
+	dead, _ := repo.GetDeliveries("", model.DeliveryDead)
+	assert.Equal(t, []string{"e4"}, eventIDs(dead))
+
+	deliveries[0].Status = model.DeliverySucceeded
+	deliveries[0].Attempts = 1
+	require.NoError(t, repo.SaveDelivery(deliveries[0]))
+	got, err := repo.GetDeliveryByID(id(deliveries[0].ID))
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliverySucceeded, got.Status)
+	assert.Equal(t, 1, got.Attempts)
+}
+
+func testIdempotencyKeys(t *testing.T, repo repository.IdempotencyRepository) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	key := &model.IdempotencyKey{Key: "bob:k1", Fingerprint: "f1", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}
+	require.NoError(t, repo.AddIdempotencyKey(key))
+	err := repo.AddIdempotencyKey(&model.IdempotencyKey{Key: "bob:k1", Fingerprint: "f2", CreatedAt: now, ExpiresAt: now.Add(time.Minute)})
+	assert.ErrorIs(t, err, repository.ErrIdempotencyKeyExists)
+
+	got, err := repo.GetIdempotencyKey("bob:k1", now)
+	require.NoError(t, err)
+	assert.Equal(t, "f1", got.Fingerprint)
+	assert.False(t, got.Completed())
+
+	key.Status, key.ContentType, key.Body = 201, "application/json", []byte(`{"id":1}`)
+	key.ExpiresAt = now.Add(time.Hour)
+	require.NoError(t, repo.SaveIdempotencyKey(key))
+	got, err = repo.GetIdempotencyKey("bob:k1", now.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 201, got.Status)
+	assert.Equal(t, `{"id":1}`, string(got.Body))
+
+	_, err = repo.GetIdempotencyKey("bob:k1", now.Add(time.Hour))
+	assert.ErrorIs(t, err, repository.ErrIdempotencyKeyNotFound, "Expired keys should not be returned")
+	later := now.Add(2 * time.Hour)
+	require.NoError(t, repo.AddIdempotencyKey(&model.IdempotencyKey{Key: "bob:k1", Fingerprint: "f3", CreatedAt: later, ExpiresAt: later.Add(time.Minute)}),
+		"An expired key should be reusable")
+
+	require.NoError(t, repo.AddIdempotencyKey(&model.IdempotencyKey{Key: "bob:k2", Fingerprint: "f1", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}))
+	n, err := repo.DeleteExpiredIdempotencyKeys(later)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = repo.GetIdempotencyKey("bob:k1", later)
+	assert.NoError(t, err, "Unexpired keys should be kept")
+
+	require.NoError(t, repo.DeleteIdempotencyKey("bob:k1"))
+	_, err = repo.GetIdempotencyKey("bob:k1", later)
+	assert.ErrorIs(t, err, repository.ErrIdempotencyKeyNotFound)
+}
+
+func songIDs(songs []model.Song) []uint {
+	ids := make([]uint, 0, len(songs))
+	for _, song := range songs {
+		ids = append(ids, song.ID)
+	}
+	return ids
+}
+
+func requestIDs(entries []model.AuditEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.RequestID)
+	}
+	return ids
+}
+
+func eventIDs(deliveries []model.WebhookDelivery) []string {
+	ids := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		ids = append(ids, d.EventID)
+	}
+	return ids
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"song-library/internal/model"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// AddRevision stores rev under the next revision number of its song
+func (r *songRepository) AddRevision(rev *model.SongRevision) error {
+	var last int
+	err := r.db.Model(&model.SongRevision{}).
+		Where("song_id = ?", rev.SongID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	rev.Revision = last + 1
+	return r.db.Create(rev).Error
+}
+
+func (r *songRepository) AddRevisions(revs []*model.SongRevision) error {
+	if len(revs) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(revs))
+	for _, rev := range revs {
+		ids = append(ids, rev.SongID)
+	}
+	var rows []struct {
+		SongID uint
+		Last   int
+	}
+	err := r.db.Model(&model.SongRevision{}).
+		Select("song_id, MAX(revision) AS last").
+		Where("song_id IN ?", ids).
+		Group("song_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	last := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		last[row.SongID] = row.Last
+	}
+	for _, rev := range revs {
+		last[rev.SongID]++
+		rev.Revision = last[rev.SongID]
+	}
+	return r.db.Create(&revs).Error
+}
+
+// GetRevisions returns the revisions of a song, oldest first
+func (r *songRepository) GetRevisions(songID string) ([]model.SongRevision, error) {
+	var revs []model.SongRevision
+	if err := r.db.Where("song_id = ?", songID).Order("revision").Find(&revs).Error; err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
+
+func (r *songRepository) GetRevision(songID string, revision int) (*model.SongRevision, error) {
+	var rev model.SongRevision
+	err := r.db.First(&rev, "song_id = ? AND revision = ?", songID, revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *songRepository) GetLatestRevisions(songIDs []string) ([]model.SongRevision, error) {
+	revs := []model.SongRevision{}
+	if len(songIDs) == 0 {
+		return revs, nil
+	}
+	err := r.db.
+		Where("song_id IN ? AND revision = (SELECT MAX(latest.revision) FROM song_revisions latest WHERE latest.song_id = song_revisions.song_id)", songIDs).
+		Order("song_id").
+		Find(&revs).Error
+	if err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
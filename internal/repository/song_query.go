@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"song-library/internal/model"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidSort is returned for a sort that names an unknown column
+var ErrInvalidSort = errors.New("invalid sort")
+
+// SortableSongColumns lists the columns songs can be sorted by
+var SortableSongColumns = []string{"id", "group_name", "song_name", "release_date", "created_at", "updated_at"}
+
+// songColumn is a column songs can be sorted by
+type songColumn struct {
+	compare func(a, b *model.Song) int
+	value   func(song *model.Song) interface{}
+}
+
+// songColumns are the sortable columns by name
+var songColumns = map[string]songColumn{
+	"id": {
+		compare: func(a, b *model.Song) int { return cmp.Compare(a.ID, b.ID) },
+		value:   func(song *model.Song) interface{} { return song.ID },
+	},
+	"group_name": {
+		compare: func(a, b *model.Song) int { return strings.Compare(a.GroupName, b.GroupName) },
+		value:   func(song *model.Song) interface{} { return song.GroupName },
+	},
+	"song_name": {
+		compare: func(a, b *model.Song) int { return strings.Compare(a.SongName, b.SongName) },
+		value:   func(song *model.Song) interface{} { return song.SongName },
+	},
+	"release_date": {
+		compare: func(a, b *model.Song) int { return a.ReleaseDate.Compare(b.ReleaseDate) },
+		value:   func(song *model.Song) interface{} { return song.ReleaseDate },
+	},
+	"created_at": {
+		compare: func(a, b *model.Song) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		value:   func(song *model.Song) interface{} { return song.CreatedAt },
+	},
+	"updated_at": {
+		compare: func(a, b *model.Song) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+		value:   func(song *model.Song) interface{} { return song.UpdatedAt },
+	},
+}
+
+// SortField is one column of a sort order
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// SongSort is the order of a song listing. It always ends with the ID, so
+// that songs with equal values keep a stable order between pages.
+type SongSort []SortField
+
+// ParseSongSort parses a comma separated list of columns, each optionally
+// prefixed with "-" for descending order, such as "release_date,-song_name".
+// An empty string sorts by ID.
+func ParseSongSort(s string) (SongSort, error) {
+	var sort SongSort
+	seen := make(map[string]bool)
+	if s != "" {
+		for _, part := range strings.Split(s, ",") {
+			field := SortField{Column: strings.TrimSpace(part)}
+			if strings.HasPrefix(field.Column, "-") {
+				field.Column, field.Desc = field.Column[1:], true
+			}
+			if _, ok := songColumns[field.Column]; !ok {
+				return nil, errors.Wrapf(ErrInvalidSort, "cannot sort by %q, use one of %s", part, strings.Join(SortableSongColumns, ", "))
+			}
+			if seen[field.Column] {
+				return nil, errors.Wrapf(ErrInvalidSort, "%q is sorted by twice", field.Column)
+			}
+			seen[field.Column] = true
+			sort = append(sort, field)
+		}
+	}
+	if !seen["id"] {
+		sort = append(sort, SortField{Column: "id"})
+	}
+	return sort, nil
+}
+
+// String formats the sort the way ParseSongSort accepts it
+func (s SongSort) String() string {
+	parts := make([]string, len(s))
+	for i, field := range s {
+		parts[i] = field.Column
+		if field.Desc {
+			parts[i] = "-" + field.Column
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Reverse returns the sort with every direction flipped
+func (s SongSort) Reverse() SongSort {
+	reversed := make(SongSort, len(s))
+	for i, field := range s {
+		reversed[i] = SortField{Column: field.Column, Desc: !field.Desc}
+	}
+	return reversed
+}
+
+// orderBy returns the sort as an SQL ORDER BY list. Columns come from the
+// allowlist, so they are safe to put in the query.
+func (s SongSort) orderBy() string {
+	parts := make([]string, len(s))
+	for i, field := range s {
+		parts[i] = field.Column
+		if field.Desc {
+			parts[i] += " DESC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// after returns an SQL condition and its arguments that select the songs
+// coming after song in the sort order. When all columns share a direction
+// it is a single row comparison, which the sort indexes can serve.
+func (s SongSort) after(song *model.Song) (string, []interface{}) {
+	columns := make([]string, len(s))
+	values := make([]interface{}, len(s))
+	uniform := true
+	for i, field := range s {
+		columns[i] = field.Column
+		values[i] = songColumns[field.Column].value(song)
+		uniform = uniform && field.Desc == s[0].Desc
+	}
+	if uniform {
+		op := ">"
+		if s[0].Desc {
+			op = "<"
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(s)), ", ")
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, placeholders), values
+	}
+
+	// (a > ?) OR (a = ? AND b < ?) OR ...
+	var terms []string
+	var args []interface{}
+	for i, field := range s {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, columns[j]+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if field.Desc {
+			op = " < ?"
+		}
+		conds = append(conds, columns[i]+op)
+		args = append(args, values[i])
+		terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// compare orders a before b when it returns a negative number
+func (s SongSort) compare(a, b *model.Song) int {
+	for _, field := range s {
+		c := songColumns[field.Column].compare(a, b)
+		if field.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// SongQuery selects songs for a listing
+type SongQuery struct {
+	// Language, when set, selects the songs whose lyrics are in this
+	// language
+	Language string
+	// BrokenLinks, when set, selects the songs whose link failed its latest
+	// check
+	BrokenLinks bool
+	// GroupName, SongName and Text, when set, select the songs whose
+	// artist, title or lyrics contain them, ignoring case. They also match
+	// the Latin transliteration of Cyrillic and Greek text.
+	GroupName string
+	SongName  string
+	Text      string
+	// ReleasedAfter and ReleasedBefore, when set, select the songs released
+	// strictly after or before them
+	ReleasedAfter  time.Time
+	ReleasedBefore time.Time
+	// Sort orders the songs; nil sorts by ID
+	Sort SongSort
+	// After, when set, skips the songs up to and including this one in
+	// Sort order. Only its sort columns are read, so it can be a song that
+	// no longer exists.
+	After *model.Song
+	// Limit caps the number of songs; 0 means no limit
+	Limit int
+}
+
+// likeEscaper escapes the LIKE wildcards of a substring, with \ as the
+// escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern returns the LIKE pattern of the search columns that contain
+// sub
+func likePattern(sub string) string {
+	return "%" + likeEscaper.Replace(strings.ToLower(sub)) + "%"
+}
+
+// matches reports whether song passes the text and release date filters of
+// q
+func (q SongQuery) matches(song *model.Song) bool {
+	contains := func(search, sub string) bool {
+		return sub == "" || strings.Contains(search, strings.ToLower(sub))
+	}
+	if !contains(song.SearchGroupName, q.GroupName) || !contains(song.SearchSongName, q.SongName) || !contains(song.SearchText, q.Text) {
+		return false
+	}
+	if !q.ReleasedAfter.IsZero() && !song.ReleaseDate.After(q.ReleasedAfter) {
+		return false
+	}
+	if !q.ReleasedBefore.IsZero() && !song.ReleaseDate.Before(q.ReleasedBefore) {
+		return false
+	}
+	return true
+}
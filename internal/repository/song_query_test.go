@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSongSort(t *testing.T) {
+	sort, err := ParseSongSort("release_date, -song_name")
+	assert.Nil(t, err)
+	assert.Equal(t, SongSort{{Column: "release_date"}, {Column: "song_name", Desc: true}, {Column: "id"}}, sort, "The ID should break ties")
+	assert.Equal(t, "release_date,-song_name,id", sort.String())
+	assert.Equal(t, "release_date, song_name DESC, id", sort.orderBy())
+
+	sort, _ = ParseSongSort("-id")
+	assert.Equal(t, SongSort{{Column: "id", Desc: true}}, sort, "An explicit ID should not be repeated")
+	sort, _ = ParseSongSort("")
+	assert.Equal(t, SongSort{{Column: "id"}}, sort)
+
+	for _, s := range []string{"text", "release_date;DROP TABLE songs", "song_name,-song_name", ",", "-"} {
+		_, err := ParseSongSort(s)
+		assert.ErrorIs(t, err, ErrInvalidSort, "%q should be rejected", s)
+	}
+}
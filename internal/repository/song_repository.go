@@ -1,18 +1,125 @@
 package repository
 
 import (
+	"context"
+	"song-library/internal/dedupe"
+	"song-library/internal/language"
 	"song-library/internal/model"
+	"time"
 
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrSongNotFound is returned when no song matches the requested ID
+	ErrSongNotFound = errors.New("song not found")
+	// ErrRevisionNotFound is returned when a song has no such revision
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrTranslationNotFound is returned when a song has no translation
+	// into the requested language
+	ErrTranslationNotFound = errors.New("translation not found")
+	// ErrLinkCheckNotFound is returned when a song's link was never checked
+	ErrLinkCheckNotFound = errors.New("link check not found")
+	// ErrDuplicateSong is returned when another song has the same
+	// normalized artist and title
+	ErrDuplicateSong = errors.New("song already exists")
+)
+
 // SongRepository defines methods for interacting with the songs database
 type SongRepository interface {
 	GetSongs() ([]model.Song, error)
+	// ListSongs returns the songs selected by query in its sort order
+	ListSongs(query SongQuery) ([]model.Song, error)
+	// CountSongs returns the number of songs selected by query, ignoring
+	// its After and Limit
+	CountSongs(query SongQuery) (int, error)
+	// ListArtists returns up to limit distinct artists in alphabetical
+	// order, starting after the given one; 0 means no limit
+	ListArtists(after string, limit int) ([]string, error)
+	CountArtists() (int, error)
 	GetSongByID(id string) (*model.Song, error)
+	GetSongsByIDs(ids []string) ([]model.Song, error)
+	GetSongsByGroups(groups []string) ([]model.Song, error)
+	// GetSongByKey returns the song with the given normalized key
+	GetSongByKey(key string) (*model.Song, error)
+	// GetSongsByKeys returns the songs with the given normalized keys in a
+	// single query
+	GetSongsByKeys(keys []string) ([]model.Song, error)
 	AddSong(song *model.Song) error
 	UpdateSong(id string, song *model.Song) error
+	SaveSong(song *model.Song) error
 	DeleteSong(id string) error
+	// AddSongs, SaveSongs and DeleteSongs write many songs with one
+	// statement each
+	AddSongs(songs []*model.Song) error
+	SaveSongs(songs []*model.Song) error
+	DeleteSongs(ids []string) error
+
+	AddRevision(rev *model.SongRevision) error
+	// AddRevisions stores revs under the next revision numbers of their
+	// songs, in order
+	AddRevisions(revs []*model.SongRevision) error
+	GetRevisions(songID string) ([]model.SongRevision, error)
+	GetRevision(songID string, revision int) (*model.SongRevision, error)
+	// GetLatestRevisions returns the newest revision of each of the songs
+	// that has any
+	GetLatestRevisions(songIDs []string) ([]model.SongRevision, error)
+
+	// GetTranslations returns the translations of a song ordered by
+	// language
+	GetTranslations(songID string) ([]model.SongTranslation, error)
+	GetTranslation(songID, lang string) (*model.SongTranslation, error)
+	// SaveTranslation creates the translation or replaces the one of the
+	// same song and language
+	SaveTranslation(translation *model.SongTranslation) error
+	DeleteTranslation(songID, lang string) error
+
+	GetLinkCheck(songID string) (*model.LinkCheck, error)
+	// SaveLinkCheck creates or replaces the link check of a song
+	SaveLinkCheck(check *model.LinkCheck) error
+	// GetLinksToCheck returns up to limit songs with a link that was never
+	// checked, changed since, or was last checked before checkedBefore
+	GetLinksToCheck(checkedBefore time.Time, limit int) ([]model.Song, error)
+
+	AddAuditEntry(entry *model.AuditEntry) error
+	AddAuditEntries(entries []*model.AuditEntry) error
+	GetAuditEntries(filter AuditFilter) ([]model.AuditEntry, error)
+
+	AddOutboxEvent(event *model.OutboxEvent) error
+	AddOutboxEvents(events []*model.OutboxEvent) error
+	GetPendingOutboxEvents(limit int) ([]model.OutboxEvent, error)
+	// GetDueOutboxEvents returns up to limit pending events in write order,
+	// at most perSong of each song. Songs with a failed event that is not
+	// due for a retry at now are left out.
+	GetDueOutboxEvents(now time.Time, perSong, limit int) ([]model.OutboxEvent, error)
+	MarkOutboxEventPublished(id uint, at time.Time) error
+	// MarkOutboxEventFailed records a failed attempt and delays the next
+	// one until retryAt
+	MarkOutboxEventFailed(id uint, reason string, retryAt time.Time) error
+	// MarkOutboxEventDead records the last failed attempt of an event
+	MarkOutboxEventDead(id uint, reason string, at time.Time) error
+
+	// Transaction runs fn inside a database transaction. The repository
+	// passed to fn is bound to the transaction; returning an error from fn
+	// rolls it back.
+	Transaction(fn func(repo SongRepository) error) error
+}
+
+// contextBinder is implemented by repositories whose queries can carry a
+// request context
+type contextBinder interface {
+	WithContext(ctx context.Context) SongRepository
+}
+
+// WithContext returns repo with its queries bound to ctx, so that they are
+// logged with the request ID, route and user. Repositories without
+// database queries are returned as is.
+func WithContext(repo SongRepository, ctx context.Context) SongRepository {
+	if binder, ok := repo.(contextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+	return repo
 }
 
 // songRepository implements SongRepository
@@ -25,30 +132,244 @@ func NewSongRepository(db *gorm.DB) SongRepository {
 	return &songRepository{db: db}
 }
 
+func (r *songRepository) WithContext(ctx context.Context) SongRepository {
+	return &songRepository{db: r.db.WithContext(ctx)}
+}
+
 func (r *songRepository) GetSongs() ([]model.Song, error) {
 	var songs []model.Song
-	if err := r.db.Find(&songs).Error; err != nil {
+	if err := r.db.Order("id").Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	return songs, nil
+}
+
+func (r *songRepository) ListSongs(query SongQuery) ([]model.Song, error) {
+	if query.Sort == nil {
+		query.Sort = SongSort{{Column: "id"}}
+	}
+	tx := r.where(query).Order(query.Sort.orderBy())
+	if query.After != nil {
+		cond, args := query.Sort.after(query.After)
+		tx = tx.Where(cond, args...)
+	}
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+	var songs []model.Song
+	if err := tx.Find(&songs).Error; err != nil {
 		return nil, err
 	}
 	return songs, nil
 }
 
+func (r *songRepository) CountSongs(query SongQuery) (int, error) {
+	var count int64
+	if err := r.where(query).Model(&model.Song{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// where selects the songs matching the filters of query
+func (r *songRepository) where(query SongQuery) *gorm.DB {
+	tx := r.db
+	if query.Language != "" {
+		tx = tx.Where("language = ?", query.Language)
+	}
+	if query.BrokenLinks {
+		tx = tx.Where("EXISTS (SELECT 1 FROM link_checks WHERE link_checks.song_id = songs.id AND link_checks.url = songs.link AND link_checks.status = ?)", model.LinkBroken)
+	}
+	for _, f := range []struct{ column, sub string }{
+		{"search_group_name", query.GroupName},
+		{"search_song_name", query.SongName},
+		{"search_text", query.Text},
+	} {
+		if f.sub != "" {
+			tx = tx.Where(f.column+` LIKE ? ESCAPE '\'`, likePattern(f.sub))
+		}
+	}
+	if !query.ReleasedAfter.IsZero() {
+		tx = tx.Where("release_date > ?", query.ReleasedAfter)
+	}
+	if !query.ReleasedBefore.IsZero() {
+		tx = tx.Where("release_date < ?", query.ReleasedBefore)
+	}
+	return tx
+}
+
+func (r *songRepository) ListArtists(after string, limit int) ([]string, error) {
+	tx := r.db.Model(&model.Song{}).Distinct("group_name").Order("group_name")
+	if after != "" {
+		tx = tx.Where("group_name > ?", after)
+	}
+	if limit > 0 {
+		tx = tx.Limit(limit)
+	}
+	names := []string{}
+	if err := tx.Pluck("group_name", &names).Error; err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (r *songRepository) CountArtists() (int, error) {
+	var count int64
+	if err := r.db.Model(&model.Song{}).Distinct("group_name").Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
 func (r *songRepository) GetSongByID(id string) (*model.Song, error) {
 	var song model.Song
 	if err := r.db.First(&song, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSongNotFound
+		}
+		return nil, err
+	}
+	return &song, nil
+}
+
+// GetSongsByIDs returns the songs with the given IDs; missing IDs are skipped
+func (r *songRepository) GetSongsByIDs(ids []string) ([]model.Song, error) {
+	var songs []model.Song
+	if err := r.db.Where("id IN ?", ids).Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	return songs, nil
+}
+
+// GetSongsByGroups returns the songs of the given groups
+func (r *songRepository) GetSongsByGroups(groups []string) ([]model.Song, error) {
+	var songs []model.Song
+	if err := r.db.Where("group_name IN ?", groups).Order("id").Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	return songs, nil
+}
+
+func (r *songRepository) GetSongByKey(key string) (*model.Song, error) {
+	var song model.Song
+	if err := r.db.First(&song, "normalized_key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSongNotFound
+		}
 		return nil, err
 	}
 	return &song, nil
 }
 
+func (r *songRepository) GetSongsByKeys(keys []string) ([]model.Song, error) {
+	songs := []model.Song{}
+	if len(keys) == 0 {
+		return songs, nil
+	}
+	if err := r.db.Where("normalized_key IN ?", keys).Order("id").Find(&songs).Error; err != nil {
+		return nil, err
+	}
+	return songs, nil
+}
+
 func (r *songRepository) AddSong(song *model.Song) error {
-	return r.db.Create(song).Error
+	setDerived(song)
+	return translateError(r.db.Create(song).Error)
 }
 
 func (r *songRepository) UpdateSong(id string, song *model.Song) error {
-	return r.db.Model(&model.Song{}).Where("id = ?", id).Updates(song).Error
+	setSearch(song)
+	return translateError(r.db.Model(&model.Song{}).Where("id = ?", id).Updates(song).Error)
+}
+
+// SaveSong writes all fields of song, including zero values
+func (r *songRepository) SaveSong(song *model.Song) error {
+	setDerived(song)
+	return translateError(r.db.Save(song).Error)
+}
+
+func (r *songRepository) AddSongs(songs []*model.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	for _, song := range songs {
+		setDerived(song)
+	}
+	return translateError(r.db.Create(&songs).Error)
+}
+
+// SaveSongs writes all fields of existing songs, like SaveSong
+func (r *songRepository) SaveSongs(songs []*model.Song) error {
+	if len(songs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, song := range songs {
+		setDerived(song)
+		song.UpdatedAt = now
+	}
+	return translateError(r.db.Save(&songs).Error)
+}
+
+func (r *songRepository) DeleteSongs(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := r.db.Delete(&model.SongTranslation{}, "song_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	if err := r.db.Delete(&model.LinkCheck{}, "song_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	return r.db.Delete(&model.Song{}, "id IN ?", ids).Error
+}
+
+// setDerived derives the normalized key of song from its artist and title,
+// the language from its text and the search columns from all three
+func setDerived(song *model.Song) {
+	song.NormalizedKey = dedupe.Key(song.GroupName, song.SongName)
+	song.Language = language.Detect(song.Text)
+	song.SearchGroupName = language.Searchable(song.GroupName)
+	song.SearchSongName = language.Searchable(song.SongName)
+	song.SearchText = language.Searchable(song.Text)
 }
 
+// setSearch derives the search columns of the non-empty fields of a
+// partial update
+func setSearch(song *model.Song) {
+	if song.GroupName != "" {
+		song.SearchGroupName = language.Searchable(song.GroupName)
+	}
+	if song.SongName != "" {
+		song.SearchSongName = language.Searchable(song.SongName)
+	}
+	if song.Text != "" {
+		song.SearchText = language.Searchable(song.Text)
+	}
+}
+
+// translateError maps unique key violations on songs to ErrDuplicateSong
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicateSong
+	}
+	return err
+}
+
+// DeleteSong deletes the song, its translations and its link check.
+// Revisions are kept as the history of the deleted song.
 func (r *songRepository) DeleteSong(id string) error {
+	if err := r.db.Delete(&model.SongTranslation{}, "song_id = ?", id).Error; err != nil {
+		return err
+	}
+	if err := r.db.Delete(&model.LinkCheck{}, "song_id = ?", id).Error; err != nil {
+		return err
+	}
 	return r.db.Delete(&model.Song{}, "id = ?", id).Error
 }
+
+func (r *songRepository) Transaction(fn func(repo SongRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&songRepository{db: tx})
+	})
+}
@@ -1,8 +1,13 @@
 package repository
 
 import (
+	"song-library/internal/cache"
+	"song-library/internal/db"
 	"song-library/internal/model"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -11,9 +16,9 @@ import (
 
 // setupTestRepository creates an in-memory database and returns a SongRepository
 func setupTestRepository() SongRepository {
-	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&model.Song{})
-	return NewSongRepository(db)
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
+	return NewSongRepository(conn)
 }
 
 func TestSongRepository_AddSong(t *testing.T) {
@@ -74,3 +79,146 @@ func TestSongRepository_DeleteSong(t *testing.T) {
 	songs, _ := repo.GetSongs()
 	assert.Len(t, songs, 0, "The database should be empty after deletion")
 }
+
+func TestSongRepository_AddRevision(t *testing.T) {
+	repo := setupTestRepository()
+
+	song := &model.Song{GroupName: "Muse", SongName: "Supermassive Black Hole"}
+	repo.AddSong(song)
+	repo.AddRevision(model.NewSongRevision(song, "alice"))
+	repo.AddRevision(model.NewSongRevision(song, "bob"))
+
+	revs, err := repo.GetRevisions("1")
+	assert.Nil(t, err, "Fetching revisions should not return an error")
+	assert.Len(t, revs, 2, "There should be two revisions")
+	assert.Equal(t, 2, revs[1].Revision, "Revisions should be numbered per song")
+
+	_, err = repo.GetRevision("1", 3)
+	assert.ErrorIs(t, err, ErrRevisionNotFound, "Missing revision should return ErrRevisionNotFound")
+}
+
+// countingRepository counts GetSongByID calls that reach the database
+type countingRepository struct {
+	SongRepository
+	calls int32
+}
+
+func (r *countingRepository) GetSongByID(id string) (*model.Song, error) {
+	atomic.AddInt32(&r.calls, 1)
+	time.Sleep(10 * time.Millisecond)
+	return r.SongRepository.GetSongByID(id)
+}
+
+func TestCachedSongRepository(t *testing.T) {
+	inner := &countingRepository{SongRepository: setupTestRepository()}
+	repo := NewCachedSongRepository(inner, cache.NewLRU(10), time.Minute)
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Starlight"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo.GetSongByID("1")
+		}()
+	}
+	wg.Wait()
+	repo.GetSongByID("1")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&inner.calls), "Concurrent misses should load the song once")
+
+	err := repo.Transaction(func(tx SongRepository) error {
+		return tx.UpdateSong("1", &model.Song{SongName: "Uprising"})
+	})
+	assert.Nil(t, err, "Updating in a transaction should not return an error")
+
+	song, _ := repo.GetSongByID("1")
+	assert.Equal(t, "Uprising", song.SongName, "Writes should invalidate the cached song")
+
+	_, err = repo.GetSongByID("2")
+	assert.ErrorIs(t, err, ErrSongNotFound, "Missing songs should not be cached as found")
+}
+
+// stallingRepository returns the song read before a write only once
+// release is closed, like a query that was overtaken by the write
+type stallingRepository struct {
+	SongRepository
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (r *stallingRepository) GetSongByID(id string) (*model.Song, error) {
+	song, err := r.SongRepository.GetSongByID(id)
+	if r.release != nil {
+		close(r.read)
+		<-r.release
+	}
+	return song, err
+}
+
+func TestCachedSongRepository_LoadOverlappingWrite(t *testing.T) {
+	inner := &stallingRepository{SongRepository: setupTestRepository(), read: make(chan struct{}), release: make(chan struct{})}
+	repo := NewCachedSongRepository(inner, cache.NewLRU(10), time.Minute)
+	inner.SongRepository.AddSong(&model.Song{GroupName: "Muse", SongName: "Starlight"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		song, _ := repo.GetSongByID("1")
+		assert.Equal(t, "Starlight", song.SongName)
+	}()
+	<-inner.read
+	repo.UpdateSong("1", &model.Song{SongName: "Uprising"})
+	close(inner.release)
+	<-done
+
+	inner.release = nil
+	song, _ := repo.GetSongByID("1")
+	assert.Equal(t, "Uprising", song.SongName, "A load that overlapped a write should not be cached")
+}
+
+func TestCachedSongRepository_ListSongs(t *testing.T) {
+	inner := setupTestRepository()
+	repo := NewCachedSongRepository(inner, cache.NewLRU(10), time.Minute)
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Starlight"})
+	query := SongQuery{Sort: SongSort{{Column: "song_name"}, {Column: "id"}}, Limit: 10}
+
+	songs, err := repo.ListSongs(query)
+	assert.Nil(t, err)
+	assert.Len(t, songs, 1)
+	inner.AddSong(&model.Song{GroupName: "Muse", SongName: "Madness"})
+	songs, _ = repo.ListSongs(query)
+	assert.Len(t, songs, 1, "The listing should be served from the cache")
+
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Uprising"})
+	songs, _ = repo.ListSongs(query)
+	assert.Len(t, songs, 3, "Writes should invalidate cached listings")
+}
+
+func TestCacheReplicaReads(t *testing.T) {
+	primary, replica := setupTestRepository(), setupTestRepository()
+	primary.AddSong(&model.Song{GroupName: "Muse", SongName: "Starlight"})
+	replica.AddSong(&model.Song{GroupName: "Muse", SongName: "Starlight"})
+	assert.Same(t, replica, CacheReplicaReads(primary, replica, time.Second), "Without a cache the replica should be used as is")
+
+	repo := NewCachedSongRepository(primary, cache.NewLRU(10), time.Minute)
+	now := time.Now()
+	repo.(*cachedSongRepository).now = func() time.Time { return now }
+	reader := CacheReplicaReads(repo, replica, 5*time.Second)
+
+	// The replica has not caught up with the update yet
+	repo.UpdateSong("1", &model.Song{SongName: "Uprising"})
+	now = now.Add(time.Second)
+	song, _ := reader.GetSongByID("1")
+	assert.Equal(t, "Starlight", song.SongName)
+	song, _ = repo.GetSongByID("1")
+	assert.Equal(t, "Uprising", song.SongName, "Replica reads within the lag should not be cached")
+	song, _ = reader.GetSongByID("1")
+	assert.Equal(t, "Uprising", song.SongName, "Replica reads should be served from the shared cache")
+
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Madness"})
+	now = now.Add(10 * time.Second)
+	songs, _ := reader.GetSongs()
+	assert.Len(t, songs, 1)
+	songs, _ = repo.GetSongs()
+	assert.Len(t, songs, 1, "Replica reads after the lag should be cached")
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"song-library/internal/model"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *songRepository) GetTranslations(songID string) ([]model.SongTranslation, error) {
+	translations := []model.SongTranslation{}
+	if err := r.db.Where("song_id = ?", songID).Order("language").Find(&translations).Error; err != nil {
+		return nil, err
+	}
+	return translations, nil
+}
+
+func (r *songRepository) GetTranslation(songID, lang string) (*model.SongTranslation, error) {
+	var translation model.SongTranslation
+	if err := r.db.First(&translation, "song_id = ? AND language = ?", songID, lang).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTranslationNotFound
+		}
+		return nil, err
+	}
+	return &translation, nil
+}
+
+func (r *songRepository) SaveTranslation(translation *model.SongTranslation) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "song_id"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"verses", "translator", "updated_at"}),
+	}).Create(translation).Error
+}
+
+func (r *songRepository) DeleteTranslation(songID, lang string) error {
+	result := r.db.Delete(&model.SongTranslation{}, "song_id = ? AND language = ?", songID, lang)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTranslationNotFound
+	}
+	return nil
+}
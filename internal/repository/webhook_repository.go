@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"song-library/internal/model"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrWebhookNotFound is returned when no webhook matches the requested ID
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound is returned when no delivery matches the requested ID
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+// WebhookRepository defines methods for storing webhook subscriptions and
+// their deliveries
+type WebhookRepository interface {
+	GetWebhooks() ([]model.Webhook, error)
+	GetWebhookByID(id string) (*model.Webhook, error)
+	AddWebhook(webhook *model.Webhook) error
+	SaveWebhook(webhook *model.Webhook) error
+	DeleteWebhook(id string) error
+
+	// AddDelivery queues delivery unless its webhook already has a delivery
+	// of the same event, in which case delivery is left without an ID
+	AddDelivery(delivery *model.WebhookDelivery) error
+	SaveDelivery(delivery *model.WebhookDelivery) error
+	GetDeliveryByID(id string) (*model.WebhookDelivery, error)
+	// GetDeliveries returns the deliveries of a webhook, newest first. An
+	// empty webhookID or status matches all.
+	GetDeliveries(webhookID, status string) ([]model.WebhookDelivery, error)
+	// GetDueDeliveries returns up to limit pending deliveries whose next
+	// attempt is due at now
+	GetDueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error)
+}
+
+// webhookRepository implements WebhookRepository
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) GetWebhooks() ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := r.db.Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepository) GetWebhookByID(id string) (*model.Webhook, error) {
+	var webhook model.Webhook
+	if err := r.db.First(&webhook, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepository) AddWebhook(webhook *model.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+func (r *webhookRepository) SaveWebhook(webhook *model.Webhook) error {
+	return r.db.Save(webhook).Error
+}
+
+// DeleteWebhook removes a webhook together with its delivery log
+func (r *webhookRepository) DeleteWebhook(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.WebhookDelivery{}, "webhook_id = ?", id).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&model.Webhook{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return nil
+	})
+}
+
+func (r *webhookRepository) AddDelivery(delivery *model.WebhookDelivery) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "webhook_id"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(delivery).Error
+}
+
+func (r *webhookRepository) SaveDelivery(delivery *model.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+func (r *webhookRepository) GetDeliveryByID(id string) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := r.db.First(&delivery, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepository) GetDeliveries(webhookID, status string) ([]model.WebhookDelivery, error) {
+	query := r.db.Model(&model.WebhookDelivery{})
+	if webhookID != "" {
+		query = query.Where("webhook_id = ?", webhookID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []model.WebhookDelivery
+	if err := query.Order("id DESC").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) GetDueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.db.
+		Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
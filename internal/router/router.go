@@ -1,20 +1,61 @@
 package router
 
 import (
+	"song-library/config"
+	"song-library/internal/graphapi"
 	"song-library/internal/handler"
+	"song-library/internal/middleware"
 	"song-library/internal/service"
+	"song-library/internal/sse"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, songService *service.SongService) {
+// SSEHeartbeat is the interval of keep-alive comments on event streams
+const SSEHeartbeat = 15 * time.Second
+
+func SetupRoutes(r *gin.Engine, cfg *config.Config, idempotency *middleware.Idempotency, songService *service.SongService, webhookService *service.WebhookService, songEvents *sse.Broker, graphQL *graphapi.Executor) {
+	cacheControl := middleware.CacheControl(cfg.CacheTTL)
+	idempotent := idempotency.Handler()
 
 	api := r.Group("/api/v1/songs")
 	{
-		api.GET("", handler.GetSongs(songService))
-		api.GET("/:id", handler.GetSongByID(songService))
-		api.POST("", handler.AddSong(songService))
+		api.GET("", cacheControl, handler.GetSongs(songService))
+		api.GET("/events", handler.StreamSongEvents(songEvents, SSEHeartbeat))
+		api.GET("/duplicates", handler.GetDuplicateSongs(songService))
+		api.POST("/merge", idempotent, handler.MergeSongs(songService))
+		api.POST("/batch", idempotent, handler.BatchSongs(songService))
+		api.GET("/:id", cacheControl, handler.GetSongByID(songService))
+		api.GET("/:id/lyrics", cacheControl, handler.GetSongLyrics(songService))
+		api.GET("/:id/chords", cacheControl, handler.GetSongChords(songService))
+		api.GET("/:id/transliteration", cacheControl, handler.GetSongTransliteration(songService))
+		api.GET("/:id/translations", handler.GetSongTranslations(songService))
+		api.GET("/:id/translations/:lang", cacheControl, handler.GetSongTranslation(songService))
+		api.PUT("/:id/translations/:lang", handler.PutSongTranslation(songService))
+		api.DELETE("/:id/translations/:lang", handler.DeleteSongTranslation(songService))
+		api.GET("/:id/side-by-side", cacheControl, handler.GetSongSideBySide(songService))
+		api.GET("/:id/link", handler.GetSongLinkCheck(songService))
+		api.GET("/:id/revisions", handler.GetSongRevisions(songService))
+		api.GET("/:id/revisions/diff", handler.DiffSongRevisions(songService))
+		api.POST("/:id/revisions/:rev/restore", idempotent, handler.RestoreSongRevision(songService))
+		api.POST("", idempotent, handler.AddSong(songService))
 		api.PUT("/:id", handler.UpdateSong(songService))
 		api.DELETE("/:id", handler.DeleteSong(songService))
 	}
+
+	r.GET("/api/v1/audit", handler.GetAuditLog(songService))
+	r.POST("/graphql", handler.GraphQL(graphQL))
+
+	webhooks := r.Group("/api/v1/webhooks")
+	{
+		webhooks.GET("", handler.GetWebhooks(webhookService))
+		webhooks.POST("", idempotent, handler.AddWebhook(webhookService))
+		webhooks.GET("/dead-letters", handler.GetWebhookDeadLetters(webhookService))
+		webhooks.POST("/dead-letters/:id/redeliver", idempotent, handler.RedeliverWebhook(webhookService))
+		webhooks.GET("/:id", handler.GetWebhookByID(webhookService))
+		webhooks.PUT("/:id", handler.UpdateWebhook(webhookService))
+		webhooks.DELETE("/:id", handler.DeleteWebhook(webhookService))
+		webhooks.GET("/:id/deliveries", handler.GetWebhookDeliveries(webhookService))
+	}
 }
@@ -0,0 +1,29 @@
+package service
+
+import "context"
+
+// Actor identifies who performs an operation and from where
+type Actor struct {
+	Name      string
+	RequestID string
+	ClientIP  string
+}
+
+// AnonymousActor is used when a request carries no user identity
+const AnonymousActor = "anonymous"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored in ctx, or an anonymous actor
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	if actor.Name == "" {
+		actor.Name = AnonymousActor
+	}
+	return actor
+}
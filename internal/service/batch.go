@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/dedupe"
+	"song-library/internal/events"
+	"song-library/internal/language"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/pkg/logger"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Batch operation kinds
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// Batch operation statuses
+const (
+	BatchSucceeded = "succeeded"
+	BatchFailed    = "failed"
+	// BatchRolledBack marks operations of an atomic batch that would have
+	// succeeded but were rolled back because another one failed
+	BatchRolledBack = "rolled_back"
+)
+
+// MaxBatchSize is the most operations a batch may hold
+const MaxBatchSize = 1000
+
+// BatchOperation is one write of a batch. Create takes Song, update takes
+// ID and the fields of Song to change, delete takes ID. IDs refer to
+// stored songs, not to songs created earlier in the batch.
+type BatchOperation struct {
+	Op   string      `json:"op" enums:"create,update,delete"`
+	ID   string      `json:"id,omitempty"`
+	Song *model.Song `json:"song,omitempty"`
+}
+
+// BatchResult is the outcome of one operation of a batch
+type BatchResult struct {
+	Index  int         `json:"index"`
+	Op     string      `json:"op"`
+	Status string      `json:"status"`
+	Song   *model.Song `json:"song,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	// Err is the error of a failed operation
+	Err error `json:"-"`
+}
+
+// BatchSongs runs the operations in order inside one transaction. The
+// operations are checked against the stored songs and each other first, and
+// then written with one statement per kind of write. When atomic is true a
+// failing operation rolls back the whole batch; otherwise failed operations
+// are skipped and the others are applied. Database errors abort the batch
+// in both modes.
+func (s *SongService) BatchSongs(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, errors.Wrap(ErrValidation, "batch has no operations")
+	}
+	if len(ops) > MaxBatchSize {
+		return nil, errors.Wrapf(ErrValidation, "batch has %d operations, at most %d are allowed", len(ops), MaxBatchSize)
+	}
+
+	actor := ActorFromContext(ctx)
+	var results []BatchResult
+	var applied int
+	err := s.store(ctx).Transaction(func(repo repository.SongRepository) error {
+		plan, err := newBatchPlan(repo, ops, actor)
+		if err != nil {
+			return err
+		}
+		results = make([]BatchResult, len(ops))
+		failed := false
+		for i, op := range ops {
+			results[i] = plan.apply(i, op)
+			failed = failed || results[i].Err != nil
+		}
+		if atomic && failed {
+			return errBatchRolledBack
+		}
+		applied = len(plan.changes)
+		return plan.write(repo)
+	})
+	if errors.Is(err, errBatchRolledBack) {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Status, results[i].Song = BatchRolledBack, nil
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "Song batch applied", logger.Fields{"operations": len(ops), "applied": applied})
+	if applied > 0 {
+		s.committed(ctx)
+	}
+	return results, nil
+}
+
+// errBatchRolledBack rolls back the transaction of a failed atomic batch
+var errBatchRolledBack = errors.New("batch rolled back")
+
+// batchChange is an applied operation, written to the revision history,
+// audit log and outbox once the songs are stored
+type batchChange struct {
+	action string
+	before *model.Song
+	// after points to the created song, whose ID is assigned on write, or
+	// is a snapshot of the updated song
+	after *model.Song
+}
+
+// batchPlan applies the operations of a batch to the songs loaded for it
+// and collects the writes
+type batchPlan struct {
+	actor Actor
+	// songs are the stored songs the batch refers to; deleted songs are
+	// removed
+	songs map[uint]*model.Song
+	// owners maps normalized keys to the songs holding them
+	owners map[string]*model.Song
+	// latest are the newest revisions of the songs; songs without any are
+	// missing
+	latest map[uint]*model.SongRevision
+
+	created   []*model.Song
+	saved     []*model.Song
+	deleted   []string
+	revisions []*model.SongRevision
+	changes   []batchChange
+}
+
+// newBatchPlan loads the songs, keys and revisions the operations refer to
+func newBatchPlan(repo repository.SongRepository, ops []BatchOperation, actor Actor) (*batchPlan, error) {
+	var ids, keys []string
+	for _, op := range ops {
+		// Other IDs name no song and would make PostgreSQL reject the
+		// queries below
+		if _, err := strconv.ParseUint(op.ID, 10, 0); err == nil {
+			ids = append(ids, op.ID)
+		}
+		if op.Song != nil {
+			keys = append(keys, dedupe.Key(op.Song.GroupName, op.Song.SongName))
+		}
+	}
+	plan := &batchPlan{
+		actor:  actor,
+		songs:  map[uint]*model.Song{},
+		owners: map[string]*model.Song{},
+		latest: map[uint]*model.SongRevision{},
+	}
+
+	stored, err := repo.GetSongsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stored {
+		song := &stored[i]
+		plan.songs[song.ID] = song
+		// Updates may keep the artist or the title of the stored song
+		for _, op := range ops {
+			if op.Song != nil && op.ID == strconv.FormatUint(uint64(song.ID), 10) {
+				group, name := renamed(song, op.Song)
+				keys = append(keys, dedupe.Key(group, name))
+			}
+		}
+	}
+	owners, err := repo.GetSongsByKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	for i := range owners {
+		song := &owners[i]
+		if loaded, ok := plan.songs[song.ID]; ok {
+			song = loaded
+		}
+		plan.owners[song.NormalizedKey] = song
+	}
+	for _, song := range plan.songs {
+		plan.owners[song.NormalizedKey] = song
+	}
+
+	revs, err := repo.GetLatestRevisions(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revs {
+		plan.latest[revs[i].SongID] = &revs[i]
+	}
+	return plan, nil
+}
+
+// apply checks op against the songs as left by the previous operations and
+// records its writes
+func (p *batchPlan) apply(index int, op BatchOperation) BatchResult {
+	result := BatchResult{Index: index, Op: op.Op, Status: BatchSucceeded}
+	song, err := p.applyOp(op)
+	if err != nil {
+		result.Status, result.Err, result.Error = BatchFailed, err, err.Error()
+		return result
+	}
+	result.Song = song
+	return result
+}
+
+func (p *batchPlan) applyOp(op BatchOperation) (*model.Song, error) {
+	switch op.Op {
+	case BatchCreate:
+		if op.Song == nil || op.Song.GroupName == "" || op.Song.SongName == "" {
+			return nil, errors.Wrap(ErrValidation, "create needs a song with group_name and song_name")
+		}
+		song := *op.Song
+		song.ID, song.NormalizedKey = 0, dedupe.Key(song.GroupName, song.SongName)
+		if err := prepareLyrics(&song); err != nil {
+			return nil, err
+		}
+		if err := p.claim(song.NormalizedKey, nil); err != nil {
+			return nil, err
+		}
+		created := &song
+		p.owners[song.NormalizedKey] = created
+		p.created = append(p.created, created)
+		p.changes = append(p.changes, batchChange{action: model.AuditActionCreate, after: created})
+		return created, nil
+
+	case BatchUpdate:
+		if op.Song == nil {
+			return nil, errors.Wrap(ErrValidation, "update needs a song")
+		}
+		stored, err := p.song(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		patch := *op.Song
+		if err := prepareLyrics(&patch); err != nil {
+			return nil, err
+		}
+		group, name := renamed(stored, &patch)
+		key := dedupe.Key(group, name)
+		if err := p.claim(key, stored); err != nil {
+			return nil, err
+		}
+		before := *stored
+		delete(p.owners, stored.NormalizedKey)
+		applyPatch(stored, &patch)
+		stored.NormalizedKey = key
+		p.owners[key] = stored
+		p.save(stored)
+		p.recordRevision(&before, stored)
+		after := *stored
+		p.changes = append(p.changes, batchChange{action: model.AuditActionUpdate, before: &before, after: &after})
+		return &after, nil
+
+	case BatchDelete:
+		stored, err := p.song(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		before := *stored
+		delete(p.songs, stored.ID)
+		delete(p.owners, stored.NormalizedKey)
+		p.deleted = append(p.deleted, strconv.FormatUint(uint64(stored.ID), 10))
+		p.changes = append(p.changes, batchChange{action: model.AuditActionDelete, before: &before})
+		return nil, nil
+
+	default:
+		return nil, errors.Wrapf(ErrValidation, "unknown operation %q, use create, update or delete", op.Op)
+	}
+}
+
+// song returns the stored song with the given ID unless it was deleted
+func (p *batchPlan) song(id string) (*model.Song, error) {
+	key, err := strconv.ParseUint(id, 10, 0)
+	if err != nil {
+		return nil, errors.Wrapf(repository.ErrSongNotFound, "song %q", id)
+	}
+	song, ok := p.songs[uint(key)]
+	if !ok {
+		return nil, errors.Wrapf(repository.ErrSongNotFound, "song %s", id)
+	}
+	return song, nil
+}
+
+// claim returns ErrDuplicateSong when a song other than owner holds key
+func (p *batchPlan) claim(key string, owner *model.Song) error {
+	holder, ok := p.owners[key]
+	if !ok || holder == owner {
+		return nil
+	}
+	if holder.ID == 0 {
+		return errors.Wrap(repository.ErrDuplicateSong, "same artist and title as a song created earlier in the batch")
+	}
+	return errors.Wrapf(repository.ErrDuplicateSong, "same artist and title as song %d", holder.ID)
+}
+
+// save schedules song to be written once, however often it is updated
+func (p *batchPlan) save(song *model.Song) {
+	for _, saved := range p.saved {
+		if saved == song {
+			return
+		}
+	}
+	p.saved = append(p.saved, song)
+}
+
+// recordRevision adds a revision for an update that changed the song, and
+// a base revision first for songs created before revision tracking
+func (p *batchPlan) recordRevision(before, after *model.Song) {
+	latest, ok := p.latest[after.ID]
+	if !ok {
+		latest = model.NewSongRevision(before, "")
+		p.revisions = append(p.revisions, latest)
+		p.latest[after.ID] = latest
+	}
+	rev := model.NewSongRevision(after, p.actor.Name)
+	if latest.SameContent(rev) {
+		return
+	}
+	p.revisions = append(p.revisions, rev)
+	p.latest[after.ID] = rev
+}
+
+// write stores the planned changes. Deletes go first and creates last, so
+// that keys freed by the batch can be taken by later operations.
+func (p *batchPlan) write(repo repository.SongRepository) error {
+	if err := repo.DeleteSongs(p.deleted); err != nil {
+		return err
+	}
+	var saved []*model.Song
+	for _, song := range p.saved {
+		if _, ok := p.songs[song.ID]; ok {
+			saved = append(saved, song)
+		}
+	}
+	if err := repo.SaveSongs(saved); err != nil {
+		return err
+	}
+	if err := repo.AddSongs(p.created); err != nil {
+		return err
+	}
+
+	revisions := p.revisions
+	var entries []*model.AuditEntry
+	var outbox []*model.OutboxEvent
+	for _, change := range p.changes {
+		var song *model.Song
+		var eventType string
+		switch change.action {
+		case model.AuditActionCreate:
+			song, eventType = change.after, events.SongCreated
+			revisions = append(revisions, model.NewSongRevision(song, p.actor.Name))
+		case model.AuditActionUpdate:
+			song, eventType = change.after, events.SongUpdated
+		case model.AuditActionDelete:
+			song, eventType = change.before, events.SongDeleted
+		}
+		entry, err := newAuditEntry(p.actor, change.action, song.ID, change.before, change.after)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		event, err := newOutboxEvent(eventType, song)
+		if err != nil {
+			return err
+		}
+		outbox = append(outbox, event)
+	}
+	if err := repo.AddRevisions(revisions); err != nil {
+		return err
+	}
+	if err := repo.AddAuditEntries(entries); err != nil {
+		return err
+	}
+	return repo.AddOutboxEvents(outbox)
+}
+
+// renamed returns the artist and title of song after applying patch
+func renamed(song, patch *model.Song) (string, string) {
+	group, name := song.GroupName, song.SongName
+	if patch.GroupName != "" {
+		group = patch.GroupName
+	}
+	if patch.SongName != "" {
+		name = patch.SongName
+	}
+	return group, name
+}
+
+// applyPatch copies the non-empty fields of patch onto song, like
+// UpdateSong
+func applyPatch(song, patch *model.Song) {
+	song.GroupName, song.SongName = renamed(song, patch)
+	if !patch.ReleaseDate.IsZero() {
+		song.ReleaseDate = patch.ReleaseDate
+	}
+	if patch.Text != "" {
+		song.Text = patch.Text
+		song.Language = language.Detect(song.Text)
+	}
+	if patch.LRC != "" {
+		song.LRC = patch.LRC
+	}
+	if patch.ChordPro != "" {
+		song.ChordPro = patch.ChordPro
+	}
+	if patch.Link != "" {
+		song.Link = patch.Link
+	}
+}
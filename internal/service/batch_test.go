@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/events"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSongService_BatchSongs(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := WithActor(context.Background(), Actor{Name: "alice"})
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Starlight"})
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Uprising"})
+
+	results, err := songService.BatchSongs(ctx, []BatchOperation{
+		{Op: BatchCreate, Song: &model.Song{GroupName: "Radiohead", SongName: "Creep"}},
+		{Op: BatchUpdate, ID: "1", Song: &model.Song{Text: "Far away"}},
+		{Op: BatchDelete, ID: "2"},
+		{Op: BatchCreate, Song: &model.Song{GroupName: "Muse", SongName: "Uprising"}},
+		{Op: BatchCreate, Song: &model.Song{GroupName: "radiohead", SongName: "CREEP"}},
+		{Op: BatchDelete, ID: "42"},
+	}, false)
+	require.Nil(t, err)
+	statuses := make([]string, len(results))
+	for i, result := range results {
+		statuses[i] = result.Status
+	}
+	assert.Equal(t, []string{BatchSucceeded, BatchSucceeded, BatchSucceeded, BatchSucceeded, BatchFailed, BatchFailed}, statuses)
+	assert.NotZero(t, results[0].Song.ID, "Created songs should get IDs")
+	assert.Equal(t, "Far away", results[1].Song.Text)
+	assert.ErrorIs(t, results[4].Err, repository.ErrDuplicateSong, "Songs created earlier in the batch should count as duplicates")
+	assert.ErrorIs(t, results[5].Err, repository.ErrSongNotFound)
+
+	songs, _ := songService.GetSongs(ctx)
+	assert.Len(t, songs, 3, "A deleted song's name should be free for a later create")
+	revs, _ := songService.GetRevisions(ctx, "1")
+	assert.Len(t, revs, 2, "Updates should record a base revision and the change")
+	assert.Equal(t, "alice", revs[1].Author)
+	entries, _ := songService.GetAuditLog(ctx, repository.AuditFilter{Actor: "alice"})
+	assert.Len(t, entries, 4, "Every applied operation should be audited")
+	pending, _ := repo.GetPendingOutboxEvents(10)
+	require.Len(t, pending, 4)
+	assert.Equal(t, events.SongCreated, pending[0].EventType, "Events should follow the order of the operations")
+	assert.Equal(t, events.SongDeleted, pending[2].EventType)
+}
+
+func TestSongService_BatchSongs_UpdateDetectsLanguage(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := WithActor(context.Background(), Actor{Name: "alice"})
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Uprising", Text: "Far away, this ship is taking me far away"})
+
+	results, err := songService.BatchSongs(ctx, []BatchOperation{
+		{Op: BatchUpdate, ID: "1", Song: &model.Song{Text: "Они не заставят нас, они перестанут унижать нас"}},
+	}, false)
+	require.Nil(t, err)
+	assert.Equal(t, "ru", results[0].Song.Language, "The updated song should carry the new language")
+
+	entries, _ := songService.GetAuditLog(ctx, repository.AuditFilter{Action: model.AuditActionUpdate})
+	require.Len(t, entries, 1)
+	assert.Contains(t, string(entries[0].Before), `"language":"en"`)
+	assert.Contains(t, string(entries[0].After), `"language":"ru"`, "The audit entry should record the new language")
+}
+
+func TestSongService_BatchSongs_Atomic(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := context.Background()
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Starlight"})
+
+	results, err := songService.BatchSongs(ctx, []BatchOperation{
+		{Op: BatchCreate, Song: &model.Song{GroupName: "Radiohead", SongName: "Creep"}},
+		{Op: BatchUpdate, ID: "1", Song: &model.Song{LRC: "not lrc"}},
+	}, true)
+	require.Nil(t, err)
+	assert.Equal(t, BatchRolledBack, results[0].Status)
+	assert.Nil(t, results[0].Song)
+	assert.ErrorIs(t, results[1].Err, ErrValidation)
+	songs, _ := songService.GetSongs(ctx)
+	assert.Len(t, songs, 1, "A failed atomic batch should change nothing")
+
+	_, err = songService.BatchSongs(ctx, nil, true)
+	assert.ErrorIs(t, err, ErrValidation, "An empty batch should be rejected")
+	_, err = songService.BatchSongs(ctx, []BatchOperation{{Op: "upsert"}}, true)
+	assert.Nil(t, err, "Invalid operations should be reported per operation")
+}
+
+// numericIDRepository rejects non-numeric song IDs like a PostgreSQL
+// bigint column does
+type numericIDRepository struct {
+	repository.SongRepository
+}
+
+func (r *numericIDRepository) checkIDs(ids []string) error {
+	for _, id := range ids {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *numericIDRepository) GetSongsByIDs(ids []string) ([]model.Song, error) {
+	if err := r.checkIDs(ids); err != nil {
+		return nil, err
+	}
+	return r.SongRepository.GetSongsByIDs(ids)
+}
+
+func (r *numericIDRepository) GetLatestRevisions(ids []string) ([]model.SongRevision, error) {
+	if err := r.checkIDs(ids); err != nil {
+		return nil, err
+	}
+	return r.SongRepository.GetLatestRevisions(ids)
+}
+
+func (r *numericIDRepository) Transaction(fn func(repo repository.SongRepository) error) error {
+	return r.SongRepository.Transaction(func(tx repository.SongRepository) error {
+		return fn(&numericIDRepository{SongRepository: tx})
+	})
+}
+
+func TestSongService_BatchSongs_InvalidIDs(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(&numericIDRepository{SongRepository: repo})
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Starlight"})
+
+	results, err := songService.BatchSongs(context.Background(), []BatchOperation{
+		{Op: BatchUpdate, ID: "1", Song: &model.Song{Text: "Far away"}},
+		{Op: BatchDelete, ID: "abc"},
+	}, false)
+	require.Nil(t, err, "Non-numeric IDs should not fail the batch")
+	require.Len(t, results, 2)
+	assert.Equal(t, BatchSucceeded, results[0].Status)
+	assert.ErrorIs(t, results[1].Err, repository.ErrSongNotFound)
+}
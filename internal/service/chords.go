@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/lyrics"
+	"song-library/internal/model"
+
+	"github.com/pkg/errors"
+)
+
+// ChordOptions change the chords of a chord sheet
+type ChordOptions struct {
+	// Transpose moves every chord by this many semitones, from -12 to 12
+	Transpose int
+	// Capo, when set, rewrites the chords for a capo on this fret, keeping
+	// the (transposed) sound of the song
+	Capo *int
+}
+
+// GetChordSheet returns the song together with its parsed chord sheet,
+// transposed as opts ask. The sheet is nil when the song has none.
+func (s *SongService) GetChordSheet(ctx context.Context, id string, opts ChordOptions) (*model.Song, *lyrics.ChordSheet, error) {
+	if opts.Transpose < -12 || opts.Transpose > 12 {
+		return nil, nil, errors.Wrap(ErrValidation, "transpose must be between -12 and 12 semitones")
+	}
+	if opts.Capo != nil && (*opts.Capo < 0 || *opts.Capo > 11) {
+		return nil, nil, errors.Wrap(ErrValidation, "capo must be a fret from 0 to 11")
+	}
+	song, err := s.reader(ctx).GetSongByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if song.ChordPro == "" {
+		return song, nil, nil
+	}
+	sheet, err := lyrics.ParseChordPro(song.ChordPro)
+	if err != nil {
+		return nil, nil, err
+	}
+	sheet = sheet.Transpose(opts.Transpose)
+	if opts.Capo != nil {
+		sheet = sheet.WithCapo(*opts.Capo)
+	}
+	return song, sheet, nil
+}
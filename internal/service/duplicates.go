@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/dedupe"
+	"song-library/internal/events"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/pkg/logger"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// FindDuplicates reports up to limit pairs of songs whose artists and
+// titles are at least threshold similar, most similar first. A limit of 0
+// reports all pairs.
+func (s *SongService) FindDuplicates(ctx context.Context, threshold float64, limit int) ([]dedupe.Pair, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, errors.Wrap(ErrValidation, "threshold must be greater than 0 and at most 1")
+	}
+	songs, err := s.reader(ctx).GetSongs()
+	if err != nil {
+		return nil, err
+	}
+	pairs := dedupe.Find(songs, threshold)
+	if limit > 0 && len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	return pairs, nil
+}
+
+// MergeSongs folds the source song into the target song. The target keeps
+// its artist and title and takes the fields it lacks from the source, and
+// the longer lyrics. The source's revisions are appended to the target's
+// history, its translations into languages the target lacks move to the
+// target, and the source is deleted.
+func (s *SongService) MergeSongs(ctx context.Context, targetID, sourceID string) (*model.Song, error) {
+	if targetID == sourceID {
+		return nil, errors.Wrap(ErrValidation, "cannot merge a song into itself")
+	}
+	actor := ActorFromContext(ctx)
+	var merged *model.Song
+	err := s.store(ctx).Transaction(func(repo repository.SongRepository) error {
+		target, err := repo.GetSongByID(targetID)
+		if err != nil {
+			return err
+		}
+		source, err := repo.GetSongByID(sourceID)
+		if err != nil {
+			return err
+		}
+		if err := ensureBaseRevision(repo, target); err != nil {
+			return err
+		}
+		if err := ensureBaseRevision(repo, source); err != nil {
+			return err
+		}
+
+		revs, err := repo.GetRevisions(strconv.FormatUint(uint64(source.ID), 10))
+		if err != nil {
+			return err
+		}
+		for _, rev := range revs {
+			rev.ID, rev.SongID = 0, target.ID
+			if err := repo.AddRevision(&rev); err != nil {
+				return err
+			}
+		}
+
+		if err := moveTranslations(repo, source, target); err != nil {
+			return err
+		}
+
+		merged = mergeFields(target, source)
+		if err := repo.DeleteSong(sourceID); err != nil {
+			return err
+		}
+		if err := repo.SaveSong(merged); err != nil {
+			return err
+		}
+		if err := recordRevision(repo, merged, actor.Name); err != nil {
+			return err
+		}
+		if err := recordAudit(repo, actor, model.AuditActionMerge, target.ID, target, merged); err != nil {
+			return err
+		}
+		if err := recordAudit(repo, actor, model.AuditActionDelete, source.ID, source, nil); err != nil {
+			return err
+		}
+		if err := recordEvent(repo, events.SongDeleted, source); err != nil {
+			return err
+		}
+		return recordEvent(repo, events.SongUpdated, merged)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "Songs merged", logger.Fields{"song_id": merged.ID, "merged_song_id": sourceID})
+	s.committed(ctx)
+	return merged, nil
+}
+
+// moveTranslations saves the translations of source into languages that
+// target has no translation for as translations of target
+func moveTranslations(repo repository.SongRepository, source, target *model.Song) error {
+	existing, err := repo.GetTranslations(strconv.FormatUint(uint64(target.ID), 10))
+	if err != nil {
+		return err
+	}
+	languages := make(map[string]bool, len(existing))
+	for _, translation := range existing {
+		languages[translation.Language] = true
+	}
+	translations, err := repo.GetTranslations(strconv.FormatUint(uint64(source.ID), 10))
+	if err != nil {
+		return err
+	}
+	for _, translation := range translations {
+		if languages[translation.Language] {
+			continue
+		}
+		translation.SongID = target.ID
+		if err := repo.SaveTranslation(&translation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFields returns target with the empty fields filled in from source
+// and the longer of both lyrics
+func mergeFields(target, source *model.Song) *model.Song {
+	merged := *target
+	if merged.ReleaseDate.IsZero() {
+		merged.ReleaseDate = source.ReleaseDate
+	}
+	if len(source.Text) > len(merged.Text) {
+		merged.Text = source.Text
+	}
+	if merged.LRC == "" {
+		merged.LRC = source.LRC
+	}
+	if merged.ChordPro == "" {
+		merged.ChordPro = source.ChordPro
+	}
+	if merged.Link == "" {
+		merged.Link = source.Link
+	}
+	return &merged
+}
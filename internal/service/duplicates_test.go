@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSongService_RejectsDuplicates(t *testing.T) {
+	songService := NewSongService(setupTestRepository())
+	ctx := context.Background()
+
+	songService.AddSong(ctx, &model.Song{GroupName: "Beyoncé", SongName: "Halo"})
+	err := songService.AddSong(ctx, &model.Song{GroupName: "beyonce", SongName: "HALO!"})
+	assert.ErrorIs(t, err, repository.ErrDuplicateSong, "Case, accents and punctuation should be ignored")
+
+	songService.AddSong(ctx, &model.Song{GroupName: "Beyoncé", SongName: "Crazy in Love"})
+	err = songService.UpdateSong(ctx, "2", &model.Song{SongName: "Halo"})
+	assert.ErrorIs(t, err, repository.ErrDuplicateSong, "Renaming onto another song should be rejected")
+	assert.Nil(t, songService.UpdateSong(ctx, "1", &model.Song{SongName: "Halo", Text: "Remember those walls I built"}),
+		"A song should not conflict with itself")
+}
+
+func TestSongService_FindAndMergeDuplicates(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := context.Background()
+
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Supermassive Black Hole", Text: "Oh baby"})
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Supermasive Black Hole", Text: "Oh baby don't you know I suffer", Link: "https://example.com"})
+	songService.AddSong(ctx, &model.Song{GroupName: "Radiohead", SongName: "Creep"})
+
+	_, err := songService.FindDuplicates(ctx, 0, 0)
+	assert.ErrorIs(t, err, ErrValidation, "Threshold must be positive")
+	pairs, err := songService.FindDuplicates(ctx, 0.85, 0)
+	assert.Nil(t, err)
+	assert.Len(t, pairs, 1, "Only the misspelled title should be reported")
+	pairs, _ = songService.FindDuplicates(ctx, 0.1, 1)
+	assert.Len(t, pairs, 1, "The report should be limited")
+
+	repo.SaveTranslation(&model.SongTranslation{SongID: 1, Language: "fr", Verses: []string{"Oh bébé"}, Translator: "target"})
+	repo.SaveTranslation(&model.SongTranslation{SongID: 2, Language: "fr", Verses: []string{"Oh chéri"}, Translator: "source"})
+	repo.SaveTranslation(&model.SongTranslation{SongID: 2, Language: "de", Verses: []string{"Oh Baby"}, Translator: "source"})
+
+	merged, err := songService.MergeSongs(ctx, "1", "2")
+	assert.Nil(t, err, "Merging should not return an error")
+	assert.Equal(t, "Supermassive Black Hole", merged.SongName, "The target should keep its title")
+	assert.Equal(t, "https://example.com", merged.Link, "Missing fields should come from the source")
+	assert.Equal(t, "Oh baby don't you know I suffer", merged.Text, "The longer lyrics should win")
+
+	_, err = songService.GetSongByID(ctx, "2")
+	assert.ErrorIs(t, err, repository.ErrSongNotFound, "The source should be deleted")
+	revs, _ := songService.GetRevisions(ctx, "1")
+	assert.Len(t, revs, 3, "The source history should move to the target")
+	translations, _ := songService.GetTranslations(ctx, "1")
+	if assert.Len(t, translations, 2, "Translations into new languages should move to the target") {
+		assert.Equal(t, "de", translations[0].Language)
+		assert.Equal(t, "target", translations[1].Translator, "The target should keep its own translations")
+	}
+	entries, _ := songService.GetAuditLog(ctx, repository.AuditFilter{Action: model.AuditActionMerge})
+	assert.Len(t, entries, 1, "The merge should be audited")
+
+	_, err = songService.MergeSongs(ctx, "1", "1")
+	assert.ErrorIs(t, err, ErrValidation)
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+)
+
+// GetLinkCheck returns the latest check of a song's link. It fails with
+// repository.ErrLinkCheckNotFound while the current link has not been
+// checked yet.
+func (s *SongService) GetLinkCheck(ctx context.Context, id string) (*model.LinkCheck, error) {
+	repo := s.reader(ctx)
+	song, err := repo.GetSongByID(id)
+	if err != nil {
+		return nil, err
+	}
+	check, err := repo.GetLinkCheck(id)
+	if err != nil {
+		return nil, err
+	}
+	if song.Link == "" || check.URL != song.Link {
+		return nil, repository.ErrLinkCheckNotFound
+	}
+	return check, nil
+}
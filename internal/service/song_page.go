@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Page sizes of song listings
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// SongPageQuery selects a page of songs
+type SongPageQuery struct {
+	// Sort, Language and BrokenLinks select songs as in ListSongs. A
+	// cursor keeps the ones it was issued for, so they may be left empty
+	// with one.
+	Sort        string
+	Language    string
+	BrokenLinks bool
+	// Filter selects songs by their artist, title, lyrics and release
+	// date. A cursor keeps it as well.
+	Filter SongFilter
+	// Limit is the page size, DefaultPageSize when zero
+	Limit int
+	// Cursor is the Next or Prev cursor of an earlier page, empty for the
+	// first page
+	Cursor string
+}
+
+// SongPage is a page of songs
+type SongPage struct {
+	Songs []model.Song
+	// Next and Prev are the cursors of the following and preceding pages,
+	// empty at either end
+	Next string
+	Prev string
+	// Cursors holds, for each song, the cursor of the page that follows it
+	Cursors []string
+}
+
+// SongFilter selects songs as the filters of repository.SongQuery do. Zero
+// fields select every song.
+type SongFilter struct {
+	GroupName      string    `json:"g,omitempty"`
+	SongName       string    `json:"n,omitempty"`
+	Text           string    `json:"t,omitempty"`
+	ReleasedAfter  time.Time `json:"a"`
+	ReleasedBefore time.Time `json:"b"`
+}
+
+func (f SongFilter) isZero() bool {
+	return f.equal(SongFilter{})
+}
+
+func (f SongFilter) equal(o SongFilter) bool {
+	return f.GroupName == o.GroupName && f.SongName == o.SongName && f.Text == o.Text &&
+		f.ReleasedAfter.Equal(o.ReleasedAfter) && f.ReleasedBefore.Equal(o.ReleasedBefore)
+}
+
+// songCursor is the signed content of a page cursor. It holds the sort
+// columns of the song the page starts after, rather than an offset, so
+// that it stays valid when songs are added or deleted.
+type songCursor struct {
+	Sort        string `json:"s"`
+	Language    string `json:"l,omitempty"`
+	BrokenLinks bool   `json:"x,omitempty"`
+	// Filter is nil without filters, to keep cursors short
+	Filter *SongFilter `json:"f,omitempty"`
+	// Backward selects the page before the song instead of after it
+	Backward bool    `json:"b,omitempty"`
+	Key      songKey `json:"k"`
+}
+
+// query returns the repository query of the songs the cursor pages through
+func (c songCursor) query() repository.SongQuery {
+	query := repository.SongQuery{Language: c.Language, BrokenLinks: c.BrokenLinks}
+	if f := c.Filter; f != nil {
+		query.GroupName, query.SongName, query.Text = f.GroupName, f.SongName, f.Text
+		query.ReleasedAfter, query.ReleasedBefore = f.ReleasedAfter, f.ReleasedBefore
+	}
+	return query
+}
+
+// songKey holds the sortable columns of a song; only those of the cursor's
+// sort are set
+type songKey struct {
+	ID          uint       `json:"id"`
+	GroupName   string     `json:"g,omitempty"`
+	SongName    string     `json:"n,omitempty"`
+	ReleaseDate *time.Time `json:"r,omitempty"`
+	CreatedAt   *time.Time `json:"c,omitempty"`
+	UpdatedAt   *time.Time `json:"u,omitempty"`
+}
+
+func newSongKey(order repository.SongSort, song *model.Song) songKey {
+	key := songKey{ID: song.ID}
+	for _, field := range order {
+		switch field.Column {
+		case "group_name":
+			key.GroupName = song.GroupName
+		case "song_name":
+			key.SongName = song.SongName
+		case "release_date":
+			key.ReleaseDate = &song.ReleaseDate
+		case "created_at":
+			key.CreatedAt = &song.CreatedAt
+		case "updated_at":
+			key.UpdatedAt = &song.UpdatedAt
+		}
+	}
+	return key
+}
+
+func (k songKey) song() *model.Song {
+	song := &model.Song{ID: k.ID, GroupName: k.GroupName, SongName: k.SongName}
+	if k.ReleaseDate != nil {
+		song.ReleaseDate = *k.ReleaseDate
+	}
+	if k.CreatedAt != nil {
+		song.CreatedAt = *k.CreatedAt
+	}
+	if k.UpdatedAt != nil {
+		song.UpdatedAt = *k.UpdatedAt
+	}
+	return song
+}
+
+// UseCursorSecret signs page cursors with secret. Without it cursors are
+// signed with a random key and stop working when the process restarts.
+func (s *SongService) UseCursorSecret(secret string) {
+	s.cursorKey = []byte(secret)
+}
+
+// ListSongsPage returns a page of songs. Pages are selected by keyset
+// rather than by offset, so deep pages are as fast as the first one.
+func (s *SongService) ListSongsPage(ctx context.Context, q SongPageQuery) (*SongPage, error) {
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit < 1 || q.Limit > MaxPageSize {
+		return nil, errors.Wrapf(ErrValidation, "limit must be between 1 and %d", MaxPageSize)
+	}
+	cursor, order, err := s.pageCursor(q)
+	if err != nil {
+		return nil, err
+	}
+	query := cursor.query()
+	query.Sort, query.Limit = order, q.Limit+1
+	if q.Cursor != "" {
+		query.After = cursor.Key.song()
+		if cursor.Backward {
+			query.Sort = order.Reverse()
+		}
+	}
+
+	songs, err := s.reader(ctx).ListSongs(query)
+	if err != nil {
+		return nil, err
+	}
+	more := len(songs) > q.Limit
+	if more {
+		songs = songs[:q.Limit]
+	}
+	if cursor.Backward {
+		for i, j := 0, len(songs)-1; i < j; i, j = i+1, j-1 {
+			songs[i], songs[j] = songs[j], songs[i]
+		}
+	}
+
+	page := &SongPage{Songs: songs}
+	if len(songs) == 0 {
+		// Past either end, for example because the rest was deleted: lead
+		// back the way the client came
+		if q.Cursor != "" {
+			back := cursor
+			back.Backward = !cursor.Backward
+			if cursor.Backward {
+				page.Next = s.encodeCursor(back)
+			} else {
+				page.Prev = s.encodeCursor(back)
+			}
+		}
+		return page, nil
+	}
+	page.Cursors = make([]string, len(songs))
+	for i := range songs {
+		next := cursor
+		next.Backward, next.Key = false, newSongKey(order, &songs[i])
+		page.Cursors[i] = s.encodeCursor(next)
+	}
+	// There are more songs in the direction of the query, and the page the
+	// cursor came from lies in the other one
+	hasNext, hasPrev := more, q.Cursor != ""
+	if cursor.Backward {
+		hasNext, hasPrev = hasPrev, hasNext
+	}
+	if hasNext {
+		page.Next = page.Cursors[len(songs)-1]
+	}
+	if hasPrev {
+		prev := cursor
+		prev.Backward, prev.Key = true, newSongKey(order, &songs[0])
+		page.Prev = s.encodeCursor(prev)
+	}
+	return page, nil
+}
+
+// CountSongs returns the number of songs selected by q, or by its cursor,
+// on all pages together
+func (s *SongService) CountSongs(ctx context.Context, q SongPageQuery) (int, error) {
+	cursor, _, err := s.pageCursor(q)
+	if err != nil {
+		return 0, err
+	}
+	return s.reader(ctx).CountSongs(cursor.query())
+}
+
+// pageCursor returns the cursor of q and its sort. Without a cursor in q,
+// it is a cursor for the first page. A cursor must have been issued for
+// the sort and filters that q sets.
+func (s *SongService) pageCursor(q SongPageQuery) (songCursor, repository.SongSort, error) {
+	order, err := repository.ParseSongSort(q.Sort)
+	if err != nil {
+		return songCursor{}, nil, errors.Wrap(ErrValidation, err.Error())
+	}
+	cursor := songCursor{Sort: order.String(), Language: q.Language, BrokenLinks: q.BrokenLinks}
+	if !q.Filter.isZero() {
+		cursor.Filter = &q.Filter
+	}
+	if q.Cursor == "" {
+		return cursor, order, nil
+	}
+
+	if cursor, err = s.decodeCursor(q.Cursor); err != nil {
+		return cursor, nil, err
+	}
+	if q.Sort != "" && cursor.Sort != order.String() {
+		return cursor, nil, errors.Wrapf(ErrValidation, "the cursor was issued for sort %q", cursor.Sort)
+	}
+	if q.Language != "" && cursor.Language != q.Language {
+		return cursor, nil, errors.Wrapf(ErrValidation, "the cursor was issued for language %q", cursor.Language)
+	}
+	if q.BrokenLinks && !cursor.BrokenLinks {
+		return cursor, nil, errors.Wrap(ErrValidation, "the cursor was issued without broken_links")
+	}
+	if !q.Filter.isZero() && (cursor.Filter == nil || !q.Filter.equal(*cursor.Filter)) {
+		return cursor, nil, errors.Wrap(ErrValidation, "the cursor was issued for another filter")
+	}
+	if order, err = repository.ParseSongSort(cursor.Sort); err != nil {
+		return cursor, nil, errors.Wrap(ErrValidation, err.Error())
+	}
+	return cursor, order, nil
+}
+
+// ListArtists returns up to limit distinct artists in alphabetical order,
+// starting after the given one
+func (s *SongService) ListArtists(ctx context.Context, after string, limit int) ([]string, error) {
+	return s.reader(ctx).ListArtists(after, limit)
+}
+
+// CountArtists returns the number of distinct artists
+func (s *SongService) CountArtists(ctx context.Context) (int, error) {
+	return s.reader(ctx).CountArtists()
+}
+
+// encodeCursor serializes and signs cursor
+func (s *SongService) encodeCursor(cursor songCursor) string {
+	payload, _ := json.Marshal(cursor)
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(s.signCursor(payload))
+}
+
+// decodeCursor checks the signature of token and returns its cursor
+func (s *SongService) decodeCursor(token string) (songCursor, error) {
+	var cursor songCursor
+	invalid := errors.Wrap(ErrValidation, "invalid cursor")
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return cursor, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.signCursor(payload)) {
+		return cursor, invalid
+	}
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return cursor, invalid
+	}
+	return cursor, nil
+}
+
+func (s *SongService) signCursor(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.cursorKey)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// randomCursorKey returns a key for signing cursors when none is configured
+func randomCursorKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
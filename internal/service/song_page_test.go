@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSongService_ListSongsPage(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := context.Background()
+	for i, name := range []string{"Uprising", "Starlight", "Madness", "Hysteria", "Resistance"} {
+		repo.AddSong(&model.Song{GroupName: "Muse", SongName: name, ReleaseDate: time.Date(2000+i%3, 1, 1, 0, 0, 0, 0, time.UTC)})
+	}
+	names := func(page *SongPage) []string {
+		var names []string
+		for _, song := range page.Songs {
+			names = append(names, song.SongName)
+		}
+		return names
+	}
+
+	first, err := songService.ListSongsPage(ctx, SongPageQuery{Sort: "-release_date", Limit: 2})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Madness", "Starlight"}, names(first))
+	assert.Empty(t, first.Prev, "The first page should have no previous page")
+
+	second, err := songService.ListSongsPage(ctx, SongPageQuery{Limit: 2, Cursor: first.Next})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Resistance", "Uprising"}, names(second), "The cursor should keep its sort")
+
+	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Knights of Cydonia", ReleaseDate: time.Date(2003, 1, 1, 0, 0, 0, 0, time.UTC)})
+	last, err := songService.ListSongsPage(ctx, SongPageQuery{Limit: 2, Cursor: second.Next})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Hysteria"}, names(last), "Songs added before the cursor should not shift later pages")
+	assert.Empty(t, last.Next, "The last page should have no next page")
+
+	back, err := songService.ListSongsPage(ctx, SongPageQuery{Limit: 2, Cursor: last.Prev})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Resistance", "Uprising"}, names(back))
+	back, err = songService.ListSongsPage(ctx, SongPageQuery{Limit: 2, Cursor: back.Prev})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Madness", "Starlight"}, names(back))
+	back, _ = songService.ListSongsPage(ctx, SongPageQuery{Limit: 2, Cursor: back.Prev})
+	assert.Equal(t, []string{"Knights of Cydonia"}, names(back), "Going back should reach songs added at the start")
+	assert.Empty(t, back.Prev)
+	assert.NotEmpty(t, back.Next)
+
+	_, err = songService.ListSongsPage(ctx, SongPageQuery{Sort: "song_name", Cursor: first.Next})
+	assert.ErrorIs(t, err, ErrValidation, "A cursor should only be used with its own sort")
+	_, err = songService.ListSongsPage(ctx, SongPageQuery{Cursor: strings.Replace(first.Next, "a", "b", 1)})
+	assert.ErrorIs(t, err, ErrValidation, "Altered cursors should be rejected")
+	_, err = NewSongService(repo).ListSongsPage(ctx, SongPageQuery{Cursor: first.Next})
+	assert.ErrorIs(t, err, ErrValidation, "Cursors signed with another key should be rejected")
+	_, err = songService.ListSongsPage(ctx, SongPageQuery{Limit: MaxPageSize + 1})
+	assert.ErrorIs(t, err, ErrValidation)
+}
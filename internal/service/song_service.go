@@ -1,34 +1,516 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"song-library/internal/dedupe"
+	"song-library/internal/events"
+	"song-library/internal/language"
+	"song-library/internal/lyrics"
 	"song-library/internal/model"
 	"song-library/internal/repository"
+	"song-library/pkg/logger"
+	"strconv"
+
+	"github.com/pkg/errors"
 )
 
+// ErrValidation is returned when a song fails validation
+var ErrValidation = errors.New("validation failed")
+
 type SongService struct {
-	repo repository.SongRepository
+	repo        repository.SongRepository
+	replicas    *repository.ReplicaRouter
+	afterCommit []func()
+	cursorKey   []byte
 }
 
 func NewSongService(repo repository.SongRepository) *SongService {
-	return &SongService{repo: repo}
+	return &SongService{repo: repo, cursorKey: randomCursorKey()}
+}
+
+// AfterCommit registers fn to be called after every committed write, e.g.
+// to wake the outbox relay
+func (s *SongService) AfterCommit(fn func()) {
+	s.afterCommit = append(s.afterCommit, fn)
+}
+
+// UseReplicas serves song reads from the replicas of router
+func (s *SongService) UseReplicas(router *repository.ReplicaRouter) {
+	s.replicas = router
+}
+
+func (s *SongService) committed(ctx context.Context) {
+	if s.replicas != nil {
+		s.replicas.Wrote(clientKey(ctx))
+	}
+	for _, fn := range s.afterCommit {
+		fn()
+	}
+}
+
+// reader returns the repository that serves song reads for the client in
+// ctx: a replica when one is available, the primary otherwise. Replica
+// reads share the cache of the primary, if any.
+func (s *SongService) reader(ctx context.Context) repository.SongRepository {
+	if s.replicas != nil {
+		if repo, ok := s.replicas.Reader(clientKey(ctx)); ok {
+			repo = repository.CacheReplicaReads(s.repo, repo, s.replicas.Window())
+			return repository.WithContext(repo, ctx)
+		}
+	}
+	return s.store(ctx)
+}
+
+// store returns the primary repository with its queries bound to ctx
+func (s *SongService) store(ctx context.Context) repository.SongRepository {
+	return repository.WithContext(s.repo, ctx)
+}
+
+// clientKey identifies the client of ctx for read-your-writes routing
+func clientKey(ctx context.Context) string {
+	actor := ActorFromContext(ctx)
+	if actor.Name != AnonymousActor {
+		return "user:" + actor.Name
+	}
+	if actor.ClientIP != "" {
+		return "ip:" + actor.ClientIP
+	}
+	return ""
+}
+
+func (s *SongService) GetSongs(ctx context.Context) ([]model.Song, error) {
+	return s.reader(ctx).GetSongs()
+}
+
+// SongListQuery selects and orders the songs of a listing
+type SongListQuery struct {
+	// Sort is a sort order such as "release_date,-song_name"; empty sorts
+	// by ID
+	Sort string
+	// Language, such as "ru", selects the songs with lyrics in a language
+	Language string
+	// BrokenLinks selects the songs whose link failed its latest check
+	BrokenLinks bool
+}
+
+// ListSongs returns the songs selected by q
+func (s *SongService) ListSongs(ctx context.Context, q SongListQuery) ([]model.Song, error) {
+	if q == (SongListQuery{}) {
+		return s.GetSongs(ctx)
+	}
+	order, err := repository.ParseSongSort(q.Sort)
+	if err != nil {
+		return nil, errors.Wrap(ErrValidation, err.Error())
+	}
+	return s.reader(ctx).ListSongs(repository.SongQuery{Sort: order, Language: q.Language, BrokenLinks: q.BrokenLinks})
+}
+
+func (s *SongService) GetSongByID(ctx context.Context, id string) (*model.Song, error) {
+	return s.reader(ctx).GetSongByID(id)
+}
+
+// GetSongsByIDs returns the songs with the given IDs in a single query
+func (s *SongService) GetSongsByIDs(ctx context.Context, ids []string) ([]model.Song, error) {
+	return s.reader(ctx).GetSongsByIDs(ids)
+}
+
+// GetSongsByGroups returns the songs of the given groups in a single query
+func (s *SongService) GetSongsByGroups(ctx context.Context, groups []string) ([]model.Song, error) {
+	return s.reader(ctx).GetSongsByGroups(groups)
+}
+
+// AddSong stores a new song together with its first revision
+func (s *SongService) AddSong(ctx context.Context, song *model.Song) error {
+	if err := prepareLyrics(song); err != nil {
+		return err
+	}
+	actor := ActorFromContext(ctx)
+	err := s.store(ctx).Transaction(func(repo repository.SongRepository) error {
+		if err := checkDuplicate(repo, song.GroupName, song.SongName, 0); err != nil {
+			return err
+		}
+		if err := repo.AddSong(song); err != nil {
+			return err
+		}
+		if err := repo.AddRevision(model.NewSongRevision(song, actor.Name)); err != nil {
+			return err
+		}
+		if err := recordAudit(repo, actor, model.AuditActionCreate, song.ID, nil, song); err != nil {
+			return err
+		}
+		return recordEvent(repo, events.SongCreated, song)
+	})
+	if err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "Song created", logger.Fields{"song_id": song.ID})
+	s.committed(ctx)
+	return nil
+}
+
+// UpdateSong applies the non-empty fields of song and records a revision
+// when the stored song changed
+func (s *SongService) UpdateSong(ctx context.Context, id string, song *model.Song) error {
+	if err := prepareLyrics(song); err != nil {
+		return err
+	}
+	actor := ActorFromContext(ctx)
+	var after *model.Song
+	err := s.store(ctx).Transaction(func(repo repository.SongRepository) error {
+		before, err := repo.GetSongByID(id)
+		if err != nil {
+			return err
+		}
+		if err := ensureBaseRevision(repo, before); err != nil {
+			return err
+		}
+		if song.GroupName != "" || song.SongName != "" {
+			group, name := before.GroupName, before.SongName
+			if song.GroupName != "" {
+				group = song.GroupName
+			}
+			if song.SongName != "" {
+				name = song.SongName
+			}
+			if err := checkDuplicate(repo, group, name, before.ID); err != nil {
+				return err
+			}
+			song.NormalizedKey = dedupe.Key(group, name)
+		}
+		if song.Text != "" {
+			song.Language = language.Detect(song.Text)
+		}
+		if err := repo.UpdateSong(id, song); err != nil {
+			return err
+		}
+		if after, err = repo.GetSongByID(id); err != nil {
+			return err
+		}
+		if err := recordRevision(repo, after, actor.Name); err != nil {
+			return err
+		}
+		if err := recordAudit(repo, actor, model.AuditActionUpdate, after.ID, before, after); err != nil {
+			return err
+		}
+		return recordEvent(repo, events.SongUpdated, after)
+	})
+	if err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "Song updated", logger.Fields{"song_id": after.ID})
+	s.committed(ctx)
+	return nil
+}
+
+// DeleteSong removes a song and records the deleted state in the audit log
+func (s *SongService) DeleteSong(ctx context.Context, id string) error {
+	actor := ActorFromContext(ctx)
+	err := s.store(ctx).Transaction(func(repo repository.SongRepository) error {
+		before, err := repo.GetSongByID(id)
+		if err != nil {
+			return err
+		}
+		if err := repo.DeleteSong(id); err != nil {
+			return err
+		}
+		if err := recordAudit(repo, actor, model.AuditActionDelete, before.ID, before, nil); err != nil {
+			return err
+		}
+		return recordEvent(repo, events.SongDeleted, before)
+	})
+	if err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "Song deleted", logger.Fields{"song_id": id})
+	s.committed(ctx)
+	return nil
+}
+
+// GetLyrics returns the song together with its parsed LRC lyrics.
+// The LRC result is nil when the song has no synchronized lyrics.
+func (s *SongService) GetLyrics(ctx context.Context, id string) (*model.Song, *lyrics.LRC, error) {
+	song, err := s.reader(ctx).GetSongByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if song.LRC == "" {
+		return song, nil, nil
+	}
+	lrc, err := lyrics.ParseLRC(song.LRC)
+	if err != nil {
+		return nil, nil, err
+	}
+	return song, lrc, nil
+}
+
+// Transliteration is a song written in Latin script
+type Transliteration struct {
+	SongID    uint   `json:"song_id"`
+	Language  string `json:"language"`
+	GroupName string `json:"group_name"`
+	SongName  string `json:"song_name"`
+	Text      string `json:"text"`
+}
+
+// Transliterate returns the artist, title and lyrics of a song with their
+// Cyrillic and Greek letters written in Latin script
+func (s *SongService) Transliterate(ctx context.Context, id string) (*Transliteration, error) {
+	song, err := s.reader(ctx).GetSongByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return &Transliteration{
+		SongID:    song.ID,
+		Language:  song.Language,
+		GroupName: language.Transliterate(song.GroupName, song.Language),
+		SongName:  language.Transliterate(song.SongName, song.Language),
+		Text:      language.Transliterate(song.Text, song.Language),
+	}, nil
+}
+
+// GetRevisions returns the revision history of a song, oldest first. The
+// history is kept when the song is deleted, so it is only
+// ErrSongNotFound when there is neither a history nor a song.
+func (s *SongService) GetRevisions(ctx context.Context, id string) ([]model.SongRevision, error) {
+	revs, err := s.store(ctx).GetRevisions(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 0 {
+		if _, err := s.store(ctx).GetSongByID(id); err != nil {
+			return nil, err
+		}
+	}
+	return revs, nil
+}
+
+// FieldChange holds the old and new value of a changed song field
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// RevisionDiff describes the changes between two revisions of a song
+type RevisionDiff struct {
+	From   int                    `json:"from"`
+	To     int                    `json:"to"`
+	Fields map[string]FieldChange `json:"fields,omitempty"`
+	Text   []lyrics.DiffLine      `json:"text"`
+}
+
+// DiffRevisions compares two revisions of a song field by field. The lyrics
+// text is compared with a line-level diff instead.
+func (s *SongService) DiffRevisions(ctx context.Context, id string, from, to int) (*RevisionDiff, error) {
+	oldRev, err := s.store(ctx).GetRevision(id, from)
+	if err != nil {
+		return nil, err
+	}
+	newRev, err := s.store(ctx).GetRevision(id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]FieldChange{}
+	addChange := func(name string, a, b interface{}) {
+		if a != b {
+			fields[name] = FieldChange{From: a, To: b}
+		}
+	}
+	addChange("group_name", oldRev.GroupName, newRev.GroupName)
+	addChange("song_name", oldRev.SongName, newRev.SongName)
+	addChange("link", oldRev.Link, newRev.Link)
+	addChange("lrc", oldRev.LRC, newRev.LRC)
+	addChange("chordpro", oldRev.ChordPro, newRev.ChordPro)
+	if !oldRev.ReleaseDate.Equal(newRev.ReleaseDate) {
+		fields["release_date"] = FieldChange{From: oldRev.ReleaseDate, To: newRev.ReleaseDate}
+	}
+
+	return &RevisionDiff{
+		From:   from,
+		To:     to,
+		Fields: fields,
+		Text:   lyrics.DiffLines(oldRev.Text, newRev.Text),
+	}, nil
+}
+
+// RestoreRevision overwrites a song with the contents of one of its
+// revisions. The restore itself is recorded as a new revision.
+func (s *SongService) RestoreRevision(ctx context.Context, id string, revision int) (*model.Song, error) {
+	actor := ActorFromContext(ctx)
+	var restored *model.Song
+	err := s.store(ctx).Transaction(func(repo repository.SongRepository) error {
+		before, err := repo.GetSongByID(id)
+		if err != nil {
+			return err
+		}
+		rev, err := repo.GetRevision(id, revision)
+		if err != nil {
+			return err
+		}
+		song := *before
+		rev.ApplyTo(&song)
+		if err := repo.SaveSong(&song); err != nil {
+			return err
+		}
+		if err := recordRevision(repo, &song, actor.Name); err != nil {
+			return err
+		}
+		restored = &song
+		if err := recordAudit(repo, actor, model.AuditActionRestore, song.ID, before, &song); err != nil {
+			return err
+		}
+		return recordEvent(repo, events.SongUpdated, &song)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "Song restored", logger.Fields{"song_id": restored.ID, "revision": revision})
+	s.committed(ctx)
+	return restored, nil
+}
+
+// Audit log page sizes
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// GetAuditLog returns audit entries matching filter, newest first. A limit
+// below 1 returns DefaultAuditLimit entries and one above MaxAuditLimit is
+// capped.
+func (s *SongService) GetAuditLog(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	if filter.Offset < 0 {
+		return nil, errors.Wrap(ErrValidation, "offset must not be negative")
+	}
+	if filter.Limit < 1 {
+		filter.Limit = DefaultAuditLimit
+	}
+	filter.Limit = min(filter.Limit, MaxAuditLimit)
+	return s.store(ctx).GetAuditEntries(filter)
+}
+
+// checkDuplicate returns ErrDuplicateSong when a song other than id has
+// the same normalized artist and title
+func checkDuplicate(repo repository.SongRepository, group, name string, id uint) error {
+	existing, err := repo.GetSongByKey(dedupe.Key(group, name))
+	if errors.Is(err, repository.ErrSongNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID == id {
+		return nil
+	}
+	return errors.Wrapf(repository.ErrDuplicateSong, "same artist and title as song %d", existing.ID)
+}
+
+// ensureBaseRevision snapshots songs created before revision tracking so
+// their original content is not lost on the first update
+func ensureBaseRevision(repo repository.SongRepository, song *model.Song) error {
+	revs, err := repo.GetRevisions(strconv.FormatUint(uint64(song.ID), 10))
+	if err != nil {
+		return err
+	}
+	if len(revs) > 0 {
+		return nil
+	}
+	return repo.AddRevision(model.NewSongRevision(song, ""))
+}
+
+// recordRevision stores the current state of a song as a new revision
+// unless it matches the latest one
+func recordRevision(repo repository.SongRepository, song *model.Song, author string) error {
+	rev := model.NewSongRevision(song, author)
+	revs, err := repo.GetRevisions(strconv.FormatUint(uint64(song.ID), 10))
+	if err != nil {
+		return err
+	}
+	if len(revs) > 0 && revs[len(revs)-1].SameContent(rev) {
+		return nil
+	}
+	return repo.AddRevision(rev)
 }
 
-func (s *SongService) GetSongs() ([]model.Song, error) {
-	return s.repo.GetSongs()
+// recordAudit appends an audit entry with the song state before and after
+// a write. Either state may be nil.
+func recordAudit(repo repository.SongRepository, actor Actor, action string, songID uint, before, after *model.Song) error {
+	entry, err := newAuditEntry(actor, action, songID, before, after)
+	if err != nil {
+		return err
+	}
+	return repo.AddAuditEntry(entry)
 }
 
-func (s *SongService) GetSongByID(id string) (*model.Song, error) {
-	return s.repo.GetSongByID(id)
+// recordEvent writes a song lifecycle event to the outbox, to be published
+// by the relay once the transaction commits
+func recordEvent(repo repository.SongRepository, eventType string, song *model.Song) error {
+	event, err := newOutboxEvent(eventType, song)
+	if err != nil {
+		return err
+	}
+	return repo.AddOutboxEvent(event)
 }
 
-func (s *SongService) AddSong(song *model.Song) error {
-	return s.repo.AddSong(song)
+// newAuditEntry builds the audit entry of a write
+func newAuditEntry(actor Actor, action string, songID uint, before, after *model.Song) (*model.AuditEntry, error) {
+	entry := &model.AuditEntry{
+		Action:    action,
+		SongID:    songID,
+		Actor:     actor.Name,
+		RequestID: actor.RequestID,
+		ClientIP:  actor.ClientIP,
+	}
+	var err error
+	if before != nil {
+		if entry.Before, err = model.NewJSON(before); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		if entry.After, err = model.NewJSON(after); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
 }
 
-func (s *SongService) UpdateSong(id string, song *model.Song) error {
-	return s.repo.UpdateSong(id, song)
+// newOutboxEvent builds the outbox record of a song lifecycle event
+func newOutboxEvent(eventType string, song *model.Song) (*model.OutboxEvent, error) {
+	e := events.NewEvent(eventType, song)
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OutboxEvent{
+		EventID:   e.ID,
+		EventType: e.Type,
+		SongID:    e.SongID,
+		Payload:   string(payload),
+	}, nil
 }
 
-func (s *SongService) DeleteSong(id string) error {
-	return s.repo.DeleteSong(id)
+// prepareLyrics validates LRC lyrics and the ChordPro chord sheet and
+// derives the plain text from them when no text was supplied
+func prepareLyrics(song *model.Song) error {
+	if song.LRC != "" {
+		lrc, err := lyrics.ParseLRC(song.LRC)
+		if err != nil {
+			return errors.Wrap(ErrValidation, err.Error())
+		}
+		if song.Text == "" {
+			song.Text = lrc.PlainText()
+		}
+	}
+	if song.ChordPro != "" {
+		sheet, err := lyrics.ParseChordPro(song.ChordPro)
+		if err != nil {
+			return errors.Wrap(ErrValidation, err.Error())
+		}
+		if song.Text == "" {
+			song.Text = sheet.PlainText()
+		}
+	}
+	return nil
 }
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"context"
+	"song-library/internal/db"
+	"song-library/internal/events"
 	"song-library/internal/model"
 	"song-library/internal/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -12,9 +16,9 @@ import (
 
 // setupTestRepository creates an in-memory database for testing
 func setupTestRepository() repository.SongRepository {
-	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&model.Song{})
-	return repository.NewSongRepository(db)
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
+	return repository.NewSongRepository(conn)
 }
 
 func TestSongService_AddSong(t *testing.T) {
@@ -26,10 +30,10 @@ func TestSongService_AddSong(t *testing.T) {
 		SongName:  "Supermassive Black Hole",
 	}
 
-	err := songService.AddSong(song)
+	err := songService.AddSong(context.Background(), song)
 	assert.Nil(t, err, "Adding song should not return an error")
 
-	songs, _ := songService.GetSongs()
+	songs, _ := songService.GetSongs(context.Background())
 	assert.Len(t, songs, 1, "There should be one song in the database")
 	assert.Equal(t, "Supermassive Black Hole", songs[0].SongName, "Song name should match")
 }
@@ -41,8 +45,161 @@ func TestSongService_GetSongs(t *testing.T) {
 	// Insert test data
 	repo.AddSong(&model.Song{GroupName: "Muse", SongName: "Supermassive Black Hole"})
 
-	songs, err := songService.GetSongs()
+	songs, err := songService.GetSongs(context.Background())
 	assert.Nil(t, err, "Fetching songs should not return an error")
 	assert.Len(t, songs, 1, "There should be one song in the database")
 	assert.Equal(t, "Supermassive Black Hole", songs[0].SongName, "Song name should match")
 }
+
+func TestSongService_AddSong_DerivesTextFromLRC(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+
+	song := &model.Song{
+		GroupName: "Muse",
+		SongName:  "Starlight",
+		LRC:       "[00:05.00]Far away\n[00:09.00]This ship is taking me",
+	}
+	err := songService.AddSong(context.Background(), song)
+	assert.Nil(t, err, "Adding song with valid LRC should not return an error")
+	assert.Equal(t, "Far away\nThis ship is taking me", song.Text, "Text should be derived from LRC")
+
+	err = songService.AddSong(context.Background(), &model.Song{GroupName: "Muse", SongName: "Uprising", LRC: "not lrc"})
+	assert.ErrorIs(t, err, ErrValidation, "Invalid LRC should be rejected")
+}
+
+func TestSongService_ChordSheet(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := context.Background()
+
+	song := &model.Song{GroupName: "Muse", SongName: "Starlight", ChordPro: "{capo: 2}\n[Bm]Far a[G]way\n\n[D]My life"}
+	assert.Nil(t, songService.AddSong(ctx, song))
+	assert.Equal(t, "Far away\n\nMy life", song.Text, "Text should be derived from the chord sheet")
+	err := songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Uprising", ChordPro: "[Bm Far away"})
+	assert.ErrorIs(t, err, ErrValidation, "Invalid chord sheets should be rejected")
+
+	capo := 0
+	_, sheet, err := songService.GetChordSheet(ctx, "1", ChordOptions{Transpose: -1, Capo: &capo})
+	assert.Nil(t, err)
+	assert.Equal(t, "Eb", sheet.Sections[1].Lines[0].Chords[0].Chord, "D with capo 2 sounds as E, one semitone lower is Eb")
+	_, _, err = songService.GetChordSheet(ctx, "1", ChordOptions{Transpose: 20})
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestSongService_Language(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := context.Background()
+
+	song := &model.Song{GroupName: "Кино", SongName: "Группа крови", Text: "Тёплое место, но улицы ждут"}
+	assert.Nil(t, songService.AddSong(ctx, song))
+	assert.Equal(t, "ru", song.Language, "The language should be detected when the song is added")
+
+	assert.Nil(t, songService.UpdateSong(ctx, "1", &model.Song{Text: "Warm place, but the streets are waiting for you"}))
+	updated, _ := songService.GetSongByID(ctx, "1")
+	assert.Equal(t, "en", updated.Language, "The language should be detected again when the text changes")
+
+	latin, err := songService.Transliterate(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Kino", latin.GroupName)
+	assert.Equal(t, "Gruppa krovi", latin.SongName)
+}
+
+func TestSongService_RevisionsAndRestore(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := WithActor(context.Background(), Actor{Name: "alice"})
+
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Starlight", Text: "Far away"})
+	err := songService.UpdateSong(ctx, "1", &model.Song{Text: "Far away\nThis ship is taking me"})
+	assert.Nil(t, err, "Updating song should not return an error")
+	songService.UpdateSong(ctx, "1", &model.Song{Text: "Far away\nThis ship is taking me"})
+
+	revs, _ := songService.GetRevisions(ctx, "1")
+	assert.Len(t, revs, 2, "Unchanged updates should not create revisions")
+	assert.Equal(t, "alice", revs[1].Author, "Revision should record the author")
+
+	diff, err := songService.DiffRevisions(ctx, "1", 1, 2)
+	assert.Nil(t, err, "Diffing revisions should not return an error")
+	assert.Equal(t, "insert", diff.Text[1].Op, "Added line should be reported as insert")
+
+	song, err := songService.RestoreRevision(ctx, "1", 1)
+	assert.Nil(t, err, "Restoring revision should not return an error")
+	assert.Equal(t, "Far away", song.Text, "Text should be restored")
+
+	revs, _ = songService.GetRevisions(ctx, "1")
+	assert.Len(t, revs, 3, "Restore should be recorded as a new revision")
+
+	assert.Nil(t, songService.DeleteSong(ctx, "1"))
+	revs, err = songService.GetRevisions(ctx, "1")
+	assert.Nil(t, err, "The history of a deleted song should be kept")
+	assert.Len(t, revs, 3)
+	_, err = songService.GetRevisions(ctx, "42")
+	assert.ErrorIs(t, err, repository.ErrSongNotFound)
+}
+
+func TestSongService_AuditLog(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := WithActor(context.Background(), Actor{Name: "alice", RequestID: "req-1", ClientIP: "10.0.0.1"})
+
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Starlight"})
+	songService.UpdateSong(ctx, "1", &model.Song{SongName: "Uprising"})
+	songService.DeleteSong(ctx, "1")
+
+	entries, err := songService.GetAuditLog(ctx, repository.AuditFilter{SongID: "1"})
+	assert.Nil(t, err, "Fetching audit log should not return an error")
+	assert.Len(t, entries, 3, "Every write should be audited")
+	assert.Equal(t, model.AuditActionDelete, entries[0].Action, "Newest entry should come first")
+	assert.Equal(t, "req-1", entries[0].RequestID, "Request ID should be recorded")
+	assert.Contains(t, string(entries[1].Before), "Starlight", "Update should record the old state")
+	assert.Contains(t, string(entries[1].After), "Uprising", "Update should record the new state")
+
+	err = songService.DeleteSong(ctx, "1")
+	assert.ErrorIs(t, err, repository.ErrSongNotFound, "Deleting a missing song should fail")
+	entries, _ = songService.GetAuditLog(ctx, repository.AuditFilter{Action: model.AuditActionDelete})
+	assert.Len(t, entries, 1, "Failed writes should not be audited")
+}
+
+func TestSongService_WritesOutboxEvents(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	commits := 0
+	songService.AfterCommit(func() { commits++ })
+
+	ctx := context.Background()
+	songService.AddSong(ctx, &model.Song{GroupName: "Muse", SongName: "Starlight"})
+	songService.UpdateSong(ctx, "1", &model.Song{SongName: "Uprising"})
+	songService.DeleteSong(ctx, "1")
+	songService.DeleteSong(ctx, "1")
+
+	pending, _ := repo.GetPendingOutboxEvents(10)
+	assert.Len(t, pending, 3, "Only successful writes should add outbox events")
+	assert.Equal(t, 3, commits, "Only successful writes should notify")
+	assert.Equal(t, events.SongUpdated, pending[1].EventType)
+	assert.Contains(t, pending[1].Payload, "Uprising", "Event should carry the updated song")
+	assert.Equal(t, events.SongDeleted, pending[2].EventType)
+}
+
+func TestSongService_ReadsFromReplicas(t *testing.T) {
+	primary, replica := setupTestRepository(), setupTestRepository()
+	songService := NewSongService(primary)
+	songService.UseReplicas(repository.NewReplicaRouter([]repository.Replica{
+		{Name: "replica", Repo: replica, Ping: func(context.Context) error { return nil }},
+	}, time.Minute))
+
+	alice := WithActor(context.Background(), Actor{Name: "alice"})
+	bob := WithActor(context.Background(), Actor{Name: "bob"})
+	song := &model.Song{GroupName: "Muse", SongName: "Uprising"}
+	assert.Nil(t, songService.AddSong(alice, song))
+
+	songs, err := songService.GetSongs(alice)
+	assert.Nil(t, err)
+	assert.Len(t, songs, 1, "The writer should read its own write from the primary")
+
+	// The replica has not caught up yet
+	songs, err = songService.GetSongs(bob)
+	assert.Nil(t, err)
+	assert.Empty(t, songs, "Other clients should read from the replica")
+}
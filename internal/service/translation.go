@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/lyrics"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/pkg/logger"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/language"
+)
+
+// ErrNoAcceptableTranslation is returned when a song has no translation
+// into any of the languages the client accepts
+var ErrNoAcceptableTranslation = errors.New("no translation in an acceptable language")
+
+// TranslationInput is the content of a translation. The verses are given
+// either as a list or as text with verses separated by blank lines.
+type TranslationInput struct {
+	Verses     []string `json:"verses"`
+	Text       string   `json:"text"`
+	Translator string   `json:"translator"`
+}
+
+// VersePair is a verse of a song next to its translation
+type VersePair struct {
+	// Number counts verses from 1
+	Number      int    `json:"number"`
+	Original    string `json:"original"`
+	Translation string `json:"translation"`
+}
+
+// SideBySide is a song with its lyrics paired verse by verse with a
+// translation
+type SideBySide struct {
+	SongID              uint   `json:"song_id"`
+	GroupName           string `json:"group_name"`
+	SongName            string `json:"song_name"`
+	Language            string `json:"language"`
+	TranslationLanguage string `json:"translation_language"`
+	Translator          string `json:"translator"`
+	// Aligned is false when the lyrics changed their number of verses
+	// since the translation was written; unmatched verses are paired with
+	// an empty string
+	Aligned bool        `json:"aligned"`
+	Verses  []VersePair `json:"verses"`
+}
+
+// ParseLanguageTag validates a BCP 47 language tag and returns it in
+// canonical form, such as "pt-BR" for "pt_br"
+func ParseLanguageTag(tag string) (string, error) {
+	parsed, err := language.Parse(strings.ReplaceAll(tag, "_", "-"))
+	if err != nil || parsed == language.Und {
+		return "", errors.Wrapf(ErrValidation, "%q is not a language tag", tag)
+	}
+	return parsed.String(), nil
+}
+
+// GetTranslations returns the translations of a song ordered by language
+func (s *SongService) GetTranslations(ctx context.Context, id string) ([]model.SongTranslation, error) {
+	repo := s.reader(ctx)
+	if _, err := repo.GetSongByID(id); err != nil {
+		return nil, err
+	}
+	return repo.GetTranslations(id)
+}
+
+// GetTranslation returns the translation of a song into lang
+func (s *SongService) GetTranslation(ctx context.Context, id, lang string) (*model.SongTranslation, error) {
+	tag, err := ParseLanguageTag(lang)
+	if err != nil {
+		return nil, err
+	}
+	repo := s.reader(ctx)
+	if _, err := repo.GetSongByID(id); err != nil {
+		return nil, err
+	}
+	return repo.GetTranslation(id, tag)
+}
+
+// SaveTranslation creates or replaces the translation of a song into lang.
+// It must have as many verses as the lyrics of the song. created reports
+// whether the song had no translation into lang before.
+func (s *SongService) SaveTranslation(ctx context.Context, id, lang string, input TranslationInput) (translation *model.SongTranslation, created bool, err error) {
+	tag, err := ParseLanguageTag(lang)
+	if err != nil {
+		return nil, false, err
+	}
+	verses, err := translationVerses(input)
+	if err != nil {
+		return nil, false, err
+	}
+	err = s.store(ctx).Transaction(func(repo repository.SongRepository) error {
+		song, err := repo.GetSongByID(id)
+		if err != nil {
+			return err
+		}
+		original := lyrics.Verses(song.Text)
+		if len(original) == 0 {
+			return errors.Wrap(ErrValidation, "the song has no lyrics to translate")
+		}
+		if len(verses) != len(original) {
+			return errors.Wrapf(ErrValidation, "the lyrics have %d verses, the translation %d", len(original), len(verses))
+		}
+		translation = &model.SongTranslation{SongID: song.ID, Language: tag, Verses: verses, Translator: input.Translator}
+		existing, err := repo.GetTranslation(id, tag)
+		switch {
+		case err == nil:
+			translation.CreatedAt = existing.CreatedAt
+		case errors.Is(err, repository.ErrTranslationNotFound):
+			created = true
+		default:
+			return err
+		}
+		return repo.SaveTranslation(translation)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	logger.InfoContext(ctx, "Translation saved", logger.Fields{"song_id": translation.SongID, "language": tag})
+	s.committed(ctx)
+	return translation, created, nil
+}
+
+// DeleteTranslation deletes the translation of a song into lang
+func (s *SongService) DeleteTranslation(ctx context.Context, id, lang string) error {
+	tag, err := ParseLanguageTag(lang)
+	if err != nil {
+		return err
+	}
+	err = s.store(ctx).Transaction(func(repo repository.SongRepository) error {
+		if _, err := repo.GetSongByID(id); err != nil {
+			return err
+		}
+		return repo.DeleteTranslation(id, tag)
+	})
+	if err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "Translation deleted", logger.Fields{"song_id": id, "language": tag})
+	s.committed(ctx)
+	return nil
+}
+
+// SideBySide pairs the lyrics of a song with a translation. lang selects
+// the translation; when it is empty the translation is negotiated from
+// acceptLanguage, an Accept-Language header value. Without either the
+// first translation is used.
+func (s *SongService) SideBySide(ctx context.Context, id, lang, acceptLanguage string) (*SideBySide, error) {
+	repo := s.reader(ctx)
+	song, err := repo.GetSongByID(id)
+	if err != nil {
+		return nil, err
+	}
+	var translation *model.SongTranslation
+	if lang != "" {
+		tag, err := ParseLanguageTag(lang)
+		if err != nil {
+			return nil, err
+		}
+		if translation, err = repo.GetTranslation(id, tag); err != nil {
+			return nil, err
+		}
+	} else {
+		translations, err := repo.GetTranslations(id)
+		if err != nil {
+			return nil, err
+		}
+		if translation, err = negotiateTranslation(translations, acceptLanguage); err != nil {
+			return nil, err
+		}
+	}
+
+	original := lyrics.Verses(song.Text)
+	view := &SideBySide{
+		SongID:              song.ID,
+		GroupName:           song.GroupName,
+		SongName:            song.SongName,
+		Language:            song.Language,
+		TranslationLanguage: translation.Language,
+		Translator:          translation.Translator,
+		Aligned:             len(original) == len(translation.Verses),
+		Verses:              []VersePair{},
+	}
+	for i := 0; i < max(len(original), len(translation.Verses)); i++ {
+		pair := VersePair{Number: i + 1}
+		if i < len(original) {
+			pair.Original = original[i]
+		}
+		if i < len(translation.Verses) {
+			pair.Translation = translation.Verses[i]
+		}
+		view.Verses = append(view.Verses, pair)
+	}
+	return view, nil
+}
+
+// negotiateTranslation picks the translation that best matches the
+// languages of an Accept-Language header value
+func negotiateTranslation(translations []model.SongTranslation, acceptLanguage string) (*model.SongTranslation, error) {
+	if len(translations) == 0 {
+		return nil, repository.ErrTranslationNotFound
+	}
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return &translations[0], nil
+	}
+	prefs, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return nil, errors.Wrap(ErrValidation, "invalid Accept-Language header")
+	}
+	tags := make([]language.Tag, len(translations))
+	for i, translation := range translations {
+		tags[i] = language.Make(translation.Language)
+	}
+	_, index, confidence := language.NewMatcher(tags).Match(prefs...)
+	if confidence == language.No {
+		// "*" accepts any language
+		for _, pref := range prefs {
+			if pref == language.Und {
+				return &translations[0], nil
+			}
+		}
+		return nil, ErrNoAcceptableTranslation
+	}
+	return &translations[index], nil
+}
+
+// translationVerses returns the verses of input, split from its text when
+// no list is given
+func translationVerses(input TranslationInput) ([]string, error) {
+	if len(input.Verses) > 0 && input.Text != "" {
+		return nil, errors.Wrap(ErrValidation, "give either verses or text, not both")
+	}
+	if len(input.Verses) == 0 {
+		input.Verses = lyrics.Verses(input.Text)
+	}
+	if len(input.Verses) == 0 {
+		return nil, errors.Wrap(ErrValidation, "the translation has no verses")
+	}
+	verses := make([]string, len(input.Verses))
+	for i, verse := range input.Verses {
+		verses[i] = strings.TrimSpace(strings.ReplaceAll(verse, "\r\n", "\n"))
+		if verses[i] == "" {
+			return nil, errors.Wrapf(ErrValidation, "verse %d is empty", i+1)
+		}
+		if len(lyrics.Verses(verses[i])) > 1 {
+			return nil, errors.Wrapf(ErrValidation, "verse %d contains a blank line", i+1)
+		}
+	}
+	return verses, nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"song-library/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSongService_Translations(t *testing.T) {
+	repo := setupTestRepository()
+	songService := NewSongService(repo)
+	ctx := context.Background()
+	songService.AddSong(ctx, &model.Song{GroupName: "Кино", SongName: "Кукушка", Text: "Песен ещё ненаписанных\nсколько\n\nСкажи, кукушка"})
+
+	_, created, err := songService.SaveTranslation(ctx, "1", "en", TranslationInput{Text: "How many songs\nstill unwritten\n\nTell me, cuckoo"})
+	require.Nil(t, err)
+	assert.True(t, created)
+	translation, created, err := songService.SaveTranslation(ctx, "1", "pt_br", TranslationInput{Verses: []string{"Quantas canções", "Diga, cuco"}, Translator: "ana"})
+	require.Nil(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "pt-BR", translation.Language, "Language tags should be canonicalized")
+	_, created, _ = songService.SaveTranslation(ctx, "1", "en", TranslationInput{Verses: []string{"How many songs", "Tell me, cuckoo"}})
+	assert.False(t, created, "Saving a language again should replace its translation")
+
+	_, _, err = songService.SaveTranslation(ctx, "1", "de", TranslationInput{Verses: []string{"Wie viele Lieder"}})
+	assert.ErrorIs(t, err, ErrValidation, "A translation should have as many verses as the lyrics")
+	_, _, err = songService.SaveTranslation(ctx, "1", "not a tag", TranslationInput{Verses: []string{"a", "b"}})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	view, err := songService.SideBySide(ctx, "1", "", "pt, en;q=0.5")
+	require.Nil(t, err)
+	assert.Equal(t, "pt-BR", view.TranslationLanguage, "Accept-Language should pick the translation")
+	assert.True(t, view.Aligned)
+	assert.Equal(t, VersePair{Number: 2, Original: "Скажи, кукушка", Translation: "Diga, cuco"}, view.Verses[1])
+	view, _ = songService.SideBySide(ctx, "1", "", "fr, en-GB;q=0.8")
+	assert.Equal(t, "en", view.TranslationLanguage)
+	_, err = songService.SideBySide(ctx, "1", "", "fr")
+	assert.ErrorIs(t, err, ErrNoAcceptableTranslation)
+
+	songService.UpdateSong(ctx, "1", &model.Song{Text: "Песен ещё ненаписанных\n\nСкажи, кукушка\n\nПропой"})
+	view, _ = songService.SideBySide(ctx, "1", "en", "")
+	assert.False(t, view.Aligned, "Changing the number of verses should break the alignment")
+	assert.Equal(t, VersePair{Number: 3, Original: "Пропой"}, view.Verses[2])
+}
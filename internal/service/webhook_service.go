@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"song-library/internal/events"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type WebhookService struct {
+	repo repository.WebhookRepository
+}
+
+func NewWebhookService(repo repository.WebhookRepository) *WebhookService {
+	return &WebhookService{repo: repo}
+}
+
+// GetWebhooks returns all subscriptions. Secrets are never returned.
+func (s *WebhookService) GetWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	webhooks, err := s.repo.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhookByID(ctx context.Context, id string) (*model.Webhook, error) {
+	webhook, err := s.repo.GetWebhookByID(id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// AddWebhook creates a subscription. A signing secret is generated when
+// none is given; the returned webhook is the only place it is shown.
+func (s *WebhookService) AddWebhook(ctx context.Context, webhook *model.Webhook) error {
+	if err := validateWebhook(webhook); err != nil {
+		return err
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = newSecret()
+	}
+	return s.repo.AddWebhook(webhook)
+}
+
+// UpdateWebhook replaces the URL, events and active flag of a subscription.
+// The secret is rotated only when a new one is given.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id string, update *model.Webhook) (*model.Webhook, error) {
+	if err := validateWebhook(update); err != nil {
+		return nil, err
+	}
+	webhook, err := s.repo.GetWebhookByID(id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.URL = update.URL
+	webhook.Events = update.Events
+	webhook.Active = update.Active
+	if update.Secret != "" {
+		webhook.Secret = update.Secret
+	}
+	if err := s.repo.SaveWebhook(webhook); err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	return s.repo.DeleteWebhook(id)
+}
+
+// GetDeliveries returns the delivery log of a webhook, newest first,
+// optionally filtered by status
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID, status string) ([]model.WebhookDelivery, error) {
+	if _, err := s.repo.GetWebhookByID(webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeliveries(webhookID, status)
+}
+
+// GetDeadLetters returns deliveries of all webhooks that ran out of attempts
+func (s *WebhookService) GetDeadLetters(ctx context.Context) ([]model.WebhookDelivery, error) {
+	return s.repo.GetDeliveries("", model.DeliveryDead)
+}
+
+// Redeliver moves a dead delivery back to the queue with a fresh set of
+// attempts
+func (s *WebhookService) Redeliver(ctx context.Context, deliveryID string) (*model.WebhookDelivery, error) {
+	delivery, err := s.repo.GetDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status != model.DeliveryDead {
+		return nil, errors.Wrap(ErrValidation, "only dead deliveries can be redelivered")
+	}
+	delivery.Status = model.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := s.repo.SaveDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func validateWebhook(webhook *model.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Wrap(ErrValidation, "url must be an absolute http or https URL")
+	}
+	if len(webhook.Events) == 0 {
+		return errors.Wrap(ErrValidation, "at least one event is required")
+	}
+	for _, e := range webhook.Events {
+		if !events.IsType(e) {
+			return errors.Wrapf(ErrValidation, "unknown event %q", e)
+		}
+	}
+	return nil
+}
+
+func newSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
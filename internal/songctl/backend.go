@@ -0,0 +1,52 @@
+package songctl
+
+import (
+	"context"
+	"song-library/internal/model"
+	"song-library/internal/service"
+)
+
+// Backend performs song operations either directly against the database or
+// through the REST API of a running server
+type Backend interface {
+	// List returns all songs ordered by sort, such as "-release_date"
+	List(ctx context.Context, sort string) ([]model.Song, error)
+	Get(ctx context.Context, id string) (*model.Song, error)
+	Add(ctx context.Context, song *model.Song) error
+	// Update applies the non-empty fields of song and returns the result
+	Update(ctx context.Context, id string, song *model.Song) (*model.Song, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// localBackend calls SongService directly
+type localBackend struct {
+	songService *service.SongService
+}
+
+// NewLocalBackend creates a Backend using songService
+func NewLocalBackend(songService *service.SongService) Backend {
+	return &localBackend{songService: songService}
+}
+
+func (b *localBackend) List(ctx context.Context, sort string) ([]model.Song, error) {
+	return b.songService.ListSongs(ctx, service.SongListQuery{Sort: sort})
+}
+
+func (b *localBackend) Get(ctx context.Context, id string) (*model.Song, error) {
+	return b.songService.GetSongByID(ctx, id)
+}
+
+func (b *localBackend) Add(ctx context.Context, song *model.Song) error {
+	return b.songService.AddSong(ctx, song)
+}
+
+func (b *localBackend) Update(ctx context.Context, id string, song *model.Song) (*model.Song, error) {
+	if err := b.songService.UpdateSong(ctx, id, song); err != nil {
+		return nil, err
+	}
+	return b.songService.GetSongByID(ctx, id)
+}
+
+func (b *localBackend) Delete(ctx context.Context, id string) error {
+	return b.songService.DeleteSong(ctx, id)
+}
@@ -0,0 +1,39 @@
+package songctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"song-library/internal/model"
+	"text/tabwriter"
+)
+
+// print writes songs in the configured output format
+func (a *App) print(songs []model.Song) error {
+	if a.Output == OutputJSON {
+		return writeJSON(a.Stdout, songs)
+	}
+	return writeTable(a.Stdout, songs)
+}
+
+func writeJSON(w io.Writer, songs []model.Song) error {
+	if songs == nil {
+		songs = []model.Song{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(songs)
+}
+
+func writeTable(w io.Writer, songs []model.Song) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tGROUP\tSONG\tRELEASED\tLINK")
+	for _, song := range songs {
+		released := "-"
+		if !song.ReleaseDate.IsZero() {
+			released = song.ReleaseDate.Format("2006-01-02")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", song.ID, song.GroupName, song.SongName, released, song.Link)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,126 @@
+package songctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"song-library/internal/handler"
+	"song-library/internal/middleware"
+	"song-library/internal/model"
+	"strings"
+)
+
+// APIError is an error response of the REST API
+type APIError struct {
+	Status  int
+	Message string
+	Details string
+}
+
+func (e *APIError) Error() string {
+	if e.Details == "" {
+		return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+	}
+	return fmt.Sprintf("%s: %s (HTTP %d)", e.Message, e.Details, e.Status)
+}
+
+// remoteBackend calls the REST API of a running server
+type remoteBackend struct {
+	baseURL string
+	client  *http.Client
+	user    string
+}
+
+// NewRemoteBackend creates a Backend for the server at baseURL. Requests are
+// sent with user in the X-User header.
+func NewRemoteBackend(baseURL string, client *http.Client, user string) Backend {
+	return &remoteBackend{
+		baseURL: strings.TrimSuffix(baseURL, "/") + "/api/v1/songs",
+		client:  client,
+		user:    user,
+	}
+}
+
+func (b *remoteBackend) List(ctx context.Context, sort string) ([]model.Song, error) {
+	path := ""
+	if sort != "" {
+		path = "?" + url.Values{"sort": {sort}}.Encode()
+	}
+	var songs []model.Song
+	if err := b.do(ctx, http.MethodGet, path, nil, &songs); err != nil {
+		return nil, err
+	}
+	return songs, nil
+}
+
+func (b *remoteBackend) Get(ctx context.Context, id string) (*model.Song, error) {
+	var song model.Song
+	if err := b.do(ctx, http.MethodGet, "/"+url.PathEscape(id), nil, &song); err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
+
+func (b *remoteBackend) Add(ctx context.Context, song *model.Song) error {
+	return b.do(ctx, http.MethodPost, "", song, song)
+}
+
+func (b *remoteBackend) Update(ctx context.Context, id string, song *model.Song) (*model.Song, error) {
+	if err := b.do(ctx, http.MethodPut, "/"+url.PathEscape(id), song, nil); err != nil {
+		return nil, err
+	}
+	// The server echoes the request, so fetch the stored song
+	return b.Get(ctx, id)
+}
+
+func (b *remoteBackend) Delete(ctx context.Context, id string) error {
+	return b.do(ctx, http.MethodDelete, "/"+url.PathEscape(id), nil, nil)
+}
+
+// do sends a request and decodes the data of the response into dst
+func (b *remoteBackend) do(ctx context.Context, method, path string, body, dst interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.user != "" {
+		req.Header.Set(middleware.UserHeader, b.user)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{Status: resp.StatusCode, Message: resp.Status}
+		var errResp handler.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != "" {
+			apiErr.Message, apiErr.Details = errResp.Error, errResp.Details
+		}
+		return apiErr
+	}
+	if dst == nil {
+		return nil
+	}
+	envelope := struct {
+		Data interface{} `json:"data"`
+	}{Data: dst}
+	return json.NewDecoder(resp.Body).Decode(&envelope)
+}
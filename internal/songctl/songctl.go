@@ -0,0 +1,353 @@
+// Package songctl implements the songctl admin tool
+package songctl
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"song-library/config"
+	"song-library/internal/db"
+	"song-library/internal/model"
+	"song-library/internal/service"
+	"song-library/internal/storage"
+	"song-library/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Output formats
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// ServerEnv names the environment variable holding the default -server URL
+const ServerEnv = "SONGCTL_SERVER"
+
+// errUsage is returned for invalid command lines
+var errUsage = errors.New("invalid usage")
+
+const usage = `Usage: songctl [flags] <command> [arguments]
+
+Commands:
+  list                      List all songs
+  get <id>                  Show a song
+  add [song flags]          Add a song
+  update <id> [song flags]  Update the given fields of a song
+  delete <id>               Delete a song
+  import [-file path]       Add the songs of a JSON array (default: stdin)
+  export [-file path]       Write all songs as a JSON array (default: stdout)
+  migrate                   Create or update the database tables (local only)
+  reindex                   Rebuild the database indexes (local only)
+
+Sort flag (list, export):
+  -sort columns             Sort by columns such as release_date,-song_name,
+                            "-" for descending (default: id)
+
+Song flags:
+  -group, -song, -release-date (YYYY-MM-DD), -text, -lrc-file,
+  -chordpro-file, -link, -file (JSON song; flags override its fields)
+
+Flags:
+`
+
+// App runs songctl commands against a Backend
+type App struct {
+	Backend Backend
+	// DB is the database connection; it is nil when running against a
+	// remote server
+	DB     *gorm.DB
+	Output string
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+// Run parses args, executes the command and returns the exit code
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("songctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	envFile := flags.String("env", ".env", "configuration file for local mode")
+	server := flags.String("server", os.Getenv(ServerEnv), "base URL of a running server; the database is used directly when empty")
+	output := flags.String("output", OutputTable, "output format: table or json")
+	user := flags.String("user", defaultUser(), "user recorded as the author of changes")
+	verbose := flags.Bool("v", false, "log database activity")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || (*output != OutputTable && *output != OutputJSON) {
+		flags.Usage()
+		return 2
+	}
+	if !*verbose {
+		logger.SetLevel(logrus.WarnLevel)
+	}
+
+	app := &App{Output: *output, Stdin: stdin, Stdout: stdout}
+	if *server != "" {
+		app.Backend = NewRemoteBackend(*server, &http.Client{Timeout: 30 * time.Second}, *user)
+	} else {
+		cfg, err := config.LoadConfig(*envFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "songctl: %v\n", err)
+			return 1
+		}
+		store, err := storage.Open(cfg)
+		if err != nil {
+			fmt.Fprintf(stderr, "songctl: %v\n", err)
+			return 1
+		}
+		defer store.Close()
+		if store.DB != nil && !*verbose {
+			store.DB.Logger = gormlogger.Discard
+		}
+		app.DB = store.DB
+		app.Backend = NewLocalBackend(service.NewSongService(store.Songs))
+	}
+
+	ctx := service.WithActor(context.Background(), service.Actor{Name: *user})
+	if err := app.Execute(ctx, flags.Arg(0), flags.Args()[1:]); err != nil {
+		fmt.Fprintf(stderr, "songctl: %v\n", err)
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// Execute runs a single command
+func (a *App) Execute(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "list":
+		flags := flag.NewFlagSet("list", flag.ContinueOnError)
+		sort := flags.String("sort", "", "columns to sort by")
+		if err := flags.Parse(args); err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+		if flags.NArg() != 0 {
+			return fmt.Errorf("%w: list takes no arguments", errUsage)
+		}
+		songs, err := a.Backend.List(ctx, *sort)
+		if err != nil {
+			return err
+		}
+		return a.print(songs)
+	case "get":
+		id, err := idArg(command, args)
+		if err != nil {
+			return err
+		}
+		song, err := a.Backend.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		return a.print([]model.Song{*song})
+	case "add":
+		song, err := parseSong(command, args)
+		if err != nil {
+			return err
+		}
+		if err := a.Backend.Add(ctx, song); err != nil {
+			return err
+		}
+		return a.print([]model.Song{*song})
+	case "update":
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			return fmt.Errorf("%w: update requires a song ID", errUsage)
+		}
+		song, err := parseSong(command, args[1:])
+		if err != nil {
+			return err
+		}
+		updated, err := a.Backend.Update(ctx, args[0], song)
+		if err != nil {
+			return err
+		}
+		return a.print([]model.Song{*updated})
+	case "delete":
+		id, err := idArg(command, args)
+		if err != nil {
+			return err
+		}
+		if err := a.Backend.Delete(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Stdout, "Deleted song %s\n", id)
+		return nil
+	case "import":
+		return a.importSongs(ctx, args)
+	case "export":
+		return a.exportSongs(ctx, args)
+	case "migrate":
+		if a.DB == nil {
+			return fmt.Errorf("%w: migrate requires a database connection", errUsage)
+		}
+		if err := db.Migrate(a.DB); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.Stdout, "Migrations applied")
+		return nil
+	case "reindex":
+		if a.DB == nil {
+			return fmt.Errorf("%w: reindex requires a database connection", errUsage)
+		}
+		if err := db.Reindex(a.DB); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.Stdout, "Indexes rebuilt")
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+}
+
+// importSongs adds the songs of a JSON array. IDs and timestamps in the
+// input are ignored.
+func (a *App) importSongs(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "-", "JSON file to import, - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	in := a.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	var songs []model.Song
+	if err := json.NewDecoder(in).Decode(&songs); err != nil {
+		return errors.Wrap(err, "invalid import data")
+	}
+
+	for i := range songs {
+		song := &songs[i]
+		song.ID, song.CreatedAt, song.UpdatedAt = 0, time.Time{}, time.Time{}
+		if err := a.Backend.Add(ctx, song); err != nil {
+			return errors.Wrapf(err, "imported %d of %d songs, failed on %q", i, len(songs), song.SongName)
+		}
+	}
+	fmt.Fprintf(a.Stdout, "Imported %d songs\n", len(songs))
+	return nil
+}
+
+// exportSongs writes all songs as a JSON array that import accepts
+func (a *App) exportSongs(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	file := flags.String("file", "-", "file to write, - for stdout")
+	sort := flags.String("sort", "", "columns to sort by")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	songs, err := a.Backend.List(ctx, *sort)
+	if err != nil {
+		return err
+	}
+	out := a.Stdout
+	if *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	return writeJSON(out, songs)
+}
+
+func idArg(command string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%w: %s requires exactly one song ID", errUsage, command)
+	}
+	return args[0], nil
+}
+
+// parseSong builds a song from the song flags
+func parseSong(command string, args []string) (*model.Song, error) {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	file := flags.String("file", "", "JSON file with the song")
+	group := flags.String("group", "", "group name")
+	name := flags.String("song", "", "song name")
+	releaseDate := flags.String("release-date", "", "release date (YYYY-MM-DD)")
+	text := flags.String("text", "", "lyrics text")
+	lrcFile := flags.String("lrc-file", "", "file with LRC lyrics")
+	chordProFile := flags.String("chordpro-file", "", "file with a ChordPro chord sheet")
+	link := flags.String("link", "", "link to the song")
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("%w: unexpected argument %q", errUsage, flags.Arg(0))
+	}
+
+	song := &model.Song{}
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, song); err != nil {
+			return nil, errors.Wrap(err, "invalid song file")
+		}
+		song.ID = 0
+	}
+	if *group != "" {
+		song.GroupName = *group
+	}
+	if *name != "" {
+		song.SongName = *name
+	}
+	if *releaseDate != "" {
+		date, err := time.Parse("2006-01-02", *releaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid release date %q", errUsage, *releaseDate)
+		}
+		song.ReleaseDate = date
+	}
+	if *text != "" {
+		song.Text = *text
+	}
+	if *lrcFile != "" {
+		data, err := os.ReadFile(*lrcFile)
+		if err != nil {
+			return nil, err
+		}
+		song.LRC = string(data)
+	}
+	if *chordProFile != "" {
+		data, err := os.ReadFile(*chordProFile)
+		if err != nil {
+			return nil, err
+		}
+		song.ChordPro = string(data)
+	}
+	if *link != "" {
+		song.Link = *link
+	}
+	return song, nil
+}
+
+func defaultUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "songctl"
+}
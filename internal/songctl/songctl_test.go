@@ -0,0 +1,105 @@
+package songctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"song-library/internal/db"
+	"song-library/internal/handler"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/internal/service"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService() (*service.SongService, *gorm.DB) {
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
+	return service.NewSongService(repository.NewSongRepository(conn)), conn
+}
+
+func TestApp_Local(t *testing.T) {
+	songService, conn := setupTestService()
+	var out bytes.Buffer
+	app := &App{Backend: NewLocalBackend(songService), DB: conn, Output: OutputTable, Stdout: &out}
+	ctx := context.Background()
+
+	err := app.Execute(ctx, "add", []string{"-group", "Muse", "-song", "Starlight", "-release-date", "2006-09-04"})
+	assert.Nil(t, err, "Adding a song should not return an error")
+	assert.Contains(t, out.String(), "2006-09-04", "Table output should show the release date")
+
+	out.Reset()
+	app.Output = OutputJSON
+	assert.Nil(t, app.Execute(ctx, "update", []string{"1", "-song", "Uprising"}))
+	var updated []model.Song
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &updated), "JSON output should be valid")
+	assert.Equal(t, "Uprising", updated[0].SongName)
+	assert.Equal(t, "Muse", updated[0].GroupName, "Omitted fields should be kept")
+
+	out.Reset()
+	assert.Nil(t, app.Execute(ctx, "export", nil))
+	exported := out.String()
+	assert.Nil(t, app.Execute(ctx, "delete", []string{"1"}), "Songs must be deleted before they can be imported again")
+
+	app.Stdin = strings.NewReader(exported)
+	out.Reset()
+	assert.Nil(t, app.Execute(ctx, "import", nil), "Exported songs should import")
+	assert.Equal(t, "Imported 1 songs\n", out.String())
+	songs, _ := songService.GetSongs(ctx)
+	assert.Len(t, songs, 1)
+	assert.Equal(t, uint(2), songs[0].ID, "Imported songs should get new IDs")
+
+	assert.Nil(t, app.Execute(ctx, "delete", []string{"2"}))
+	assert.Nil(t, app.Execute(ctx, "reindex", nil), "Reindexing should not return an error")
+	assert.ErrorIs(t, app.Execute(ctx, "get", nil), errUsage, "get without an ID should be a usage error")
+	assert.ErrorIs(t, app.Execute(ctx, "get", []string{"2"}), repository.ErrSongNotFound)
+}
+
+func TestApp_Remote(t *testing.T) {
+	songService, _ := setupTestService()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/songs", handler.GetSongs(songService))
+	r.GET("/api/v1/songs/:id", handler.GetSongByID(songService))
+	r.POST("/api/v1/songs", handler.AddSong(songService))
+	r.PUT("/api/v1/songs/:id", handler.UpdateSong(songService))
+	r.DELETE("/api/v1/songs/:id", handler.DeleteSong(songService))
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	var out bytes.Buffer
+	app := &App{Backend: NewRemoteBackend(server.URL, http.DefaultClient, "alice"), Output: OutputTable, Stdout: &out}
+	ctx := context.Background()
+	var apiErr *APIError
+
+	assert.Nil(t, app.Execute(ctx, "add", []string{"-group", "Muse", "-song", "Starlight"}))
+	assert.Nil(t, app.Execute(ctx, "update", []string{"1", "-link", "https://example.com"}))
+	out.Reset()
+	assert.Nil(t, app.Execute(ctx, "list", nil))
+	assert.Contains(t, out.String(), "https://example.com", "Updates should reach the server")
+
+	assert.Nil(t, app.Execute(ctx, "add", []string{"-group", "Muse", "-song", "Uprising"}))
+	out.Reset()
+	app.Output = OutputJSON
+	assert.Nil(t, app.Execute(ctx, "export", []string{"-sort", "-song_name"}))
+	var sorted []model.Song
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &sorted))
+	assert.Equal(t, []uint{2, 1}, []uint{sorted[0].ID, sorted[1].ID}, "The sort should be sent to the server")
+	assert.ErrorAs(t, app.Execute(ctx, "list", []string{"-sort", "lyrics"}), &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Status, "Unknown sort columns should be rejected")
+
+	revs, _ := songService.GetRevisions(ctx, "1")
+	assert.Equal(t, "alice", revs[len(revs)-1].Author, "The user should be sent in X-User")
+
+	assert.ErrorAs(t, app.Execute(ctx, "update", []string{"42", "-song", "Madness"}), &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.Status)
+	assert.ErrorIs(t, app.Execute(ctx, "migrate", nil), errUsage, "migrate should need a database")
+}
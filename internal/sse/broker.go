@@ -0,0 +1,120 @@
+package sse
+
+import (
+	"encoding/json"
+	"song-library/internal/events"
+	"strconv"
+	"sync"
+)
+
+// DefaultBufferSize is the number of recent messages kept for resuming
+const DefaultBufferSize = 1000
+
+// clientBuffer is how many messages may queue up for a slow client before
+// it is disconnected. It can resume from the buffer with Last-Event-ID.
+const clientBuffer = 64
+
+// Message is a single server-sent event
+type Message struct {
+	ID    uint64
+	Event string
+	Data  []byte
+}
+
+// Broker fans song events out to connected SSE clients. It numbers messages
+// sequentially and keeps the most recent ones in a bounded buffer so that
+// reconnecting clients can resume after their Last-Event-ID.
+type Broker struct {
+	mu      sync.Mutex
+	buffer  []Message
+	size    int
+	lastID  uint64
+	clients map[chan Message]struct{}
+}
+
+// NewBroker creates a Broker remembering the last size messages
+func NewBroker(size int) *Broker {
+	return &Broker{
+		size:    size,
+		clients: make(map[chan Message]struct{}),
+	}
+}
+
+// HandleEvent broadcasts e to all clients. It is meant to be registered on
+// the song event bus.
+func (b *Broker) HandleEvent(e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	msg := Message{ID: b.lastID, Event: e.Type, Data: data}
+	b.buffer = append(b.buffer, msg)
+	if len(b.buffer) > b.size {
+		b.buffer = b.buffer[len(b.buffer)-b.size:]
+	}
+
+	for ch := range b.clients {
+		select {
+		case ch <- msg:
+		default:
+			// Never block the publisher on a slow client
+			delete(b.clients, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a client. Messages after lastEventID that are still
+// buffered are returned for replay; complete is false when some of the
+// missed messages have already left the buffer. The channel is closed when
+// the client falls too far behind. Call cancel when the client disconnects.
+func (b *Broker) Subscribe(lastEventID string) (replay []Message, complete bool, messages <-chan Message, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastEventID != "" {
+		last, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil || last > b.lastID {
+			// Unknown ID, e.g. from before a restart
+			complete = false
+			last = 0
+		}
+		for _, msg := range b.buffer {
+			if msg.ID > last {
+				replay = append(replay, msg)
+			}
+		}
+		if len(b.buffer) > 0 && b.buffer[0].ID > last+1 {
+			complete = false
+		}
+		if len(b.buffer) == 0 && last < b.lastID {
+			complete = false
+		}
+	}
+
+	ch := make(chan Message, clientBuffer)
+	b.clients[ch] = struct{}{}
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.clients[ch]; ok {
+			delete(b.clients, ch)
+			close(ch)
+		}
+	}
+	return replay, complete, ch, cancel
+}
+
+// Clients returns the number of connected clients
+func (b *Broker) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
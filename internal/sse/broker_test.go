@@ -0,0 +1,50 @@
+package sse
+
+import (
+	"song-library/internal/events"
+	"song-library/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func publish(b *Broker, n int) {
+	for i := 0; i < n; i++ {
+		b.HandleEvent(events.NewEvent(events.SongUpdated, &model.Song{ID: uint(i + 1)}))
+	}
+}
+
+func TestBroker_Resume(t *testing.T) {
+	b := NewBroker(3)
+	publish(b, 5)
+
+	replay, complete, _, cancel := b.Subscribe("3")
+	defer cancel()
+	assert.True(t, complete, "Resuming inside the buffer should be complete")
+	assert.Len(t, replay, 2, "Messages after Last-Event-ID should be replayed")
+	assert.Equal(t, uint64(4), replay[0].ID)
+
+	replay, complete, _, cancel2 := b.Subscribe("1")
+	defer cancel2()
+	assert.False(t, complete, "Resuming before the buffer should report missed messages")
+	assert.Len(t, replay, 3, "All buffered messages should be replayed")
+
+	replay, _, _, cancel3 := b.Subscribe("")
+	defer cancel3()
+	assert.Empty(t, replay, "New clients should only get new messages")
+}
+
+func TestBroker_DropsSlowClients(t *testing.T) {
+	b := NewBroker(DefaultBufferSize)
+	_, _, messages, cancel := b.Subscribe("")
+	defer cancel()
+
+	publish(b, clientBuffer+1)
+	assert.Equal(t, 0, b.Clients(), "Slow client should be disconnected")
+
+	count := 0
+	for range messages {
+		count++
+	}
+	assert.Equal(t, clientBuffer, count, "Queued messages should still be readable before close")
+}
@@ -0,0 +1,95 @@
+// Package storage opens the repositories of the configured storage backend
+package storage
+
+import (
+	"context"
+	"fmt"
+	"song-library/config"
+	"song-library/internal/db"
+	"song-library/internal/repository"
+	"song-library/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// Storage holds the repositories of one backend
+type Storage struct {
+	Songs    repository.SongRepository
+	Webhooks repository.WebhookRepository
+	// Idempotency stores the Idempotency-Key responses
+	Idempotency repository.IdempotencyRepository
+	// DB is the database connection; it is nil for the memory driver
+	DB *gorm.DB
+	// Replicas routes reads to the read replicas; it is nil when none are
+	// configured
+	Replicas   *repository.ReplicaRouter
+	replicaDBs []*gorm.DB
+}
+
+// Open creates the repositories for cfg.DBDriver, connecting to and
+// migrating the database when the driver has one
+func Open(cfg *config.Config) (*Storage, error) {
+	if cfg.DBDriver == db.DriverMemory {
+		logger.Info("Using in-memory storage; data is lost on restart", nil)
+		return &Storage{
+			Songs:       repository.NewMemorySongRepository(),
+			Webhooks:    repository.NewMemoryWebhookRepository(),
+			Idempotency: repository.NewMemoryIdempotencyRepository(),
+		}, nil
+	}
+
+	conn, err := db.Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &Storage{
+		Songs:       repository.NewSongRepository(conn),
+		Webhooks:    repository.NewWebhookRepository(conn),
+		Idempotency: repository.NewIdempotencyRepository(conn),
+		DB:          conn,
+	}
+
+	s.replicaDBs, err = db.ConnectReplicas(cfg)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	if len(s.replicaDBs) > 0 {
+		replicas := make([]repository.Replica, len(s.replicaDBs))
+		for i, replica := range s.replicaDBs {
+			replica := replica
+			replicas[i] = repository.Replica{
+				Name: fmt.Sprintf("replica-%d", i),
+				Repo: repository.NewSongRepository(replica),
+				Ping: func(ctx context.Context) error {
+					sqlDB, err := replica.DB()
+					if err != nil {
+						return err
+					}
+					return sqlDB.PingContext(ctx)
+				},
+			}
+		}
+		s.Replicas = repository.NewReplicaRouter(replicas, cfg.DBReadYourWritesWindow)
+		logger.Info("Routing reads to replicas", logger.Fields{"replicas": len(replicas)})
+	}
+	return s, nil
+}
+
+// Close closes the database connections, if any
+func (s *Storage) Close() error {
+	var firstErr error
+	for _, conn := range append([]*gorm.DB{s.DB}, s.replicaDBs...) {
+		if conn == nil {
+			continue
+		}
+		sqlDB, err := conn.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -8,10 +8,12 @@ import (
 	"net/http/httptest"
 	"song-library/config"
 	"song-library/internal/db"
-	"song-library/internal/model"
+	"song-library/internal/graphapi"
+	"song-library/internal/middleware"
 	"song-library/internal/repository"
 	"song-library/internal/router"
 	"song-library/internal/service"
+	"song-library/internal/sse"
 	"strings"
 	"testing"
 
@@ -47,7 +49,7 @@ func setupIntegrationTest() (*gin.Engine, error) {
 	}
 
 	// Run migrations
-	err = dbConn.AutoMigrate(&model.Song{})
+	err = db.Migrate(dbConn)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": err,
@@ -59,10 +61,16 @@ func setupIntegrationTest() (*gin.Engine, error) {
 	// Initialize the repository and service
 	songRepository := repository.NewSongRepository(dbConn)
 	songService := service.NewSongService(songRepository)
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(dbConn))
+
+	graphQL, err := graphapi.NewExecutor(songService, graphapi.Limits{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %v", err)
+	}
 
 	// Set up the router
 	r := gin.Default()
-	router.SetupRoutes(r, songService) // Pass the service to the router
+	router.SetupRoutes(r, cfg, middleware.NewIdempotency(repository.NewIdempotencyRepository(dbConn), cfg.IdempotencyTTL), songService, webhookService, sse.NewBroker(sse.DefaultBufferSize), graphQL) // Pass the service to the router
 	return r, nil
 }
 
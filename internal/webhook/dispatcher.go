@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"song-library/internal/events"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"song-library/pkg/logger"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Dispatcher defaults
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBaseBackoff  = 5 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultPollInterval = time.Second
+	DefaultConcurrency  = 4
+	defaultBatchSize    = 100
+)
+
+// Sign returns the signature of a delivery body: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret, prefixed with
+// "sha256=". Receivers should recompute it and reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher turns song events into webhook deliveries and sends them.
+// Deliveries are persisted before they are attempted, so pending retries
+// survive restarts. Failed attempts are retried with exponential backoff
+// until MaxAttempts is reached, after which the delivery is marked dead.
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	now    func() time.Time
+	wake   chan struct{}
+
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	Concurrency  int
+}
+
+// NewDispatcher creates a Dispatcher with default retry settings
+func NewDispatcher(repo repository.WebhookRepository, client *http.Client) *Dispatcher {
+	return &Dispatcher{
+		repo:         repo,
+		client:       client,
+		now:          time.Now,
+		wake:         make(chan struct{}, 1),
+		MaxAttempts:  DefaultMaxAttempts,
+		BaseBackoff:  DefaultBaseBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+		PollInterval: DefaultPollInterval,
+		Concurrency:  DefaultConcurrency,
+	}
+}
+
+// HandleEvent queues a delivery of e for every active webhook subscribed to
+// its type. It is meant to be registered on the song event bus. When a
+// delivery cannot be queued, the error is returned so that the event is
+// published again; webhooks that already have a delivery of e do not get
+// a second one.
+func (d *Dispatcher) HandleEvent(e events.Event) error {
+	webhooks, err := d.repo.GetWebhooks()
+	if err != nil {
+		return errors.Wrap(err, "load webhooks")
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "encode webhook payload")
+	}
+
+	var failed error
+
+	for _, webhook := range webhooks {
+		if !webhook.Active || !webhook.Subscribes(e.Type) {
+			continue
+		}
+		delivery := &model.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Payload:       string(payload),
+			Status:        model.DeliveryPending,
+			NextAttemptAt: d.now(),
+		}
+		if err := d.repo.AddDelivery(delivery); err != nil {
+			logger.Error("Failed to queue webhook delivery", logger.Fields{
+				"webhook_id": webhook.ID,
+				"event_id":   e.ID,
+				"error":      err.Error(),
+			})
+			if failed == nil {
+				failed = errors.Wrapf(err, "queue delivery to webhook %d", webhook.ID)
+			}
+		}
+	}
+	d.Wake()
+	return failed
+}
+
+// Wake makes Run process due deliveries without waiting for the next poll
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes due deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+		d.ProcessDue(ctx)
+	}
+}
+
+// ProcessDue makes one attempt at every delivery that is currently due
+func (d *Dispatcher) ProcessDue(ctx context.Context) {
+	deliveries, err := d.repo.GetDueDeliveries(d.now(), defaultBatchSize)
+	if err != nil {
+		logger.Error("Failed to load due webhook deliveries", logger.Fields{"error": err.Error()})
+		return
+	}
+
+	sem := make(chan struct{}, max(d.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			d.attempt(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt sends a delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) {
+	delivery.Attempts++
+
+	webhook, err := d.repo.GetWebhookByID(strconv.FormatUint(uint64(delivery.WebhookID), 10))
+	switch {
+	case err != nil:
+		delivery.LastError = err.Error()
+	case !webhook.Active:
+		delivery.LastError = "webhook is inactive"
+	default:
+		delivery.ResponseCode, err = d.send(ctx, webhook, delivery)
+		delivery.LastError = ""
+		if err != nil {
+			delivery.LastError = err.Error()
+		}
+	}
+
+	switch {
+	case delivery.LastError == "":
+		delivery.Status = model.DeliverySucceeded
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = model.DeliveryDead
+		logger.Error("Webhook delivery moved to dead letters", logger.Fields{
+			"delivery_id": delivery.ID,
+			"webhook_id":  delivery.WebhookID,
+			"attempts":    delivery.Attempts,
+			"error":       delivery.LastError,
+		})
+	default:
+		delivery.NextAttemptAt = d.now().Add(d.backoff(delivery.Attempts))
+	}
+
+	if err := d.repo.SaveDelivery(delivery); err != nil {
+		logger.Error("Failed to save webhook delivery", logger.Fields{"delivery_id": delivery.ID, "error": err.Error()})
+	}
+}
+
+// send posts the signed payload and returns the response status code
+func (d *Dispatcher) send(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt: BaseBackoff doubled
+// for every failed attempt, capped at MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempts && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.MaxBackoff)
+}
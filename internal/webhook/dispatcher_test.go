@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"song-library/internal/db"
+	"song-library/internal/events"
+	"song-library/internal/model"
+	"song-library/internal/repository"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDispatcher creates a Dispatcher over an in-memory database with
+// one webhook pointing at url
+func setupTestDispatcher(url string) (*Dispatcher, repository.WebhookRepository) {
+	conn, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.Migrate(conn)
+	repo := repository.NewWebhookRepository(conn)
+	repo.AddWebhook(&model.Webhook{
+		URL:    url,
+		Secret: "s3cret",
+		Events: model.StringList{events.SongCreated},
+		Active: true,
+	})
+	return NewDispatcher(repo, http.DefaultClient), repo
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer server.Close()
+
+	dispatcher, repo := setupTestDispatcher(server.URL)
+	song := &model.Song{ID: 1, GroupName: "Muse", SongName: "Starlight"}
+	dispatcher.HandleEvent(events.NewEvent(events.SongCreated, song))
+	dispatcher.HandleEvent(events.NewEvent(events.SongDeleted, song))
+	dispatcher.ProcessDue(context.Background())
+
+	deliveries, _ := repo.GetDeliveries("1", "")
+	assert.Len(t, deliveries, 1, "Only subscribed events should be delivered")
+	assert.Equal(t, model.DeliverySucceeded, deliveries[0].Status, "Delivery should succeed")
+	assert.Equal(t, events.SongCreated, header.Get(EventHeader), "Event type should be sent")
+
+	timestamp, _ := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	assert.Equal(t, Sign("s3cret", timestamp, body), header.Get(SignatureHeader), "Signature should match the body")
+	assert.Contains(t, string(body), "Starlight", "Payload should contain the song")
+}
+
+func TestDispatcher_RetriesThenDeadLetters(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	dispatcher, repo := setupTestDispatcher(server.URL)
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.MaxAttempts = 3
+	dispatcher.HandleEvent(events.NewEvent(events.SongCreated, &model.Song{ID: 1}))
+
+	dispatcher.ProcessDue(context.Background())
+	delivery, _ := repo.GetDeliveryByID("1")
+	assert.Equal(t, model.DeliveryPending, delivery.Status, "Failed delivery should be retried")
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.ResponseCode, "Response code should be logged")
+	assert.WithinDuration(t, now.Add(DefaultBaseBackoff), delivery.NextAttemptAt, time.Millisecond)
+
+	dispatcher.ProcessDue(context.Background())
+	assert.Equal(t, 1, calls, "Retry should wait for the backoff")
+
+	now = now.Add(DefaultBaseBackoff)
+	dispatcher.ProcessDue(context.Background())
+	delivery, _ = repo.GetDeliveryByID("1")
+	assert.WithinDuration(t, now.Add(2*DefaultBaseBackoff), delivery.NextAttemptAt, time.Millisecond, "Backoff should double")
+
+	now = now.Add(2 * DefaultBaseBackoff)
+	dispatcher.ProcessDue(context.Background())
+	dead, _ := repo.GetDeliveries("", model.DeliveryDead)
+	assert.Len(t, dead, 1, "Delivery should be dead after MaxAttempts")
+	assert.Equal(t, 3, calls, "Every attempt should reach the server")
+}
+
+// failingWebhookRepository cannot queue deliveries
+type failingWebhookRepository struct {
+	repository.WebhookRepository
+}
+
+func (r *failingWebhookRepository) AddDelivery(delivery *model.WebhookDelivery) error {
+	return errors.New("database is down")
+}
+
+func TestDispatcher_HandleEvent(t *testing.T) {
+	dispatcher, repo := setupTestDispatcher("http://example.com")
+	event := events.NewEvent(events.SongCreated, &model.Song{ID: 1})
+	assert.NoError(t, dispatcher.HandleEvent(event))
+	assert.NoError(t, dispatcher.HandleEvent(event), "A republished event should be accepted")
+	deliveries, _ := repo.GetDeliveries("1", "")
+	assert.Len(t, deliveries, 1, "A republished event should not be queued twice")
+
+	dispatcher.repo = &failingWebhookRepository{WebhookRepository: repo}
+	assert.Error(t, dispatcher.HandleEvent(events.NewEvent(events.SongCreated, &model.Song{ID: 1})),
+		"Events that could not be queued should be published again")
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"context"
+	"time"
+)
+
+type contextKey struct{}
+
+// requestFields are the fields carried by a context
+type requestFields struct {
+	fields Fields
+	// start is when the request began; lines logged with the context get
+	// the time elapsed since as latency_ms
+	start time.Time
+}
+
+// NewRequestContext returns a copy of ctx whose log lines carry fields and
+// the latency since now
+func NewRequestContext(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestFields{fields: fields, start: time.Now()})
+}
+
+// WithFields returns a copy of ctx whose log lines carry fields in addition
+// to those already in ctx
+func WithFields(ctx context.Context, fields Fields) context.Context {
+	merged := make(Fields)
+	next := &requestFields{fields: merged}
+	if current, ok := ctx.Value(contextKey{}).(*requestFields); ok {
+		for k, v := range current.fields {
+			merged[k] = v
+		}
+		next.start = current.start
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, contextKey{}, next)
+}
+
+// FieldsFromContext returns the fields carried by ctx, including the
+// current latency
+func FieldsFromContext(ctx context.Context) Fields {
+	fields := make(Fields)
+	current, ok := ctx.Value(contextKey{}).(*requestFields)
+	if !ok {
+		return fields
+	}
+	for k, v := range current.fields {
+		fields[k] = v
+	}
+	if !current.start.IsZero() {
+		fields["latency_ms"] = Milliseconds(time.Since(current.start))
+	}
+	return fields
+}
+
+// Milliseconds converts d to fractional milliseconds for log fields
+func Milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func contextFields(ctx context.Context, fields Fields) Fields {
+	merged := FieldsFromContext(ctx)
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+// InfoContext logs an informational message with the fields of ctx
+func InfoContext(ctx context.Context, message string, fields Fields) {
+	log.WithFields(contextFields(ctx, fields)).Info(message)
+}
+
+// WarnContext logs a warning with the fields of ctx
+func WarnContext(ctx context.Context, message string, fields Fields) {
+	log.WithFields(contextFields(ctx, fields)).Warn(message)
+}
+
+// ErrorContext logs an error message with the fields of ctx
+func ErrorContext(ctx context.Context, message string, fields Fields) {
+	log.WithFields(contextFields(ctx, fields)).Error(message)
+}
+
+// DebugContext logs a debug message with the fields of ctx
+func DebugContext(ctx context.Context, message string, fields Fields) {
+	log.WithFields(contextFields(ctx, fields)).Debug(message)
+}
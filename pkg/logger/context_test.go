@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfoContext(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	defer Init()
+
+	ctx := NewRequestContext(context.Background(), Fields{"request_id": "abc", "route": "/songs"})
+	ctx = WithFields(ctx, Fields{"user": "alice"})
+	InfoContext(ctx, "Song created", Fields{"song_id": 7})
+
+	var line map[string]interface{}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "abc", line["request_id"])
+	assert.Equal(t, "/songs", line["route"])
+	assert.Equal(t, "alice", line["user"])
+	assert.Equal(t, float64(7), line["song_id"])
+	assert.Contains(t, line, "latency_ms")
+
+	assert.Empty(t, FieldsFromContext(context.Background()), "A plain context should carry no fields")
+}
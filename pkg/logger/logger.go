@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -9,7 +10,19 @@ import (
 // Fields is an alias for logrus.Fields to simplify usage
 type Fields = logrus.Fields
 
-var log = logrus.New()
+// Output formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+var log = newLogger()
+
+func newLogger() *logrus.Logger {
+	l := logrus.New()
+	l.AddHook(redactHook{})
+	return l
+}
 
 func Init() {
 	log.SetFormatter(&logrus.JSONFormatter{})
@@ -27,7 +40,41 @@ func Error(message string, fields Fields) {
 	log.WithFields(fields).Error(message)
 }
 
+// Warn logs warnings
+func Warn(message string, fields Fields) {
+	log.WithFields(fields).Warn(message)
+}
+
 // Debug logs debug messages
 func Debug(message string, fields Fields) {
 	log.WithFields(fields).Debug(message)
 }
+
+// DebugEnabled reports whether debug messages are logged
+func DebugEnabled() bool {
+	return log.IsLevelEnabled(logrus.DebugLevel)
+}
+
+// SetLevel changes the minimum level of logged messages
+func SetLevel(level logrus.Level) {
+	log.SetLevel(level)
+}
+
+// Configure sets the minimum level ("debug", "info", "warn" or "error") and
+// the output format, FormatJSON or FormatText
+func Configure(level, format string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatJSON, "":
+		log.SetFormatter(&logrus.JSONFormatter{})
+	case FormatText:
+		log.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	log.SetLevel(lvl)
+	return nil
+}
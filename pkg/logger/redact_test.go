@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "postgres://app:***@db:5432/songs", Redact("postgres://app:hunter2@db:5432/songs"))
+	assert.Equal(t, "host=db password=*** dbname=songs", Redact("host=db password='hunter 2' dbname=songs"))
+
+	RegisterSecret("s3cr3t-token")
+	assert.Equal(t, "token *** rejected", Redact("token s3cr3t-token rejected"))
+}
+
+func TestRegisterSecret(t *testing.T) {
+	before := len(secrets)
+	RegisterSecret("another-token")
+	RegisterSecret("another-token")
+	assert.Len(t, secrets, before+1, "A secret should be registered once")
+
+	RegisterSecret("app")
+	assert.Equal(t, "app started", Redact("app started"), "Short secrets should not be replaced")
+}
+
+func TestRedactHook(t *testing.T) {
+	var buf bytes.Buffer
+	l := newLogger()
+	l.SetOutput(&buf)
+	l.SetFormatter(&logrus.JSONFormatter{})
+
+	l.WithFields(Fields{"dsn": "host=db password=hunter2"}).Error("connect postgres://app:hunter2@db failed")
+	assert.NotContains(t, buf.String(), "hunter2", "Passwords should never reach the output")
+}